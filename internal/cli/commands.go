@@ -5,6 +5,38 @@ import (
 	"konfigo/internal/logger"
 )
 
+// Subcommands. Without a subcommand, konfigo merges and processes sources.
+const (
+	// CommandSubst substitutes variables in arbitrary text files.
+	CommandSubst = "subst"
+	// CommandVars lists the resolved variables and where they came from.
+	CommandVars = "vars"
+	// CommandExplain shows where a configuration value came from.
+	CommandExplain = "explain"
+	// CommandSchema works with schema files, e.g. "schema export".
+	CommandSchema = "schema"
+	// CommandLint checks a schema for mistakes without processing sources.
+	CommandLint = "lint"
+)
+
+// ExportFormatJSONSchema is the --format of schema export that writes a JSON
+// Schema document.
+const ExportFormatJSONSchema = "jsonschema"
+
+// commands lists the supported subcommands.
+var commands = map[string]bool{
+	CommandSubst:   true,
+	CommandVars:    true,
+	CommandExplain: true,
+	CommandSchema:  true,
+	CommandLint:    true,
+}
+
+// IsCommand reports whether name is a supported subcommand.
+func IsCommand(name string) bool {
+	return commands[name]
+}
+
 // Command represents the main command execution logic
 type Command struct {
 	Config *Config
@@ -37,6 +69,11 @@ func (cmd *Command) Execute() (*Config, error) {
 	isDebug, isQuiet := cmd.Config.GetLoggerConfig()
 	logger.Init(isDebug, isQuiet)
 
+	// Subcommands validate their own arguments
+	if cmd.Config.Command != "" {
+		return cmd.Config, nil
+	}
+
 	// Validate that we have input sources
 	sourcePaths := cmd.Config.GetSourcePaths()
 	if sourcePaths == "" {
@@ -30,6 +30,7 @@ import (
 	"strings"
 
 	"konfigo/internal/errors"
+	"konfigo/internal/util"
 )
 
 // flagSet is the package-level flag set used for parsing.
@@ -39,14 +40,22 @@ var flagSet *flag.FlagSet
 
 // Config holds all CLI flag values
 type Config struct {
+	// Command is the subcommand being run (e.g. "subst"); empty for the
+	// default merge-and-process mode.
+	Command string
+	// Args holds the positional arguments of a subcommand.
+	Args []string
+
 	// Schema and Variables
 	SchemaFile string
-	VarsFile   string
+	// VarsFiles lists the -V files in the order given; later files override earlier ones.
+	VarsFiles []string
 
 	// Sources and Input
 	SourcePaths   string
 	Recursive     bool
 	CaseSensitive bool
+	KeyCase       string
 	InputJSON     bool
 	InputYAML     bool
 	InputTOML     bool
@@ -59,11 +68,41 @@ type Config struct {
 	OutputTOML bool
 	OutputENV  bool
 
+	// TemplateFile renders a text/template instead of marshalling the output
+	TemplateFile string
+
+	// Text substitution (subst command)
+	InPlace   bool
+	OutputDir string
+
+	// ExportFormat is the document format of schema export
+	ExportFormat string
+
 	// Behavior and Logging
-	MergeArrays bool
-	Verbose     bool
-	Debug       bool
-	Help        bool
+	MergeArrays    bool
+	NullDeletes    bool
+	OverrideReport bool
+	ShapeConflicts string
+	StrictShapes   bool
+	StrictVars     bool
+	Verbose        bool
+	Debug          bool
+	Help           bool
+}
+
+// stringList is a flag.Value that collects every occurrence of a repeated flag.
+type stringList []string
+
+func (s *stringList) String() string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	*s = append(*s, value)
+	return nil
 }
 
 // ParseFlags parses command line flags and returns a Config struct.
@@ -71,18 +110,25 @@ type Config struct {
 func ParseFlags() (*Config, error) {
 	config := &Config{}
 
+	args := os.Args[1:]
+	if len(args) > 0 && IsCommand(args[0]) {
+		config.Command = args[0]
+		args = args[1:]
+	}
+
 	flagSet = flag.NewFlagSet("konfigo", flag.ContinueOnError)
 
 	// Schema & Variables
 	flagSet.StringVar(&config.SchemaFile, "schema", "", "Path to a schema file for processing the config.")
 	flagSet.StringVar(&config.SchemaFile, "S", "", "Path to a schema file (shorthand for --schema).")
-	flagSet.StringVar(&config.VarsFile, "vars-file", "", "Path to a file providing high-priority variables.")
-	flagSet.StringVar(&config.VarsFile, "V", "", "Path to a variables file (shorthand for --vars-file).")
+	flagSet.Var((*stringList)(&config.VarsFiles), "vars-file", "Path to a file providing high-priority variables. Repeat to layer files.")
+	flagSet.Var((*stringList)(&config.VarsFiles), "V", "Path to a variables file (shorthand for --vars-file).")
 
 	// Sources and Input
 	flagSet.StringVar(&config.SourcePaths, "s", "", "Comma-separated list of source files/directories. Use '-' for stdin.")
 	flagSet.BoolVar(&config.Recursive, "r", false, "Recursively search for configuration files in subdirectories")
 	flagSet.BoolVar(&config.CaseSensitive, "c", false, "Use case-sensitive key matching (default is case-insensitive)")
+	flagSet.StringVar(&config.KeyCase, "key-case", "", "Convert every source key to a case style: lower, upper, snake, camel, kebab or pascal.")
 	flagSet.BoolVar(&config.InputJSON, "sj", false, "Force input to be parsed as JSON (required for stdin)")
 	flagSet.BoolVar(&config.InputYAML, "sy", false, "Force input to be parsed as YAML (required for stdin)")
 	flagSet.BoolVar(&config.InputTOML, "st", false, "Force input to be parsed as TOML (required for stdin)")
@@ -94,16 +140,38 @@ func ParseFlags() (*Config, error) {
 	flagSet.BoolVar(&config.OutputYAML, "oy", false, "Output in YAML format")
 	flagSet.BoolVar(&config.OutputTOML, "ot", false, "Output in TOML format")
 	flagSet.BoolVar(&config.OutputENV, "oe", false, "Output in ENV format")
+	flagSet.StringVar(&config.TemplateFile, "template", "", "Render a Go text/template file against the final config instead of marshalling it.")
+
+	// Text substitution
+	flagSet.BoolVar(&config.InPlace, "in-place", false, "subst: rewrite the given files in place.")
+	flagSet.StringVar(&config.OutputDir, "out-dir", "", "subst: write substituted files into this directory.")
+
+	// Schema export
+	flagSet.StringVar(&config.ExportFormat, "format", "", "schema export: format of the exported document (jsonschema).")
 
 	// Behavior and Logging
 	flagSet.BoolVar(&config.MergeArrays, "m", false, "Merge arrays by union with deduplication instead of replacing.")
+	flagSet.BoolVar(&config.NullDeletes, "null-deletes", false, "Treat null values in later sources as deleting the key.")
+	flagSet.BoolVar(&config.OverrideReport, "override-report", false, "Print the keys each source overrode to stderr.")
+	flagSet.StringVar(&config.ShapeConflicts, "shape-conflicts", "warn", "Report values replaced by a different shape (map, array, scalar) as 'warn' or 'error' log messages.")
+	flagSet.BoolVar(&config.StrictShapes, "strict-shapes", false, "Fail when a source replaces a value with a different shape.")
+	flagSet.BoolVar(&config.StrictVars, "strict-vars", false, "Fail if any ${VAR} placeholder remains unresolved.")
 	flagSet.BoolVar(&config.Verbose, "v", false, "Enable informational (INFO) logging. Overrides default quiet behavior.")
 	flagSet.BoolVar(&config.Debug, "d", false, "Enable debug (DEBUG and INFO) logging. Overrides -v and default quiet behavior.")
 	flagSet.BoolVar(&config.Help, "h", false, "Show this help message.")
 
-	if err := flagSet.Parse(os.Args[1:]); err != nil {
+	if err := flagSet.Parse(args); err != nil {
 		return nil, errors.WrapError(errors.ErrorTypeCLIFlag, "failed to parse flags", err)
 	}
+	if config.Command != "" {
+		// Subcommands accept flags after their positional arguments
+		for rest := flagSet.Args(); len(rest) > 0; rest = flagSet.Args() {
+			config.Args = append(config.Args, rest[0])
+			if err := flagSet.Parse(rest[1:]); err != nil {
+				return nil, errors.WrapError(errors.ErrorTypeCLIFlag, "failed to parse flags", err)
+			}
+		}
+	}
 
 	return config, nil
 }
@@ -124,7 +192,7 @@ func (c *Config) GetInputFormat() string {
 
 // GetSourcePaths returns the list of source paths, handling both -s flag and positional args
 func (c *Config) GetSourcePaths() string {
-	if c.SourcePaths == "" && flagSet != nil && flagSet.NArg() > 0 {
+	if c.SourcePaths == "" && c.Command == "" && flagSet != nil && flagSet.NArg() > 0 {
 		return strings.Join(flagSet.Args(), ",")
 	}
 	return c.SourcePaths
@@ -135,6 +203,9 @@ func (c *Config) ShouldShowHelp() bool {
 	if flagSet == nil {
 		return c.Help
 	}
+	if c.Command != "" {
+		return c.Help
+	}
 	return c.Help || (flagSet.NFlag() == 0 && flagSet.NArg() == 0)
 }
 
@@ -162,6 +233,34 @@ func (c *Config) Validate() error {
 		return errors.NewError(errors.ErrorTypeCLIFlag, "only one input format flag (-sj, -sy, -st, -se) can be specified")
 	}
 
+	if c.InPlace && c.OutputDir != "" {
+		return errors.NewError(errors.ErrorTypeCLIFlag, "--in-place and --out-dir cannot be used together")
+	}
+	if (c.InPlace || c.OutputDir != "") && c.Command != CommandSubst {
+		return errors.NewError(errors.ErrorTypeCLIFlag, "--in-place and --out-dir are only supported by the subst command")
+	}
+
+	if c.ExportFormat != "" && c.Command != CommandSchema {
+		return errors.NewError(errors.ErrorTypeCLIFlag, "--format is only supported by the schema command")
+	}
+	if c.ExportFormat != "" && c.ExportFormat != ExportFormatJSONSchema {
+		return errors.NewErrorf(errors.ErrorTypeCLIFlag, "invalid --format '%s' (expected %s)", c.ExportFormat, ExportFormatJSONSchema)
+	}
+
+	if c.ShapeConflicts != "" && c.ShapeConflicts != "warn" && c.ShapeConflicts != "error" {
+		return errors.NewErrorf(errors.ErrorTypeCLIFlag, "invalid --shape-conflicts '%s' (expected warn or error)", c.ShapeConflicts)
+	}
+
+	if c.KeyCase != "" {
+		if _, err := util.ChangeCase("", c.KeyCase); err != nil {
+			return errors.WrapError(errors.ErrorTypeCLIFlag, "invalid --key-case", err)
+		}
+	}
+
+	if c.TemplateFile != "" && (c.OutputJSON || c.OutputYAML || c.OutputTOML || c.OutputENV) {
+		return errors.NewError(errors.ErrorTypeCLIFlag, "--template cannot be combined with output format flags (-oj, -oy, -ot, -oe)")
+	}
+
 	return nil
 }
 
@@ -14,27 +14,59 @@ func PrintHelp() {
 	fmt.Fprintf(out, "  to validate, transform, and generate final configuration values.\n\n")
 	fmt.Fprintf(out, "USAGE:\n")
 	fmt.Fprintf(out, "  konfigo [flags] -s <sources...>\n")
-	fmt.Fprintf(out, "  cat config.yml | konfigo -sy -S schema.yml\n\n")
+	fmt.Fprintf(out, "  cat config.yml | konfigo -sy -S schema.yml\n")
+	fmt.Fprintf(out, "  konfigo <command> [flags] [args...]\n\n")
+	fmt.Fprintf(out, "COMMANDS:\n")
+	fmt.Fprintf(out, "  subst [files...]\n\t\tSubstitute ${VAR} placeholders in arbitrary text files (stdin when no files are given).\n")
+	fmt.Fprintf(out, "\t\tUses the -V, -S and KONFIGO_VAR_ variables; -s sources back ${.path} references.\n")
+	fmt.Fprintf(out, "\t\tWrites to stdout unless --in-place or --out-dir <dir> is given.\n")
+	fmt.Fprintf(out, "  vars\t\tList the resolved variables with their values and where each value came from.\n")
+	fmt.Fprintf(out, "\t\tUses the -V, -S and KONFIGO_VAR_ variables; -s sources back fromPath. -oj prints JSON.\n")
+	fmt.Fprintf(out, "  explain <path>\tShow the final value at <path> (or every value below it), the file:line or step\n")
+	fmt.Fprintf(out, "\t\tthat set it and the values it overrode. Takes the usual -s, -S and -V flags; -oj prints JSON.\n")
+	fmt.Fprintf(out, "  schema export --format jsonschema -S <schema>\n")
+	fmt.Fprintf(out, "\t\tTranslate the inputSchema and validate rules of a schema into a JSON Schema document\n")
+	fmt.Fprintf(out, "\t\tfor editor completion and checks. Writes to stdout, or to the file given with -of.\n")
+	fmt.Fprintf(out, "  schema resolve -S <schema>\n")
+	fmt.Fprintf(out, "\t\tPrint the schema merged with the schemas it extends and imports (YAML, or -oj/-ot).\n")
+	fmt.Fprintf(out, "  lint -S <schema>\tCheck a schema for unknown fields, invalid generators, transforms and validate\n")
+	fmt.Fprintf(out, "\t\trules, and unused or shadowed vars. -s sources count as produced paths and var\n")
+	fmt.Fprintf(out, "\t\treferences. -oj prints JSON. Fails when errors are found.\n\n")
 	fmt.Fprintf(out, "FLAGS:\n")
 	fmt.Fprintf(out, "  Input & Sources:\n")
 	fmt.Fprintf(out, "    -s <paths>\tComma-separated list of source files/directories. Use '-' for stdin.\n")
 	fmt.Fprintf(out, "    -r\t\tRecursively search for configuration files in subdirectories.\n")
+	fmt.Fprintf(out, "    --key-case <style>\n\t\tConvert every source key to lower, upper, snake, camel, kebab or pascal case.\n")
 	fmt.Fprintf(out, "    -sj, -sy, -st, -se\n\t\tForce input to be parsed as a specific format (required for stdin).\n\n")
 	fmt.Fprintf(out, "  Schema & Variables:\n")
 	fmt.Fprintf(out, "    -S, --schema <path>\n\t\tPath to a schema file (YAML, JSON, TOML) for processing the config.\n")
-	fmt.Fprintf(out, "    -V, --vars-file <path>\n\t\tPath to a file providing high-priority variables for substitution.\n\n")
+	fmt.Fprintf(out, "    -V, --vars-file <path>\n\t\tPath to a file providing high-priority variables for substitution.\n")
+	fmt.Fprintf(out, "\t\tRepeat to layer files; later files override earlier ones.\n\n")
 	fmt.Fprintf(out, "    Variable Priority:\n")
 	fmt.Fprintf(out, "    Variable values are resolved with the following priority (1 is highest):\n")
 	fmt.Fprintf(out, "      1. Environment variables (KONFIGO_VAR_...).\n")
-	fmt.Fprintf(out, "      2. Variables from the --vars-file (-V).\n")
+	fmt.Fprintf(out, "      2. Variables from the --vars-file (-V), later files first.\n")
 	fmt.Fprintf(out, "      3. Variables defined in the schema's `vars:` section (-S).\n\n")
+	fmt.Fprintf(out, "    Placeholder Syntax:\n")
+	fmt.Fprintf(out, "      ${VAR}            Value of VAR (left as-is when unresolved).\n")
+	fmt.Fprintf(out, "      ${VAR:-default}   Value of VAR, or 'default' when VAR is unset or empty.\n")
+	fmt.Fprintf(out, "      ${VAR:?message}   Value of VAR, or fail with 'message' when VAR is unset or empty.\n")
+	fmt.Fprintf(out, "      ${VAR:+alt}       'alt' when VAR is set and non-empty, otherwise empty.\n")
+	fmt.Fprintf(out, "      $${VAR}           Literal ${VAR}, not substituted.\n")
+	fmt.Fprintf(out, "      ${{ expr }}       Result of a sandboxed expression, e.g. ${{ upper(ENV) + \"-\" + .app.name }}.\n")
+	fmt.Fprintf(out, "    --strict-vars\tFail and list every unresolved placeholder with its config path.\n\n")
 	fmt.Fprintf(out, "  Output & Formatting:\n")
 	fmt.Fprintf(out, "    -of <path>\tWrite output to file. Extension determines format, or use with -oX flags.\n")
-	fmt.Fprintf(out, "    -oj, -oy, -ot, -oe\n\t\tOutput in a specific format.\n\n")
+	fmt.Fprintf(out, "    -oj, -oy, -ot, -oe\n\t\tOutput in a specific format.\n")
+	fmt.Fprintf(out, "    --template <path>\n\t\tRender a Go text/template file against the final config (to -of or stdout).\n\n")
 	fmt.Fprintf(out, "  Behavior & Logging:\n")
 	fmt.Fprintf(out, "    (Default behavior is quiet; no informational or debug logs are printed unless specified.)\n")
 	fmt.Fprintf(out, "    -c\t\tUse case-sensitive key matching (default is case-insensitive).\n")
 	fmt.Fprintf(out, "    -m\t\tMerge arrays by union with deduplication instead of replacing.\n")
+	fmt.Fprintf(out, "    --null-deletes\n\t\tTreat null values in later sources as deleting the key ($delete: true).\n")
+	fmt.Fprintf(out, "    --override-report\n\t\tPrint every key that a later source overrode, grouped by source, to stderr.\n")
+	fmt.Fprintf(out, "    --shape-conflicts <warn|error>\n\t\tLog level for values replaced by a different shape, e.g. a map by a string (default warn).\n")
+	fmt.Fprintf(out, "    --strict-shapes\tFail when a source replaces a value with a different shape.\n")
 	fmt.Fprintf(out, "    -v\t\tEnable informational (INFO) logging.\n")
 	fmt.Fprintf(out, "    -d\t\tEnable debug (DEBUG and INFO) logging. Overrides -v.\n")
 	fmt.Fprintf(out, "    -h\t\tShow this help message.\n\n")
@@ -9,8 +9,10 @@ import (
 
 // Environment handles loading configuration from environment variables.
 type Environment struct {
-	keyPrefix string
-	varPrefix string
+	keyPrefix     string
+	varPrefix     string
+	stringPaths   []string
+	caseSensitive bool
 }
 
 // NewEnvironment creates a new environment loader with default prefixes.
@@ -29,6 +31,31 @@ func NewEnvironmentWithPrefixes(keyPrefix, varPrefix string) *Environment {
 	}
 }
 
+// KeepStrings makes Load keep the values of config keys that match one of
+// the path patterns as strings instead of inferring their type, so that
+// values such as "0123" are not read as numbers.
+func (e *Environment) KeepStrings(patterns []string) *Environment {
+	e.stringPaths = patterns
+	return e
+}
+
+// CaseSensitive makes Load match config keys exactly, as the -c flag does,
+// instead of ignoring their case.
+func (e *Environment) CaseSensitive(enabled bool) *Environment {
+	e.caseSensitive = enabled
+	return e
+}
+
+// keepString reports whether the value of configKey is kept as a string.
+func (e *Environment) keepString(configKey string) bool {
+	for _, pattern := range e.stringPaths {
+		if util.MatchPath(pattern, configKey, e.caseSensitive) {
+			return true
+		}
+	}
+	return false
+}
+
 // LoadResult contains the results of loading from environment.
 type LoadResult struct {
 	Config *Config
@@ -37,9 +64,12 @@ type LoadResult struct {
 
 // Load loads configuration and variables from environment variables.
 // Returns configuration from KONFIGO_KEY_ variables and variables from KONFIGO_VAR_ variables.
-func (e *Environment) Load() *LoadResult {
+// A KONFIGO_KEY_ variable whose key is not a valid path is skipped with a
+// warning that names it.
+func (e *Environment) Load() (*LoadResult, error) {
 	config := New()
 	vars := make(map[string]string)
+	paths := util.Paths{CaseSensitive: e.caseSensitive}
 
 	for _, envVar := range os.Environ() {
 		parts := strings.SplitN(envVar, "=", 2)
@@ -54,10 +84,16 @@ func (e *Environment) Load() *LoadResult {
 			logger.Debug("  - Loading from env config: %s -> %s", key, configKey)
 
 			// Apply type inference to the environment variable value
-			typedValue := util.InferType(value)
-			logger.Debug("    Type inference: '%s' (%T) -> %v (%T)", value, value, typedValue, typedValue)
+			var typedValue interface{} = value
+			if !e.keepString(configKey) {
+				typedValue = util.InferType(value)
+				logger.Debug("    Type inference: '%s' (%T) -> %v (%T)", value, value, typedValue, typedValue)
+			}
 
-			util.SetNestedValue(config.Data, configKey, typedValue)
+			if err := paths.Set(config.Data, configKey, typedValue); err != nil {
+				logger.Warn("Ignoring environment variable %s: %v", key, err)
+				continue
+			}
 			config.Sources[configKey] = "environment:" + key
 		} else if strings.HasPrefix(key, e.varPrefix) {
 			varName := strings.TrimPrefix(key, e.varPrefix)
@@ -69,7 +105,7 @@ func (e *Environment) Load() *LoadResult {
 	return &LoadResult{
 		Config: config,
 		Vars:   vars,
-	}
+	}, nil
 }
 
 // GetKeyPrefix returns the current key prefix.
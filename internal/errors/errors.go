@@ -33,6 +33,9 @@ const (
 	ErrorTypeConfigMerge    ErrorType = "CONFIG_MERGE"
 	ErrorTypeImmutableField ErrorType = "IMMUTABLE_FIELD"
 
+	// Output errors
+	ErrorTypeTemplate ErrorType = "TEMPLATE"
+
 	// CLI errors
 	ErrorTypeCLIFlag       ErrorType = "CLI_FLAG"
 	ErrorTypeCLIValidation ErrorType = "CLI_VALIDATION"
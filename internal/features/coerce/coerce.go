@@ -0,0 +1,352 @@
+// Package coerce converts configuration values to the type a schema
+// declares for them.
+//
+// Environment variables, .env and INI files only hold strings, and
+// KONFIGO_KEY_ values are typed by guessing (see util.InferType). A coerce
+// definition fixes the type of a path:
+//
+//	coerce:
+//	  - path: server.port
+//	    type: int
+//	  - path: features.*.enabled   # wildcards convert every match
+//	    type: bool
+//	  - path: cors.origins
+//	    type: list                 # "a.com, b.com" -> [a.com, b.com]
+//	  - path: zip
+//	    type: string               # "0123" stays "0123"
+//
+// Values that cannot be converted fail with an error naming the path.
+package coerce
+
+import (
+	"fmt"
+	"konfigo/internal/logger"
+	"konfigo/internal/util"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Target types.
+const (
+	TypeInt      = "int"
+	TypeFloat    = "float"
+	TypeBool     = "bool"
+	TypeDuration = "duration"
+	TypeByteSize = "bytesize"
+	TypeList     = "list"
+	TypeString   = "string"
+)
+
+// converters maps the scalar target types to their conversion.
+var converters = map[string]func(interface{}) (interface{}, error){
+	TypeInt:      toInt,
+	TypeFloat:    toFloat,
+	TypeBool:     toBool,
+	TypeDuration: toDuration,
+	TypeByteSize: toByteSize,
+	TypeString:   toString,
+}
+
+// Definition is an entry of the coerce section.
+type Definition struct {
+	Path string `yaml:"path" json:"path"`
+	Type string `yaml:"type" json:"type"`
+	// Separator splits strings into lists; the default is ",".
+	Separator string `yaml:"separator,omitempty" json:"separator,omitempty"`
+	// ItemType converts the items of a list.
+	ItemType string `yaml:"itemType,omitempty" json:"itemType,omitempty"`
+}
+
+// types returns the names of the target types, sorted.
+func types() []string {
+	names := []string{TypeList}
+	for name := range converters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ValidateDefinition checks a definition without applying it.
+func ValidateDefinition(def Definition) error {
+	if def.Path == "" {
+		return fmt.Errorf("coerce: 'path' is required")
+	}
+	if _, err := util.ParsePath(def.Path); err != nil {
+		return fmt.Errorf("coerce '%s': %w", def.Path, err)
+	}
+	if def.Type != TypeList && converters[def.Type] == nil {
+		return fmt.Errorf("coerce '%s': unsupported type '%s'%s; use one of %s",
+			def.Path, def.Type, util.DidYouMean(def.Type, types()), strings.Join(types(), ", "))
+	}
+	if def.Type != TypeList && (def.Separator != "" || def.ItemType != "") {
+		return fmt.Errorf("coerce '%s': separator and itemType only apply to type list", def.Path)
+	}
+	if def.ItemType != "" && converters[def.ItemType] == nil {
+		return fmt.Errorf("coerce '%s': unsupported itemType '%s'%s", def.Path, def.ItemType, util.DidYouMean(def.ItemType, types()))
+	}
+	return nil
+}
+
+// ValidateDefinitions checks every definition.
+func ValidateDefinitions(defs []Definition) error {
+	for i, def := range defs {
+		if err := ValidateDefinition(def); err != nil {
+			return fmt.Errorf("coerce[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// StringPaths returns the paths that defs convert to strings, whose values
+// should be read without guessing their type.
+func StringPaths(defs []Definition) []string {
+	var paths []string
+	for _, def := range defs {
+		if def.Type == TypeString {
+			paths = append(paths, def.Path)
+		}
+	}
+	return paths
+}
+
+// Apply converts the values at the paths of defs, in order. Null values are
+// left alone. With skipPlaceholders, strings that hold a ${...} placeholder
+// are left for a later call, once variables are substituted. Paths are
+// looked up with paths.
+func Apply(config map[string]interface{}, defs []Definition, skipPlaceholders bool, paths util.Paths) error {
+	if err := ValidateDefinitions(defs); err != nil {
+		return err
+	}
+	for _, def := range defs {
+		for _, path := range paths.Expand(config, def.Path) {
+			value, _ := paths.Get(config, path)
+			if value == nil {
+				continue
+			}
+			if s, ok := value.(string); ok && skipPlaceholders && strings.Contains(s, "${") {
+				logger.Debug("  - Not coercing '%s' until its placeholders are substituted", path)
+				continue
+			}
+			converted, err := Convert(value, def)
+			if err != nil {
+				return fmt.Errorf("coerce '%s': %w", path, err)
+			}
+			if err := paths.Set(config, path, converted); err != nil {
+				return fmt.Errorf("coerce '%s': %w", path, err)
+			}
+			logger.Debug("  - Coerced '%s' to %s: %v", path, def.Type, converted)
+		}
+	}
+	return nil
+}
+
+// Convert converts a value to the type of def.
+func Convert(value interface{}, def Definition) (interface{}, error) {
+	if def.Type != TypeList {
+		convert := converters[def.Type]
+		if convert == nil {
+			return nil, fmt.Errorf("unsupported type '%s'", def.Type)
+		}
+		return convert(value)
+	}
+
+	var items []interface{}
+	switch v := value.(type) {
+	case []interface{}:
+		items = v
+	case string:
+		sep := def.Separator
+		if sep == "" {
+			sep = ","
+		}
+		items = []interface{}{}
+		if strings.TrimSpace(v) != "" {
+			for _, item := range strings.Split(v, sep) {
+				items = append(items, strings.TrimSpace(item))
+			}
+		}
+	case map[string]interface{}:
+		return nil, cannot(v, TypeList)
+	default:
+		items = []interface{}{v}
+	}
+	if def.ItemType == "" {
+		return items, nil
+	}
+	converted := make([]interface{}, len(items))
+	for i, item := range items {
+		c, err := converters[def.ItemType](item)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %w", i, err)
+		}
+		converted[i] = c
+	}
+	return converted, nil
+}
+
+// cannot returns the error for a value that does not convert to target.
+func cannot(value interface{}, target string) error {
+	switch v := value.(type) {
+	case string:
+		return fmt.Errorf("cannot convert %q to %s", v, target)
+	case map[string]interface{}:
+		return fmt.Errorf("cannot convert a mapping to %s", target)
+	case []interface{}:
+		return fmt.Errorf("cannot convert a list to %s", target)
+	}
+	return fmt.Errorf("cannot convert %v to %s", value, target)
+}
+
+// number returns the value of a numeric type as a float64.
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+// integer returns an integer as an int when it fits, like util.InferType.
+func integer(n int64) interface{} {
+	if n >= math.MinInt32 && n <= math.MaxInt32 {
+		return int(n)
+	}
+	return n
+}
+
+func toInt(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int, int64:
+		return v, nil
+	case uint64:
+		if v > math.MaxInt64 {
+			return nil, cannot(value, "int")
+		}
+		return integer(int64(v)), nil
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return nil, cannot(value, "int")
+		}
+		return integer(n), nil
+	}
+	if f, ok := number(value); ok && f == math.Trunc(f) && math.Abs(f) < 1<<63 {
+		return integer(int64(f)), nil
+	}
+	return nil, cannot(value, "int")
+}
+
+func toFloat(value interface{}) (interface{}, error) {
+	if f, ok := number(value); ok {
+		return f, nil
+	}
+	if s, ok := value.(string); ok {
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return f, nil
+		}
+	}
+	return nil, cannot(value, "float")
+}
+
+func toBool(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "true", "yes", "y", "on", "1":
+			return true, nil
+		case "false", "no", "n", "off", "0":
+			return false, nil
+		}
+	}
+	if f, ok := number(value); ok && (f == 0 || f == 1) {
+		return f == 1, nil
+	}
+	return nil, cannot(value, "bool")
+}
+
+// toDuration normalizes a duration such as "90s" to its Go form ("1m30s").
+func toDuration(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		if _, isNumber := number(value); isNumber {
+			return nil, fmt.Errorf("cannot convert %v to duration: a unit is required, e.g. \"%vs\"", value, value)
+		}
+		return nil, cannot(value, "duration")
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return nil, cannot(value, "duration")
+	}
+	return d.String(), nil
+}
+
+// byteUnits maps the units of a byte size, lower-cased, to their factor.
+// Decimal units (KB, MB) are powers of 1000, binary units (KiB, Ki) powers
+// of 1024.
+var byteUnits = func() map[string]float64 {
+	units := map[string]float64{"": 1, "b": 1}
+	for i, prefix := range []string{"k", "m", "g", "t", "p"} {
+		decimal := math.Pow(1000, float64(i+1))
+		binary := math.Pow(1024, float64(i+1))
+		units[prefix] = decimal
+		units[prefix+"b"] = decimal
+		units[prefix+"i"] = binary
+		units[prefix+"ib"] = binary
+	}
+	return units
+}()
+
+// toByteSize converts a size such as "512MiB" or "1.5GB" to a number of bytes.
+func toByteSize(value interface{}) (interface{}, error) {
+	if _, ok := number(value); ok {
+		return toInt(value)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return nil, cannot(value, "bytesize")
+	}
+	trimmed := strings.TrimSpace(s)
+	split := strings.IndexFunc(trimmed, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if split < 0 {
+		split = len(trimmed)
+	}
+	n, err := strconv.ParseFloat(trimmed[:split], 64)
+	factor, known := byteUnits[strings.ToLower(strings.TrimSpace(trimmed[split:]))]
+	if err != nil || !known {
+		return nil, cannot(value, "bytesize")
+	}
+	bytes := n * factor
+	if bytes != math.Trunc(bytes) || bytes >= 1<<63 {
+		return nil, fmt.Errorf("cannot convert %q to bytesize: not a whole number of bytes", s)
+	}
+	return integer(int64(bytes)), nil
+}
+
+func toString(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int, int64, uint64:
+		return fmt.Sprint(v), nil
+	}
+	return nil, cannot(value, "string")
+}
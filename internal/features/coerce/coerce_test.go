@@ -0,0 +1,126 @@
+package coerce
+
+import (
+	"konfigo/internal/util"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		def   Definition
+		want  interface{}
+	}{
+		{"0123", Definition{Type: TypeInt}, 123},
+		{" 42 ", Definition{Type: TypeInt}, 42},
+		{8080.0, Definition{Type: TypeInt}, 8080},
+		{"5000000000", Definition{Type: TypeInt}, int64(5000000000)},
+		{uint64(42), Definition{Type: TypeInt}, 42},
+		{uint64(1<<63 - 1), Definition{Type: TypeInt}, int64(1<<63 - 1)},
+		{"1.5", Definition{Type: TypeFloat}, 1.5},
+		{3, Definition{Type: TypeFloat}, 3.0},
+		{"yes", Definition{Type: TypeBool}, true},
+		{"OFF", Definition{Type: TypeBool}, false},
+		{1, Definition{Type: TypeBool}, true},
+		{"90s", Definition{Type: TypeDuration}, "1m30s"},
+		{"1.5h", Definition{Type: TypeDuration}, "1h30m0s"},
+		{"512MiB", Definition{Type: TypeByteSize}, 536870912},
+		{"1.5 KB", Definition{Type: TypeByteSize}, 1500},
+		{"2g", Definition{Type: TypeByteSize}, 2000000000},
+		{"1024", Definition{Type: TypeByteSize}, 1024},
+		{4096, Definition{Type: TypeByteSize}, 4096},
+		{"a.com, b.com,c.com", Definition{Type: TypeList}, []interface{}{"a.com", "b.com", "c.com"}},
+		{"80;443", Definition{Type: TypeList, Separator: ";", ItemType: TypeInt}, []interface{}{80, 443}},
+		{"", Definition{Type: TypeList}, []interface{}{}},
+		{"solo", Definition{Type: TypeList}, []interface{}{"solo"}},
+		{[]interface{}{"1", "0"}, Definition{Type: TypeList, ItemType: TypeBool}, []interface{}{true, false}},
+		{123, Definition{Type: TypeString}, "123"},
+		{0.25, Definition{Type: TypeString}, "0.25"},
+		{false, Definition{Type: TypeString}, "false"},
+	}
+	for _, tt := range tests {
+		got, err := Convert(tt.value, tt.def)
+		if err != nil {
+			t.Errorf("Convert(%#v, %s): %v", tt.value, tt.def.Type, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Convert(%#v, %s) = %#v, want %#v", tt.value, tt.def.Type, got, tt.want)
+		}
+	}
+}
+
+func TestConvert_Errors(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		def   Definition
+		want  string
+	}{
+		{"abc", Definition{Type: TypeInt}, `cannot convert "abc" to int`},
+		{1.5, Definition{Type: TypeInt}, "cannot convert 1.5 to int"},
+		{uint64(1 << 63), Definition{Type: TypeInt}, "cannot convert 9223372036854775808 to int"},
+		{"maybe", Definition{Type: TypeBool}, `cannot convert "maybe" to bool`},
+		{30, Definition{Type: TypeDuration}, "a unit is required"},
+		{"10 parsecs", Definition{Type: TypeByteSize}, "to bytesize"},
+		{"1.5B", Definition{Type: TypeByteSize}, "not a whole number of bytes"},
+		{map[string]interface{}{}, Definition{Type: TypeString}, "cannot convert a mapping to string"},
+		{"1,x", Definition{Type: TypeList, ItemType: TypeInt}, `item 1: cannot convert "x" to int`},
+	}
+	for _, tt := range tests {
+		_, err := Convert(tt.value, tt.def)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Convert(%#v, %s) = %v, want error containing %q", tt.value, tt.def.Type, err, tt.want)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	config := map[string]interface{}{
+		"server":   map[string]interface{}{"port": "8080", "host": nil},
+		"features": map[string]interface{}{"a": map[string]interface{}{"on": "yes"}, "b": map[string]interface{}{"on": "0"}},
+		"timeout":  "${TIMEOUT}",
+	}
+	defs := []Definition{
+		{Path: "server.port", Type: TypeInt},
+		{Path: "server.host", Type: TypeString},
+		{Path: "features.*.on", Type: TypeBool},
+		{Path: "timeout", Type: TypeDuration},
+	}
+	if err := Apply(config, defs, true, util.Paths{}); err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	want := map[string]interface{}{
+		"server":   map[string]interface{}{"port": 8080, "host": nil},
+		"features": map[string]interface{}{"a": map[string]interface{}{"on": true}, "b": map[string]interface{}{"on": false}},
+		"timeout":  "${TIMEOUT}",
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Errorf("config = %v, want %v", config, want)
+	}
+
+	err := Apply(config, defs, false, util.Paths{})
+	if err == nil || !strings.Contains(err.Error(), "coerce 'timeout'") {
+		t.Errorf("expected an error naming the path, got %v", err)
+	}
+}
+
+func TestValidateDefinition(t *testing.T) {
+	tests := []struct {
+		def  Definition
+		want string
+	}{
+		{Definition{Type: TypeInt}, "'path' is required"},
+		{Definition{Path: "a", Type: "integer"}, "unsupported type 'integer'"},
+		{Definition{Path: "a", Type: "boll"}, "did you mean 'bool'?"},
+		{Definition{Path: "a", Type: TypeInt, Separator: ";"}, "only apply to type list"},
+		{Definition{Path: "a", Type: TypeList, ItemType: "lst"}, "unsupported itemType"},
+	}
+	for _, tt := range tests {
+		err := ValidateDefinition(tt.def)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ValidateDefinition(%+v) = %v, want error containing %q", tt.def, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+// Package condition implements the `when:` conditions of schema definitions.
+//
+// A condition is either an expression (see the expression package):
+//
+//	when: 'ENV == "prod" && .tls.enabled'
+//
+// or a structured test on a variable (var) or a config path (path):
+//
+//	when: {equals: {var: ENV, value: prod}}
+//	when: {in: {path: region, values: [eu-west-1, eu-central-1]}}
+//	when: {exists: {path: tls.cert}}
+//	when: {matches: {var: VERSION, pattern: '^2\.'}}
+//
+// combined with and, or and not:
+//
+//	when:
+//	  and:
+//	    - equals: {var: ENV, value: prod}
+//	    - not: {exists: {path: tls.disabled}}
+//
+// Values are compared by their string form, so the variable ENV=8080 equals
+// the number 8080. Tests on a variable or path that is not set are false.
+package condition
+
+import (
+	"fmt"
+	"konfigo/internal/features/expression"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Condition is a `when:` condition. Exactly one of its fields is set.
+type Condition struct {
+	// Expression is set when the condition is written as a string.
+	Expression string `yaml:"-" json:"-"`
+
+	Equals  *Test        `yaml:"equals,omitempty" json:"equals,omitempty"`
+	In      *Test        `yaml:"in,omitempty" json:"in,omitempty"`
+	Exists  *Test        `yaml:"exists,omitempty" json:"exists,omitempty"`
+	Matches *Test        `yaml:"matches,omitempty" json:"matches,omitempty"`
+	And     []*Condition `yaml:"and,omitempty" json:"and,omitempty"`
+	Or      []*Condition `yaml:"or,omitempty" json:"or,omitempty"`
+	Not     *Condition   `yaml:"not,omitempty" json:"not,omitempty"`
+}
+
+// Test is the operand of a structured condition: a variable or a config
+// path, and what it is compared with.
+type Test struct {
+	Var     string        `yaml:"var,omitempty" json:"var,omitempty"`
+	Path    string        `yaml:"path,omitempty" json:"path,omitempty"`
+	Value   interface{}   `yaml:"value,omitempty" json:"value,omitempty"`
+	Values  []interface{} `yaml:"values,omitempty" json:"values,omitempty"`
+	Pattern string        `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+}
+
+// UnmarshalYAML decodes a condition written as an expression string or as
+// a mapping.
+func (c *Condition) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*c = Condition{Expression: node.Value}
+		return nil
+	}
+	type plain Condition
+	return node.Decode((*plain)(c))
+}
+
+// MarshalYAML encodes an expression condition as its string.
+func (c *Condition) MarshalYAML() (interface{}, error) {
+	if c.Expression != "" {
+		return c.Expression, nil
+	}
+	type plain Condition
+	return (*plain)(c), nil
+}
+
+// Validate checks the structure of a condition without evaluating it.
+func (c *Condition) Validate() error {
+	set := c.operators()
+	if len(set) != 1 {
+		if len(set) == 0 {
+			return fmt.Errorf("condition needs an expression or one of equals, in, exists, matches, and, or, not")
+		}
+		return fmt.Errorf("condition sets %s; use and/or to combine them", strings.Join(set, ", "))
+	}
+
+	switch {
+	case c.Expression != "":
+		if _, err := expression.Compile(c.Expression); err != nil {
+			return fmt.Errorf("condition '%s': %w", c.Expression, err)
+		}
+	case c.Equals != nil:
+		if err := c.Equals.validate("equals"); err != nil {
+			return err
+		}
+		if c.Equals.Value == nil {
+			return fmt.Errorf("equals: 'value' is required")
+		}
+	case c.In != nil:
+		if err := c.In.validate("in"); err != nil {
+			return err
+		}
+		if len(c.In.Values) == 0 {
+			return fmt.Errorf("in: 'values' is required")
+		}
+	case c.Exists != nil:
+		return c.Exists.validate("exists")
+	case c.Matches != nil:
+		if err := c.Matches.validate("matches"); err != nil {
+			return err
+		}
+		if c.Matches.Pattern == "" {
+			return fmt.Errorf("matches: 'pattern' is required")
+		}
+		if _, err := regexp.Compile(c.Matches.Pattern); err != nil {
+			return fmt.Errorf("matches: invalid pattern '%s': %w", c.Matches.Pattern, err)
+		}
+	case c.Not != nil:
+		if err := c.Not.Validate(); err != nil {
+			return fmt.Errorf("not: %w", err)
+		}
+	default:
+		op, list := "and", c.And
+		if c.Or != nil {
+			op, list = "or", c.Or
+		}
+		if len(list) == 0 {
+			return fmt.Errorf("%s: at least one condition is required", op)
+		}
+		for i, sub := range list {
+			if sub == nil {
+				return fmt.Errorf("%s[%d]: condition is empty", op, i)
+			}
+			if err := sub.Validate(); err != nil {
+				return fmt.Errorf("%s[%d]: %w", op, i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// operators returns the names of the operators set in c.
+func (c *Condition) operators() []string {
+	var set []string
+	for _, op := range []struct {
+		name string
+		set  bool
+	}{
+		{"expression", c.Expression != ""},
+		{"equals", c.Equals != nil},
+		{"in", c.In != nil},
+		{"exists", c.Exists != nil},
+		{"matches", c.Matches != nil},
+		{"and", c.And != nil},
+		{"or", c.Or != nil},
+		{"not", c.Not != nil},
+	} {
+		if op.set {
+			set = append(set, op.name)
+		}
+	}
+	return set
+}
+
+// validate checks that a test names exactly one of a variable or a path.
+func (t *Test) validate(op string) error {
+	if (t.Var == "") == (t.Path == "") {
+		return fmt.Errorf("%s: set exactly one of 'var' or 'path'", op)
+	}
+	return nil
+}
+
+// Evaluate reports whether the condition holds. Variables are looked up
+// with env.Lookup and config paths with env.Reference.
+func (c *Condition) Evaluate(env expression.Env) (bool, error) {
+	switch {
+	case c.Expression != "":
+		val, err := expression.Evaluate(c.Expression, env)
+		if err != nil {
+			return false, fmt.Errorf("condition '%s': %w", c.Expression, err)
+		}
+		return expression.Truthy(val), nil
+	case c.Equals != nil:
+		val, ok := c.Equals.lookup(env)
+		return ok && sameValue(val, c.Equals.Value), nil
+	case c.In != nil:
+		val, ok := c.In.lookup(env)
+		if !ok {
+			return false, nil
+		}
+		for _, allowed := range c.In.Values {
+			if sameValue(val, allowed) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case c.Exists != nil:
+		_, ok := c.Exists.lookup(env)
+		return ok, nil
+	case c.Matches != nil:
+		val, ok := c.Matches.lookup(env)
+		if !ok {
+			return false, nil
+		}
+		re, err := regexp.Compile(c.Matches.Pattern)
+		if err != nil {
+			return false, fmt.Errorf("matches: invalid pattern '%s': %w", c.Matches.Pattern, err)
+		}
+		return re.MatchString(fmt.Sprint(val)), nil
+	case c.Not != nil:
+		ok, err := c.Not.Evaluate(env)
+		return !ok, err
+	case c.And != nil:
+		for _, sub := range c.And {
+			if ok, err := sub.Evaluate(env); err != nil || !ok {
+				return false, err
+			}
+		}
+		return true, nil
+	case c.Or != nil:
+		for _, sub := range c.Or {
+			if ok, err := sub.Evaluate(env); err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	}
+	return false, fmt.Errorf("condition is empty")
+}
+
+// lookup returns the value of the variable or path a test names.
+func (t *Test) lookup(env expression.Env) (interface{}, bool) {
+	if t.Var != "" {
+		return env.Lookup(t.Var)
+	}
+	return env.Reference(strings.TrimPrefix(t.Path, "."))
+}
+
+// sameValue compares two scalar values by their string form.
+func sameValue(a, b interface{}) bool {
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+// Variables returns the names of the variables the condition reads.
+func (c *Condition) Variables() []string {
+	var names []string
+	c.walk(func(c *Condition) {
+		if c.Expression != "" {
+			if expr, err := expression.Compile(c.Expression); err == nil {
+				names = append(names, expr.Variables()...)
+			}
+		}
+		for _, t := range []*Test{c.Equals, c.In, c.Exists, c.Matches} {
+			if t != nil && t.Var != "" {
+				names = append(names, t.Var)
+			}
+		}
+	})
+	return names
+}
+
+// walk calls fn for c and every condition nested in it.
+func (c *Condition) walk(fn func(*Condition)) {
+	if c == nil {
+		return
+	}
+	fn(c)
+	for _, sub := range c.And {
+		sub.walk(fn)
+	}
+	for _, sub := range c.Or {
+		sub.walk(fn)
+	}
+	c.Not.walk(fn)
+}
+
+// String returns a short description of the condition for log messages.
+func (c *Condition) String() string {
+	switch {
+	case c.Expression != "":
+		return c.Expression
+	case c.Equals != nil:
+		return fmt.Sprintf("%s == %v", c.Equals.operand(), c.Equals.Value)
+	case c.In != nil:
+		return fmt.Sprintf("%s in %v", c.In.operand(), c.In.Values)
+	case c.Exists != nil:
+		return fmt.Sprintf("exists %s", c.Exists.operand())
+	case c.Matches != nil:
+		return fmt.Sprintf("%s matches /%s/", c.Matches.operand(), c.Matches.Pattern)
+	case c.Not != nil:
+		return fmt.Sprintf("not (%s)", c.Not)
+	case c.And != nil, c.Or != nil:
+		op, list := " and ", c.And
+		if c.Or != nil {
+			op, list = " or ", c.Or
+		}
+		parts := make([]string, len(list))
+		for i, sub := range list {
+			parts[i] = "(" + sub.String() + ")"
+		}
+		return strings.Join(parts, op)
+	}
+	return "(empty)"
+}
+
+// operand names the variable or path of a test.
+func (t *Test) operand() string {
+	if t.Var != "" {
+		return t.Var
+	}
+	return "." + strings.TrimPrefix(t.Path, ".")
+}
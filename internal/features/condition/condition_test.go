@@ -0,0 +1,100 @@
+package condition
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// testEnv serves variables and config references from maps.
+type testEnv struct {
+	vars   map[string]interface{}
+	config map[string]interface{}
+}
+
+func (e testEnv) Lookup(name string) (interface{}, bool) {
+	v, ok := e.vars[name]
+	return v, ok
+}
+
+func (e testEnv) Reference(path string) (interface{}, bool) {
+	v, ok := e.config[path]
+	return v, ok
+}
+
+func parse(t *testing.T, src string) *Condition {
+	t.Helper()
+	var c Condition
+	if err := yaml.Unmarshal([]byte(src), &c); err != nil {
+		t.Fatalf("unmarshal %q: %v", src, err)
+	}
+	if err := c.Validate(); err != nil {
+		t.Fatalf("validate %q: %v", src, err)
+	}
+	return &c
+}
+
+func TestEvaluate(t *testing.T) {
+	env := testEnv{
+		vars:   map[string]interface{}{"ENV": "prod", "PORT": "8080", "VERSION": "2.4.1"},
+		config: map[string]interface{}{"tls.cert": "/etc/cert.pem", "region": "eu-west-1", "debug": false},
+	}
+	tests := []struct {
+		src  string
+		want bool
+	}{
+		{`ENV == "prod"`, true},
+		{`ENV == "dev" || .debug`, false},
+		{`{equals: {var: ENV, value: prod}}`, true},
+		{`{equals: {var: PORT, value: 8080}}`, true},
+		{`{equals: {var: MISSING, value: ""}}`, false},
+		{`{in: {path: region, values: [eu-west-1, eu-central-1]}}`, true},
+		{`{exists: {path: tls.cert}}`, true},
+		{`{exists: {path: tls.key}}`, false},
+		{`{matches: {var: VERSION, pattern: '^2\.'}}`, true},
+		{`{not: {exists: {var: ENV}}}`, false},
+		{"and:\n  - equals: {var: ENV, value: prod}\n  - 'PORT == \"8080\"'", true},
+		{"or:\n  - equals: {var: ENV, value: dev}\n  - exists: {path: tls.key}", false},
+	}
+	for _, tt := range tests {
+		got, err := parse(t, tt.src).Evaluate(env)
+		if err != nil {
+			t.Errorf("Evaluate(%q): %v", tt.src, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Evaluate(%q) = %v, want %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := map[string]string{
+		`{}`: "needs an expression",
+		`{equals: {var: ENV, value: prod}, exists: {var: ENV}}`: "use and/or",
+		`{equals: {value: prod}}`:                               "exactly one of 'var' or 'path'",
+		`{equals: {var: ENV}}`:                                  "'value' is required",
+		`{in: {var: ENV}}`:                                      "'values' is required",
+		`{matches: {var: ENV, pattern: "("}}`:                   "invalid pattern",
+		`'ENV =='`:                                              "expression error",
+		`{and: []}`:                                             "at least one condition",
+	}
+	for src, want := range tests {
+		var c Condition
+		if err := yaml.Unmarshal([]byte(src), &c); err != nil {
+			t.Fatalf("unmarshal %q: %v", src, err)
+		}
+		if err := c.Validate(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate(%q) = %v, want error containing %q", src, err, want)
+		}
+	}
+}
+
+func TestVariables(t *testing.T) {
+	c := parse(t, "and:\n  - equals: {var: ENV, value: prod}\n  - 'REGION == \"eu\" && .tls.enabled'\n  - not: {exists: {path: debug}}")
+	got := strings.Join(c.Variables(), ",")
+	if got != "ENV,REGION" {
+		t.Errorf("Variables() = %s, want ENV,REGION", got)
+	}
+}
@@ -0,0 +1,126 @@
+// Package defaults fills configuration values that the sources leave out.
+//
+// Each definition names a path and the value it takes when the path is
+// absent:
+//
+//	defaults:
+//	  - path: server.port
+//	    value: 8080
+//	  - path: services.*.timeout   # every entry of services
+//	    value: 30s
+//	  - path: logging.level
+//	    value: info
+//	    fillNull: true             # also replace an explicit null
+//
+// Values keep the type they are written with. Wildcards may appear in the
+// parent of the path, so that every matching map gets the key; the last
+// segment must be a plain key.
+package defaults
+
+import (
+	"fmt"
+	"konfigo/internal/logger"
+	"konfigo/internal/util"
+	"strings"
+)
+
+// Definition is an entry of the defaults section.
+type Definition struct {
+	Path  string      `yaml:"path" json:"path"`
+	Value interface{} `yaml:"value" json:"value"`
+	// FillNull also replaces values that are set to null. By default only
+	// absent paths are filled.
+	FillNull bool `yaml:"fillNull,omitempty" json:"fillNull,omitempty"`
+}
+
+// ValidateDefinition checks a definition without applying it.
+func ValidateDefinition(def Definition) error {
+	if def.Path == "" {
+		return fmt.Errorf("default: 'path' is required")
+	}
+	segments, err := util.ParsePath(def.Path)
+	if err != nil {
+		return fmt.Errorf("default for '%s': %w", def.Path, err)
+	}
+	last := segments[len(segments)-1]
+	if last.Kind != util.SegmentKey || !last.Quoted && strings.Contains(last.Key, "*") {
+		return fmt.Errorf("default for '%s': the path must end with a key", def.Path)
+	}
+	if def.Value == nil {
+		return fmt.Errorf("default for '%s': 'value' is required", def.Path)
+	}
+	return nil
+}
+
+// ValidateDefinitions checks every definition.
+func ValidateDefinitions(defs []Definition) error {
+	for i, def := range defs {
+		if err := ValidateDefinition(def); err != nil {
+			return fmt.Errorf("defaults[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Apply fills the paths of defs that config does not set, in order, and
+// returns the concrete paths it filled. Paths are looked up with paths.
+func Apply(config map[string]interface{}, defs []Definition, paths util.Paths) ([]string, error) {
+	if err := ValidateDefinitions(defs); err != nil {
+		return nil, err
+	}
+	var filled []string
+	for _, def := range defs {
+		defaulted, err := apply(config, def, paths)
+		if err != nil {
+			return filled, err
+		}
+		filled = append(filled, defaulted...)
+	}
+	return filled, nil
+}
+
+// apply fills the paths one definition names.
+func apply(config map[string]interface{}, def Definition, paths util.Paths) ([]string, error) {
+	var filled []string
+	for _, path := range targets(config, def.Path, paths) {
+		current, found := paths.Get(config, path)
+		if found && (current != nil || !def.FillNull) {
+			continue
+		}
+		value, err := util.DeepCopyValue(def.Value)
+		if err != nil {
+			return filled, fmt.Errorf("default for '%s': %w", path, err)
+		}
+		if err := paths.Set(config, path, value); err != nil {
+			return filled, fmt.Errorf("default for '%s': %w", path, err)
+		}
+		logger.Debug("  - Defaulted '%s' to %v", path, value)
+		filled = append(filled, path)
+	}
+	return filled, nil
+}
+
+// targets returns the concrete paths a definition path names. A parent that
+// is a pattern is expanded against the maps config has; a plain parent is
+// created when it is missing.
+func targets(config map[string]interface{}, path string, paths util.Paths) []string {
+	parent, ok := util.ParentPath(path)
+	if !ok || !util.IsPattern(parent) {
+		return []string{path}
+	}
+	segments, _ := util.ParsePath(path)
+	key := segments[len(segments)-1].Key
+
+	var concrete []string
+	for _, p := range paths.Expand(config, parent) {
+		if value, _ := paths.Get(config, p); isMap(value) {
+			concrete = append(concrete, util.AppendKey(p, key))
+		}
+	}
+	return concrete
+}
+
+func isMap(v interface{}) bool {
+	_, ok := v.(map[string]interface{})
+	return ok
+}
@@ -0,0 +1,74 @@
+package defaults
+
+import (
+	"konfigo/internal/util"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	config := map[string]interface{}{
+		"server": map[string]interface{}{"host": "localhost", "port": nil},
+		"services": map[string]interface{}{
+			"api": map[string]interface{}{"timeout": "5s"},
+			"web": map[string]interface{}{},
+			"tag": "v1",
+		},
+		"logging": map[string]interface{}{"level": nil},
+	}
+	defs := []Definition{
+		{Path: "server.host", Value: "0.0.0.0"},
+		{Path: "server.port", Value: 8080},
+		{Path: "services.*.timeout", Value: "30s"},
+		{Path: "logging.level", Value: "info", FillNull: true},
+		{Path: "metrics.labels", Value: map[string]interface{}{"team": "core"}},
+	}
+
+	filled, err := Apply(config, defs, util.Paths{})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	want := []string{"services.web.timeout", "logging.level", "metrics.labels"}
+	if !reflect.DeepEqual(filled, want) {
+		t.Errorf("filled = %v, want %v", filled, want)
+	}
+
+	server := config["server"].(map[string]interface{})
+	if server["host"] != "localhost" || server["port"] != nil {
+		t.Errorf("set values and nulls without fillNull must be kept, got %v", server)
+	}
+	services := config["services"].(map[string]interface{})
+	if services["api"].(map[string]interface{})["timeout"] != "5s" || services["web"].(map[string]interface{})["timeout"] != "30s" {
+		t.Errorf("wildcard default: got %v", services)
+	}
+	if config["logging"].(map[string]interface{})["level"] != "info" {
+		t.Errorf("fillNull should replace null, got %v", config["logging"])
+	}
+
+	// Each path gets its own copy of the value
+	labels := config["metrics"].(map[string]interface{})["labels"].(map[string]interface{})
+	labels["team"] = "changed"
+	if defs[4].Value.(map[string]interface{})["team"] != "core" {
+		t.Error("default value was shared with the config")
+	}
+}
+
+func TestValidateDefinition(t *testing.T) {
+	tests := []struct {
+		def  Definition
+		want string
+	}{
+		{Definition{Value: 1}, "'path' is required"},
+		{Definition{Path: "services.*", Value: 1}, "must end with a key"},
+		{Definition{Path: "servers[0]", Value: 1}, "must end with a key"},
+		{Definition{Path: "a[", Value: 1}, "missing ']'"},
+		{Definition{Path: "server.port"}, "'value' is required"},
+	}
+	for _, tt := range tests {
+		err := ValidateDefinition(tt.def)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ValidateDefinition(%+v) = %v, want error containing %q", tt.def, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,583 @@
+package expression
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// maxResultLength bounds the size of strings and lists built by an expression.
+const maxResultLength = 1 << 20
+
+type literalNode struct {
+	pos   int
+	value interface{}
+}
+
+func (n *literalNode) position() int { return n.pos }
+
+func (n *literalNode) eval(Env) (interface{}, error) {
+	return normalize(n.value), nil
+}
+
+type identNode struct {
+	pos  int
+	name string
+}
+
+func (n *identNode) position() int { return n.pos }
+
+func (n *identNode) eval(env Env) (interface{}, error) {
+	v, ok := env.Lookup(n.name)
+	if !ok {
+		return nil, newError(n.pos, "unknown variable '%s'", n.name)
+	}
+	return normalize(v), nil
+}
+
+type refNode struct {
+	pos  int
+	path string
+}
+
+func (n *refNode) position() int { return n.pos }
+
+func (n *refNode) eval(env Env) (interface{}, error) {
+	v, ok := env.Reference(n.path)
+	if !ok {
+		return nil, newError(n.pos, "config path '.%s' not found", n.path)
+	}
+	return normalize(v), nil
+}
+
+type listNode struct {
+	pos   int
+	items []node
+}
+
+func (n *listNode) position() int { return n.pos }
+
+func (n *listNode) eval(env Env) (interface{}, error) {
+	out := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		v, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+type unaryNode struct {
+	pos     int
+	op      string
+	operand node
+}
+
+func (n *unaryNode) position() int { return n.pos }
+
+func (n *unaryNode) eval(env Env) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "!":
+		return !truthy(v), nil
+	case "-":
+		switch x := v.(type) {
+		case int64:
+			if x == math.MinInt64 {
+				return nil, newError(n.pos, "integer overflow")
+			}
+			return -x, nil
+		case float64:
+			return -x, nil
+		}
+		return nil, newError(n.pos, "cannot negate %s", typeName(v))
+	}
+	return nil, newError(n.pos, "unknown operator '%s'", n.op)
+}
+
+type logicalNode struct {
+	pos         int
+	op          string
+	left, right node
+}
+
+func (n *logicalNode) position() int { return n.pos }
+
+func (n *logicalNode) eval(env Env) (interface{}, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" && !truthy(l) {
+		return false, nil
+	}
+	if n.op == "||" && truthy(l) {
+		return true, nil
+	}
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(r), nil
+}
+
+type ternaryNode struct {
+	pos             int
+	cond            node
+	then, otherwise node
+}
+
+func (n *ternaryNode) position() int { return n.pos }
+
+func (n *ternaryNode) eval(env Env) (interface{}, error) {
+	c, err := n.cond.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(c) {
+		return n.then.eval(env)
+	}
+	return n.otherwise.eval(env)
+}
+
+type indexNode struct {
+	pos           int
+	target, index node
+}
+
+func (n *indexNode) position() int { return n.pos }
+
+func (n *indexNode) eval(env Env) (interface{}, error) {
+	target, err := n.target.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch t := target.(type) {
+	case []interface{}:
+		i, ok := index.(int64)
+		if !ok {
+			return nil, newError(n.index.position(), "list index must be an integer, got %s", typeName(index))
+		}
+		if i < 0 {
+			i += int64(len(t))
+		}
+		if i < 0 || i >= int64(len(t)) {
+			return nil, newError(n.index.position(), "list index %d out of range (length %d)", i, len(t))
+		}
+		return normalize(t[i]), nil
+	case map[string]interface{}:
+		key, ok := index.(string)
+		if !ok {
+			return nil, newError(n.index.position(), "map key must be a string, got %s", typeName(index))
+		}
+		v, ok := t[key]
+		if !ok {
+			return nil, newError(n.index.position(), "key '%s' not found", key)
+		}
+		return normalize(v), nil
+	case string:
+		i, ok := index.(int64)
+		if !ok {
+			return nil, newError(n.index.position(), "string index must be an integer, got %s", typeName(index))
+		}
+		runes := []rune(t)
+		if i < 0 {
+			i += int64(len(runes))
+		}
+		if i < 0 || i >= int64(len(runes)) {
+			return nil, newError(n.index.position(), "string index %d out of range (length %d)", i, len(runes))
+		}
+		return string(runes[i]), nil
+	}
+	return nil, newError(n.pos, "cannot index %s", typeName(target))
+}
+
+type callNode struct {
+	pos  int
+	name string
+	args []node
+}
+
+func (n *callNode) position() int { return n.pos }
+
+func (n *callNode) eval(env Env) (interface{}, error) {
+	fn, ok := builtins[n.name]
+	if !ok {
+		return nil, newError(n.pos, "unknown function '%s'", n.name)
+	}
+	args := make([]interface{}, 0, len(n.args))
+	for _, a := range n.args {
+		v, err := a.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, v)
+	}
+	result, err := fn(args)
+	if err != nil {
+		if exprErr, ok := err.(*Error); ok {
+			return nil, exprErr
+		}
+		return nil, newError(n.pos, "%s(): %v", n.name, err)
+	}
+	return normalize(result), nil
+}
+
+type binaryNode struct {
+	pos         int
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) position() int { return n.pos }
+
+func (n *binaryNode) eval(env Env) (interface{}, error) {
+	l, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(l, r), nil
+	case "!=":
+		return !equal(l, r), nil
+	case "in":
+		return contains(r, l, n.pos)
+	case "<", "<=", ">", ">=":
+		return n.compare(l, r)
+	case "+":
+		return n.add(l, r)
+	case "-", "*", "/", "%":
+		return n.arithmetic(l, r)
+	}
+	return nil, newError(n.pos, "unknown operator '%s'", n.op)
+}
+
+// add implements numeric addition, string concatenation and list concatenation.
+func (n *binaryNode) add(l, r interface{}) (interface{}, error) {
+	if ll, ok := l.([]interface{}); ok {
+		if rl, ok := r.([]interface{}); ok {
+			if len(ll)+len(rl) > maxResultLength {
+				return nil, newError(n.pos, "list exceeds maximum length of %d", maxResultLength)
+			}
+			return append(append([]interface{}{}, ll...), rl...), nil
+		}
+	}
+	_, lString := l.(string)
+	_, rString := r.(string)
+	if lString || rString {
+		s := toString(l) + toString(r)
+		if len(s) > maxResultLength {
+			return nil, newError(n.pos, "string exceeds maximum length of %d bytes", maxResultLength)
+		}
+		return s, nil
+	}
+	return n.arithmetic(l, r)
+}
+
+// arithmetic implements numeric operators, keeping integer results when both
+// operands are integers and the result is exact.
+func (n *binaryNode) arithmetic(l, r interface{}) (interface{}, error) {
+	li, lInt := l.(int64)
+	ri, rInt := r.(int64)
+	if lInt && rInt {
+		switch n.op {
+		case "+":
+			if sum := li + ri; (sum > li) == (ri > 0) {
+				return sum, nil
+			}
+			return nil, newError(n.pos, "integer overflow")
+		case "-":
+			if diff := li - ri; (diff < li) == (ri > 0) {
+				return diff, nil
+			}
+			return nil, newError(n.pos, "integer overflow")
+		case "*":
+			product := li * ri
+			if li != 0 && (product/li != ri || li == -1 && ri == math.MinInt64) {
+				return nil, newError(n.pos, "integer overflow")
+			}
+			return product, nil
+		case "/":
+			if ri == 0 {
+				return nil, newError(n.pos, "division by zero")
+			}
+			if li == math.MinInt64 && ri == -1 {
+				return nil, newError(n.pos, "integer overflow")
+			}
+			if li%ri == 0 {
+				return li / ri, nil
+			}
+			return float64(li) / float64(ri), nil
+		case "%":
+			if ri == 0 {
+				return nil, newError(n.pos, "division by zero")
+			}
+			return li % ri, nil
+		}
+	}
+
+	lf, lok := toFloat(l)
+	rf, rok := toFloat(r)
+	if !lok || !rok {
+		return nil, newError(n.pos, "operator '%s' not supported for %s and %s", n.op, typeName(l), typeName(r))
+	}
+	var result float64
+	switch n.op {
+	case "+":
+		result = lf + rf
+	case "-":
+		result = lf - rf
+	case "*":
+		result = lf * rf
+	case "/":
+		if rf == 0 {
+			return nil, newError(n.pos, "division by zero")
+		}
+		result = lf / rf
+	case "%":
+		if rf == 0 {
+			return nil, newError(n.pos, "division by zero")
+		}
+		return math.Mod(lf, rf), nil
+	default:
+		return nil, newError(n.pos, "unknown operator '%s'", n.op)
+	}
+	if math.IsInf(result, 0) {
+		return nil, newError(n.pos, "number out of range")
+	}
+	return result, nil
+}
+
+// compare implements ordering comparisons for numbers and strings.
+func (n *binaryNode) compare(l, r interface{}) (interface{}, error) {
+	var cmp int
+	if ls, ok := l.(string); ok {
+		rs, ok := r.(string)
+		if !ok {
+			return nil, newError(n.pos, "cannot compare %s and %s", typeName(l), typeName(r))
+		}
+		cmp = strings.Compare(ls, rs)
+	} else {
+		lf, lok := toFloat(l)
+		rf, rok := toFloat(r)
+		if !lok || !rok {
+			return nil, newError(n.pos, "cannot compare %s and %s", typeName(l), typeName(r))
+		}
+		switch {
+		case lf < rf:
+			cmp = -1
+		case lf > rf:
+			cmp = 1
+		}
+	}
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// contains reports whether needle is an element of a list, a key of a map or
+// a substring of a string.
+func contains(haystack, needle interface{}, pos int) (bool, error) {
+	switch h := haystack.(type) {
+	case []interface{}:
+		for _, item := range h {
+			if equal(item, needle) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]interface{}:
+		key, ok := needle.(string)
+		if !ok {
+			return false, nil
+		}
+		_, exists := h[key]
+		return exists, nil
+	case string:
+		return strings.Contains(h, toString(needle)), nil
+	}
+	return false, newError(pos, "'in' requires a list, map or string, got %s", typeName(haystack))
+}
+
+// normalize converts Go numeric types to int64 or float64 so that operators
+// only need to handle those two numeric representations.
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case int:
+		return int64(x)
+	case int8:
+		return int64(x)
+	case int16:
+		return int64(x)
+	case int32:
+		return int64(x)
+	case uint:
+		if uint64(x) > math.MaxInt64 {
+			return float64(x)
+		}
+		return int64(x)
+	case uint8:
+		return int64(x)
+	case uint16:
+		return int64(x)
+	case uint32:
+		return int64(x)
+	case uint64:
+		if x > math.MaxInt64 {
+			return float64(x)
+		}
+		return int64(x)
+	case float32:
+		return float64(x)
+	}
+	return v
+}
+
+// floatToInt converts f to an int64 when it is a whole number in range.
+func floatToInt(f float64) (int64, bool) {
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+// toFloat converts a numeric value to float64.
+func toFloat(v interface{}) (float64, bool) {
+	switch x := normalize(v).(type) {
+	case int64:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	return 0, false
+}
+
+// equal compares two values, treating integers and floats with the same value as equal.
+func equal(l, r interface{}) bool {
+	lf, lok := toFloat(l)
+	rf, rok := toFloat(r)
+	if lok && rok {
+		return lf == rf
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+// Truthy reports the boolean interpretation of a value the way && || ! and
+// ?: do: null, false, zero, empty strings and empty collections are false.
+func Truthy(v interface{}) bool {
+	return truthy(normalize(v))
+}
+
+// truthy reports the boolean interpretation of a value: null, false, zero,
+// empty strings and empty collections are false.
+func truthy(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case int64:
+		return x != 0
+	case float64:
+		return x != 0
+	case string:
+		return x != ""
+	case []interface{}:
+		return len(x) > 0
+	case map[string]interface{}:
+		return len(x) > 0
+	}
+	return true
+}
+
+// toString renders a value as a string for concatenation and string functions.
+func toString(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// typeName returns the expression-level type name of a value.
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case int64:
+		return "integer"
+	case float64:
+		return "float"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// Result converts an evaluated value to the representation used in
+// configuration data: integers, including those nested in lists and maps,
+// become int.
+func Result(v interface{}) interface{} {
+	switch x := v.(type) {
+	case int64:
+		if x >= math.MinInt && x <= math.MaxInt {
+			return int(x)
+		}
+	case []interface{}:
+		out := make([]interface{}, len(x))
+		for i, item := range x {
+			out[i] = Result(normalize(item))
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(x))
+		for k, item := range x {
+			out[k] = Result(normalize(item))
+		}
+		return out
+	}
+	return v
+}
+
+// Format renders an evaluated value for interpolation into a larger string:
+// null becomes an empty string and lists and maps are rendered as JSON.
+func Format(v interface{}) string {
+	switch v.(type) {
+	case []interface{}, map[string]interface{}:
+		if s, err := fnJSON([]interface{}{v}); err == nil {
+			return s.(string)
+		}
+	}
+	return toString(v)
+}
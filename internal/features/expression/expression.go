@@ -0,0 +1,154 @@
+// Package expression implements the sandboxed expression language used by
+// ${{ ... }} placeholders, the expression generator and schema conditions.
+//
+// Expressions support:
+//   - literals: numbers, 'strings' or "strings", true, false, null, [lists]
+//   - variables by name (env, region) and config references (.database.host)
+//   - arithmetic (+ - * / %), string and list concatenation with +
+//   - comparisons (== != < <= > >=), membership (x in list) and logic (&& || !)
+//   - conditionals (cond ? a : b) and indexing (list[0], map["key"])
+//   - a curated function library (see Functions)
+//
+// Expressions cannot perform I/O, access the environment or loop, so they are
+// safe to evaluate from untrusted configuration sources.
+//
+// Usage:
+//
+//	value, err := expression.Evaluate(`upper(env) + "-" + region`, env)
+package expression
+
+import (
+	"fmt"
+	"konfigo/internal/util"
+)
+
+// maxSourceLength bounds the size of a single expression.
+const maxSourceLength = 64 * 1024
+
+// Env supplies variables and config references to an expression.
+type Env interface {
+	// Lookup returns the value of a variable.
+	Lookup(name string) (interface{}, bool)
+
+	// Reference returns the value at a dot-separated config path.
+	Reference(path string) (interface{}, bool)
+}
+
+// Error is an expression parse or evaluation error with its source position.
+type Error struct {
+	// Pos is the zero-based byte offset of the error in the expression.
+	Pos int
+	// Msg describes the error.
+	Msg string
+}
+
+// Error implements the error interface. Positions are reported one-based.
+func (e *Error) Error() string {
+	return fmt.Sprintf("expression error at position %d: %s", e.Pos+1, e.Msg)
+}
+
+// newError creates an Error at the given offset.
+func newError(pos int, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Expression is a compiled expression that can be evaluated repeatedly.
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile parses an expression.
+func Compile(source string) (*Expression, error) {
+	if len(source) > maxSourceLength {
+		return nil, newError(0, "expression exceeds maximum length of %d bytes", maxSourceLength)
+	}
+	root, err := parse(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the expression source.
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression against env.
+func (e *Expression) Eval(env Env) (interface{}, error) {
+	if env == nil {
+		env = MapEnv{}
+	}
+	return e.root.eval(env)
+}
+
+// Variables returns the names of the variables the expression reads, in
+// order of first use.
+func (e *Expression) Variables() []string {
+	var names []string
+	seen := make(map[string]bool)
+	var walk func(n node)
+	walk = func(n node) {
+		switch v := n.(type) {
+		case *identNode:
+			if !seen[v.name] {
+				seen[v.name] = true
+				names = append(names, v.name)
+			}
+		case *listNode:
+			for _, item := range v.items {
+				walk(item)
+			}
+		case *unaryNode:
+			walk(v.operand)
+		case *logicalNode:
+			walk(v.left)
+			walk(v.right)
+		case *binaryNode:
+			walk(v.left)
+			walk(v.right)
+		case *ternaryNode:
+			walk(v.cond)
+			walk(v.then)
+			walk(v.otherwise)
+		case *indexNode:
+			walk(v.target)
+			walk(v.index)
+		case *callNode:
+			for _, arg := range v.args {
+				walk(arg)
+			}
+		}
+	}
+	walk(e.root)
+	return names
+}
+
+// Evaluate compiles and evaluates an expression in one step.
+func Evaluate(source string, env Env) (interface{}, error) {
+	expr, err := Compile(source)
+	if err != nil {
+		return nil, err
+	}
+	return expr.Eval(env)
+}
+
+// MapEnv is an Env backed by a variables map and an optional configuration map.
+type MapEnv struct {
+	Vars   map[string]interface{}
+	Config map[string]interface{}
+	// Paths looks up references in Config.
+	Paths util.Paths
+}
+
+// Lookup returns a variable from the Vars map.
+func (m MapEnv) Lookup(name string) (interface{}, bool) {
+	v, ok := m.Vars[name]
+	return v, ok
+}
+
+// Reference returns a value from the Config map.
+func (m MapEnv) Reference(path string) (interface{}, bool) {
+	return m.Paths.Get(m.Config, path)
+}
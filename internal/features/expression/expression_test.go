@@ -0,0 +1,169 @@
+package expression
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEvaluate_Operators(t *testing.T) {
+	env := MapEnv{
+		Vars: map[string]interface{}{
+			"env":    "prod",
+			"region": "eu",
+			"count":  3,
+			"tags":   []interface{}{"a", "b"},
+		},
+		Config: map[string]interface{}{
+			"database": map[string]interface{}{"port": 5432, "host": "db"},
+			"servers":  []interface{}{"s1", "s2", "s3"},
+		},
+	}
+
+	tests := []struct {
+		expr string
+		want interface{}
+	}{
+		{`upper(env) + "-" + region`, "PROD-eu"},
+		{`1 + 2 * 3`, int64(7)},
+		{`(1 + 2) * 3`, int64(9)},
+		{`7 / 2`, 3.5},
+		{`6 / 2`, int64(3)},
+		{`7 % 4`, int64(3)},
+		{`-count + 1`, int64(-2)},
+		{`.database.port + 1`, int64(5433)},
+		{`count > 2 && env == 'prod'`, true},
+		{`!(count > 2) || false`, false},
+		{`env == "prod" ? "large" : "small"`, "large"},
+		{`"b" in tags`, true},
+		{`"port" in .database`, true},
+		{`.servers[-1]`, "s3"},
+		{`.database["host"]`, "db"},
+		{`[1, 2] + [3]`, []interface{}{int64(1), int64(2), int64(3)}},
+		{`len(.servers)`, int64(3)},
+		{`join(sort(["b", "a"]), ",")`, "a,b"},
+		{`base64encode("hi")`, "aGk="},
+		{`sha256("abc")`, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{`int("42") + float("0.5")`, 42.5},
+		{`default("", "fallback")`, "fallback"},
+		{`max(1, 5, 3)`, int64(5)},
+		{`format("%s:%d", .database.host, .database.port)`, "db:5432"},
+		{`format("%05d|%-4s|%.2f", 42, "ab", 1.5)`, "00042|ab  |1.50"},
+		{`substr("hello", 1, 3)`, "ell"},
+		{`substr("hello", 3, 10)`, "lo"},
+		{`substr("hello", -10, 2)`, ""},
+		{`substr("hello", -1, 3)`, "he"},
+		{`substr("hello", 9, 2)`, ""},
+		{`substr("hello", -3)`, "hello"},
+		{`int(float("1e18")) + 1`, int64(1000000000000000001)},
+		{`9223372036854775807 - 1 + 1`, int64(9223372036854775807)},
+		{`null`, nil},
+	}
+	for _, tt := range tests {
+		got, err := Evaluate(tt.expr, env)
+		if err != nil {
+			t.Errorf("Evaluate(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Evaluate(%q) = %#v, want %#v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluate_ShortCircuit(t *testing.T) {
+	got, err := Evaluate(`false && missing`, nil)
+	if err != nil {
+		t.Fatalf("Evaluate failed: %v", err)
+	}
+	if got != false {
+		t.Errorf("expected false, got %v", got)
+	}
+}
+
+func TestEvaluate_ErrorPositions(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`1 +`, "position 4: unexpected end of expression"},
+		{`upper(env`, "position 10: expected \")\""},
+		{`"abc`, "position 1: unterminated string literal"},
+		{`1 + missing`, "position 5: unknown variable 'missing'"},
+		{`nope(1)`, "position 1: unknown function 'nope'"},
+		{`1 / 0`, "position 3: division by zero"},
+		{`"a" - 1`, "position 5: operator '-' not supported for string and integer"},
+		{`.a.b`, "position 1: config path '.a.b' not found"},
+		{`1 # 2`, "position 3: unexpected character"},
+		{`upper(1, 2)`, "position 1: upper(): expected 1 argument(s), got 2"},
+		{`9223372036854775807 + 1`, "position 21: integer overflow"},
+		{`-9223372036854775807 - 2`, "position 22: integer overflow"},
+		{`3037000500 * 3037000500`, "position 12: integer overflow"},
+		{`float("1e308") * 10`, "position 16: number out of range"},
+		{`int("99999999999999999999")`, `position 1: int(): "99999999999999999999" is out of the integer range`},
+		{`int(float("1e19"))`, "position 1: int(): 1e+19 is out of the integer range"},
+		{`float("inf")`, `position 1: float(): cannot convert "inf" to float`},
+		{`float("NaN")`, `position 1: float(): cannot convert "NaN" to float`},
+		{`format("%999999999d", 1)`, "position 1: format(): width or precision 999999999 exceeds maximum of 1024"},
+		{`format("%.5000f", 1.5)`, "exceeds maximum of 1024"},
+		{`format("%*d", 999999999, 1)`, "'*' widths are not supported"},
+	}
+	for _, tt := range tests {
+		_, err := Evaluate(tt.expr, nil)
+		if err == nil {
+			t.Errorf("Evaluate(%q) expected error", tt.expr)
+			continue
+		}
+		if _, ok := err.(*Error); !ok {
+			t.Errorf("Evaluate(%q) returned %T, want *Error", tt.expr, err)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Evaluate(%q) error = %q, want it to contain %q", tt.expr, err.Error(), tt.want)
+		}
+	}
+}
+
+func TestCompile_DepthLimit(t *testing.T) {
+	expr := strings.Repeat("(", maxDepth+1) + "1" + strings.Repeat(")", maxDepth+1)
+	if _, err := Compile(expr); err == nil || !strings.Contains(err.Error(), "nested too deeply") {
+		t.Errorf("expected nesting error, got %v", err)
+	}
+}
+
+func TestEvaluate_ResultLengthLimit(t *testing.T) {
+	env := MapEnv{Vars: map[string]interface{}{
+		"s":     strings.Repeat("x", 1000),
+		"y":     strings.Repeat("y", 2000),
+		"parts": []interface{}{strings.Repeat("a", maxResultLength/2), strings.Repeat("b", maxResultLength/2)},
+	}}
+	for _, expr := range []string{
+		`replace(replace(s, "", y), "", y)`,
+		`join(parts, "-")`,
+	} {
+		if _, err := Evaluate(expr, env); err == nil || !strings.Contains(err.Error(), "exceeds maximum length") {
+			t.Errorf("Evaluate(%q) = %v, want a length error", expr, err)
+		}
+	}
+	if got, err := Evaluate(`join(parts, "")`, env); err != nil || len(got.(string)) != maxResultLength {
+		t.Errorf("a join of exactly the maximum length should pass, got error %v", err)
+	}
+}
+
+func TestResult_ConvertsIntegers(t *testing.T) {
+	got := Result([]interface{}{int64(1), map[string]interface{}{"n": int64(2)}})
+	want := []interface{}{1, map[string]interface{}{"n": 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Result = %#v, want %#v", got, want)
+	}
+}
+
+func TestExpression_Variables(t *testing.T) {
+	expr, err := Compile(`upper(ENV) + "-" + (DEBUG ? NAME : .app.name) + ENV + [PORT][0]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ENV", "DEBUG", "NAME", "PORT"}
+	if got := expr.Variables(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Variables() = %v, want %v", got, want)
+	}
+}
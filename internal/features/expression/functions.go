@@ -0,0 +1,584 @@
+package expression
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Function is a builtin expression function. Arguments are normalized values
+// (int64, float64, string, bool, nil, []interface{} or map[string]interface{}).
+type Function func(args []interface{}) (interface{}, error)
+
+// builtins is the curated function library available to expressions.
+var builtins = map[string]Function{
+	// strings
+	"upper":      stringFunc(strings.ToUpper),
+	"lower":      stringFunc(strings.ToLower),
+	"trim":       stringFunc(strings.TrimSpace),
+	"trimPrefix": string2Func(strings.TrimPrefix),
+	"trimSuffix": string2Func(strings.TrimSuffix),
+	"replace":    fnReplace,
+	"split":      fnSplit,
+	"join":       fnJoin,
+	"contains":   fnContains,
+	"startsWith": boolString2Func(strings.HasPrefix),
+	"endsWith":   boolString2Func(strings.HasSuffix),
+	"substr":     fnSubstr,
+	"format":     fnFormat,
+
+	// collections
+	"len":      fnLen,
+	"first":    fnFirst,
+	"last":     fnLast,
+	"sort":     fnSort,
+	"unique":   fnUnique,
+	"keys":     fnKeys,
+	"default":  fnDefault,
+	"coalesce": fnCoalesce,
+
+	// conversion
+	"string": fnString,
+	"int":    fnInt,
+	"float":  fnFloat,
+	"bool":   fnBool,
+
+	// encoding and hashing
+	"base64encode": stringFunc(func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }),
+	"base64decode": fnBase64Decode,
+	"urlencode":    stringFunc(url.QueryEscape),
+	"json":         fnJSON,
+	"md5":          stringFunc(func(s string) string { h := md5.Sum([]byte(s)); return hex.EncodeToString(h[:]) }),
+	"sha1":         stringFunc(func(s string) string { h := sha1.Sum([]byte(s)); return hex.EncodeToString(h[:]) }),
+	"sha256":       stringFunc(func(s string) string { h := sha256.Sum256([]byte(s)); return hex.EncodeToString(h[:]) }),
+	"sha512":       stringFunc(func(s string) string { h := sha512.Sum512([]byte(s)); return hex.EncodeToString(h[:]) }),
+
+	// math
+	"min":   fnMin,
+	"max":   fnMax,
+	"abs":   fnAbs,
+	"floor": floatFunc(math.Floor),
+	"ceil":  floatFunc(math.Ceil),
+	"round": floatFunc(math.Round),
+}
+
+// Functions returns the names of the builtin functions in sorted order.
+func Functions() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Call invokes a builtin function by name. It allows other evaluators, such
+// as the template renderer, to share the expression function library.
+func Call(name string, args ...interface{}) (interface{}, error) {
+	fn, ok := builtins[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function '%s'", name)
+	}
+	normalized := make([]interface{}, len(args))
+	for i, a := range args {
+		normalized[i] = normalize(a)
+	}
+	result, err := fn(normalized)
+	if err != nil {
+		return nil, err
+	}
+	return Result(normalize(result)), nil
+}
+
+// arity checks the number of arguments passed to a function.
+func arity(args []interface{}, min, max int) error {
+	if len(args) < min || (max >= 0 && len(args) > max) {
+		switch {
+		case min == max:
+			return fmt.Errorf("expected %d argument(s), got %d", min, len(args))
+		case max < 0:
+			return fmt.Errorf("expected at least %d argument(s), got %d", min, len(args))
+		default:
+			return fmt.Errorf("expected %d to %d arguments, got %d", min, max, len(args))
+		}
+	}
+	return nil
+}
+
+// intArg extracts an integer argument.
+func intArg(args []interface{}, i int) (int, error) {
+	switch v := args[i].(type) {
+	case int64:
+		return int(v), nil
+	case float64:
+		if n, ok := floatToInt(v); ok {
+			return int(n), nil
+		}
+	}
+	return 0, fmt.Errorf("argument %d must be an integer, got %s", i+1, typeName(args[i]))
+}
+
+// listArg extracts a list argument.
+func listArg(args []interface{}, i int) ([]interface{}, error) {
+	if l, ok := args[i].([]interface{}); ok {
+		return l, nil
+	}
+	return nil, fmt.Errorf("argument %d must be a list, got %s", i+1, typeName(args[i]))
+}
+
+func stringFunc(f func(string) string) Function {
+	return func(args []interface{}) (interface{}, error) {
+		if err := arity(args, 1, 1); err != nil {
+			return nil, err
+		}
+		return f(toString(args[0])), nil
+	}
+}
+
+func string2Func(f func(string, string) string) Function {
+	return func(args []interface{}) (interface{}, error) {
+		if err := arity(args, 2, 2); err != nil {
+			return nil, err
+		}
+		return f(toString(args[0]), toString(args[1])), nil
+	}
+}
+
+func boolString2Func(f func(string, string) bool) Function {
+	return func(args []interface{}) (interface{}, error) {
+		if err := arity(args, 2, 2); err != nil {
+			return nil, err
+		}
+		return f(toString(args[0]), toString(args[1])), nil
+	}
+}
+
+func floatFunc(f func(float64) float64) Function {
+	return func(args []interface{}) (interface{}, error) {
+		if err := arity(args, 1, 1); err != nil {
+			return nil, err
+		}
+		if i, ok := args[0].(int64); ok {
+			return i, nil
+		}
+		v, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("argument must be a number, got %s", typeName(args[0]))
+		}
+		r := f(v)
+		if n, ok := floatToInt(r); ok {
+			return n, nil
+		}
+		return r, nil
+	}
+}
+
+func fnReplace(args []interface{}) (interface{}, error) {
+	if err := arity(args, 3, 3); err != nil {
+		return nil, err
+	}
+	s, old, repl := toString(args[0]), toString(args[1]), toString(args[2])
+	if len(repl) > len(old) {
+		if size := len(s) + strings.Count(s, old)*(len(repl)-len(old)); size > maxResultLength {
+			return nil, fmt.Errorf("result exceeds maximum length of %d bytes", maxResultLength)
+		}
+	}
+	return strings.ReplaceAll(s, old, repl), nil
+}
+
+func fnSplit(args []interface{}) (interface{}, error) {
+	if err := arity(args, 2, 2); err != nil {
+		return nil, err
+	}
+	parts := strings.Split(toString(args[0]), toString(args[1]))
+	out := make([]interface{}, len(parts))
+	for i, p := range parts {
+		out[i] = p
+	}
+	return out, nil
+}
+
+func fnJoin(args []interface{}) (interface{}, error) {
+	if err := arity(args, 2, 2); err != nil {
+		return nil, err
+	}
+	list, err := listArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	sep := toString(args[1])
+	parts := make([]string, len(list))
+	size := 0
+	for i, item := range list {
+		parts[i] = toString(item)
+		size += len(parts[i])
+		if i > 0 {
+			size += len(sep)
+		}
+		if size > maxResultLength {
+			return nil, fmt.Errorf("result exceeds maximum length of %d bytes", maxResultLength)
+		}
+	}
+	return strings.Join(parts, sep), nil
+}
+
+func fnContains(args []interface{}) (interface{}, error) {
+	if err := arity(args, 2, 2); err != nil {
+		return nil, err
+	}
+	return contains(args[0], args[1], 0)
+}
+
+func fnSubstr(args []interface{}) (interface{}, error) {
+	if err := arity(args, 2, 3); err != nil {
+		return nil, err
+	}
+	runes := []rune(toString(args[0]))
+	start, err := intArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	end := len(runes)
+	if len(args) == 3 {
+		length, err := intArg(args, 2)
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, fmt.Errorf("length must not be negative")
+		}
+		end = start + length
+	}
+	start = max(0, min(start, len(runes)))
+	end = max(start, min(end, len(runes)))
+	return string(runes[start:end]), nil
+}
+
+func fnFormat(args []interface{}) (interface{}, error) {
+	if err := arity(args, 1, -1); err != nil {
+		return nil, err
+	}
+	format := toString(args[0])
+	if err := checkFormat(format); err != nil {
+		return nil, err
+	}
+	s := fmt.Sprintf(format, args[1:]...)
+	if len(s) > maxResultLength {
+		return nil, fmt.Errorf("result exceeds maximum length of %d bytes", maxResultLength)
+	}
+	return s, nil
+}
+
+// maxFormatWidth bounds the width and precision of format verbs, which
+// fmt.Sprintf would otherwise pad without limit.
+const maxFormatWidth = 1024
+
+// checkFormat rejects format verbs whose width or precision exceeds
+// maxFormatWidth, and '*' widths taken from the arguments.
+func checkFormat(format string) error {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		for i++; i < len(format) && strings.IndexByte("+-# 0123456789.*[", format[i]) >= 0; i++ {
+			switch c := format[i]; {
+			case c == '*':
+				return fmt.Errorf("'*' widths are not supported")
+			case c == '[':
+				for i < len(format) && format[i] != ']' {
+					i++
+				}
+			case c >= '1' && c <= '9':
+				j := i
+				for j < len(format) && format[j] >= '0' && format[j] <= '9' {
+					j++
+				}
+				if n, err := strconv.Atoi(format[i:j]); err != nil || n > maxFormatWidth {
+					return fmt.Errorf("width or precision %s exceeds maximum of %d", format[i:j], maxFormatWidth)
+				}
+				i = j - 1
+			}
+		}
+	}
+	return nil
+}
+
+func fnLen(args []interface{}) (interface{}, error) {
+	if err := arity(args, 1, 1); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case string:
+		return int64(len([]rune(v))), nil
+	case []interface{}:
+		return int64(len(v)), nil
+	case map[string]interface{}:
+		return int64(len(v)), nil
+	case nil:
+		return int64(0), nil
+	}
+	return nil, fmt.Errorf("argument must be a string, list or map, got %s", typeName(args[0]))
+}
+
+func fnFirst(args []interface{}) (interface{}, error) {
+	if err := arity(args, 1, 1); err != nil {
+		return nil, err
+	}
+	list, err := listArg(args, 0)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return list[0], nil
+}
+
+func fnLast(args []interface{}) (interface{}, error) {
+	if err := arity(args, 1, 1); err != nil {
+		return nil, err
+	}
+	list, err := listArg(args, 0)
+	if err != nil || len(list) == 0 {
+		return nil, err
+	}
+	return list[len(list)-1], nil
+}
+
+func fnSort(args []interface{}) (interface{}, error) {
+	if err := arity(args, 1, 1); err != nil {
+		return nil, err
+	}
+	list, err := listArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	out := append([]interface{}{}, list...)
+	sort.SliceStable(out, func(i, j int) bool {
+		fi, iok := toFloat(out[i])
+		fj, jok := toFloat(out[j])
+		if iok && jok {
+			return fi < fj
+		}
+		return toString(out[i]) < toString(out[j])
+	})
+	return out, nil
+}
+
+func fnUnique(args []interface{}) (interface{}, error) {
+	if err := arity(args, 1, 1); err != nil {
+		return nil, err
+	}
+	list, err := listArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]interface{}, 0, len(list))
+	for _, item := range list {
+		seen := false
+		for _, existing := range out {
+			if equal(existing, item) {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			out = append(out, item)
+		}
+	}
+	return out, nil
+}
+
+func fnKeys(args []interface{}) (interface{}, error) {
+	if err := arity(args, 1, 1); err != nil {
+		return nil, err
+	}
+	m, ok := args[0].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("argument must be a map, got %s", typeName(args[0]))
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]interface{}, len(keys))
+	for i, k := range keys {
+		out[i] = k
+	}
+	return out, nil
+}
+
+func fnDefault(args []interface{}) (interface{}, error) {
+	if err := arity(args, 2, 2); err != nil {
+		return nil, err
+	}
+	if truthy(args[0]) {
+		return args[0], nil
+	}
+	return args[1], nil
+}
+
+func fnCoalesce(args []interface{}) (interface{}, error) {
+	for _, a := range args {
+		if a != nil && a != "" {
+			return a, nil
+		}
+	}
+	return nil, nil
+}
+
+func fnString(args []interface{}) (interface{}, error) {
+	if err := arity(args, 1, 1); err != nil {
+		return nil, err
+	}
+	switch args[0].(type) {
+	case []interface{}, map[string]interface{}:
+		return fnJSON(args)
+	}
+	return toString(args[0]), nil
+}
+
+func fnInt(args []interface{}) (interface{}, error) {
+	if err := arity(args, 1, 1); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case int64:
+		return v, nil
+	case float64:
+		if n, ok := floatToInt(math.Trunc(v)); ok {
+			return n, nil
+		}
+		return nil, fmt.Errorf("%v is out of the integer range", v)
+	case bool:
+		if v {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	case string:
+		s := strings.TrimSpace(v)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			if n, ok := floatToInt(math.Trunc(f)); ok {
+				return n, nil
+			}
+			return nil, fmt.Errorf("%q is out of the integer range", v)
+		}
+		return nil, fmt.Errorf("cannot convert %q to integer", v)
+	}
+	return nil, fmt.Errorf("cannot convert %s to integer", typeName(args[0]))
+}
+
+func fnFloat(args []interface{}) (interface{}, error) {
+	if err := arity(args, 1, 1); err != nil {
+		return nil, err
+	}
+	if f, ok := toFloat(args[0]); ok {
+		return f, nil
+	}
+	if s, ok := args[0].(string); ok {
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, fmt.Errorf("cannot convert %q to float", s)
+		}
+		return f, nil
+	}
+	return nil, fmt.Errorf("cannot convert %s to float", typeName(args[0]))
+}
+
+func fnBool(args []interface{}) (interface{}, error) {
+	if err := arity(args, 1, 1); err != nil {
+		return nil, err
+	}
+	if s, ok := args[0].(string); ok {
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert %q to bool", s)
+		}
+		return b, nil
+	}
+	return truthy(args[0]), nil
+}
+
+func fnBase64Decode(args []interface{}) (interface{}, error) {
+	if err := arity(args, 1, 1); err != nil {
+		return nil, err
+	}
+	b, err := base64.StdEncoding.DecodeString(toString(args[0]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 input: %v", err)
+	}
+	return string(b), nil
+}
+
+func fnJSON(args []interface{}) (interface{}, error) {
+	if err := arity(args, 1, 1); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func fnMin(args []interface{}) (interface{}, error) {
+	return extreme(args, func(a, b float64) bool { return a < b })
+}
+
+func fnMax(args []interface{}) (interface{}, error) {
+	return extreme(args, func(a, b float64) bool { return a > b })
+}
+
+// extreme returns the argument (or list element) preferred by better.
+func extreme(args []interface{}, better func(a, b float64) bool) (interface{}, error) {
+	if len(args) == 1 {
+		if list, ok := args[0].([]interface{}); ok {
+			args = list
+		}
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("expected at least 1 argument")
+	}
+	var best interface{}
+	var bestValue float64
+	for i, a := range args {
+		v, ok := toFloat(a)
+		if !ok {
+			return nil, fmt.Errorf("argument %d must be a number, got %s", i+1, typeName(a))
+		}
+		if i == 0 || better(v, bestValue) {
+			best, bestValue = a, v
+		}
+	}
+	return best, nil
+}
+
+func fnAbs(args []interface{}) (interface{}, error) {
+	if err := arity(args, 1, 1); err != nil {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case int64:
+		if v == math.MinInt64 {
+			return nil, fmt.Errorf("integer overflow")
+		}
+		if v < 0 {
+			return -v, nil
+		}
+		return v, nil
+	case float64:
+		return math.Abs(v), nil
+	}
+	return nil, fmt.Errorf("argument must be a number, got %s", typeName(args[0]))
+}
@@ -0,0 +1,168 @@
+package expression
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// tokenKind identifies the kind of a lexical token.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenString
+	tokenIdent
+	tokenRef
+	tokenOp
+)
+
+// token is a lexical token together with its byte offset in the source.
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{} // parsed literal for numbers and strings
+	pos   int
+}
+
+// operators lists the supported operators and punctuation, longest first.
+var operators = []string{
+	"==", "!=", "<=", ">=", "&&", "||",
+	"+", "-", "*", "/", "%", "<", ">", "!", "?", ":", "(", ")", "[", "]", ",",
+}
+
+// lex splits an expression source into tokens.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c >= '0' && c <= '9':
+			start := i
+			isFloat := false
+			for i < len(src) && (isDigit(src[i]) || (src[i] == '.' && !isFloat && i+1 < len(src) && isDigit(src[i+1]))) {
+				if src[i] == '.' {
+					isFloat = true
+				}
+				i++
+			}
+			text := src[start:i]
+			tok := token{kind: tokenNumber, text: text, pos: start}
+			if isFloat {
+				f, err := strconv.ParseFloat(text, 64)
+				if err != nil {
+					return nil, newError(start, "invalid number %q", text)
+				}
+				tok.value = f
+			} else {
+				n, err := strconv.ParseInt(text, 10, 64)
+				if err != nil {
+					return nil, newError(start, "invalid number %q", text)
+				}
+				tok.value = n
+			}
+			tokens = append(tokens, tok)
+
+		case c == '"' || c == '\'':
+			start := i
+			str, next, err := lexString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: src[start:next], value: str, pos: start})
+			i = next
+
+		case c == '.' && i+1 < len(src) && isRefStart(src[i+1]):
+			start := i
+			i++
+			for i < len(src) && isRefChar(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenRef, text: src[start+1 : i], pos: start})
+
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && isIdentChar(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[start:i], pos: start})
+
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, newError(i, "unexpected character %q", string(c))
+			}
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(src)})
+	return tokens, nil
+}
+
+// lexString reads a quoted string literal starting at src[start] and returns
+// its unescaped value and the offset just past the closing quote.
+func lexString(src string, start int) (string, int, error) {
+	quote := src[start]
+	var b strings.Builder
+	i := start + 1
+	for i < len(src) {
+		c := src[i]
+		if c == quote {
+			return b.String(), i + 1, nil
+		}
+		if c == '\\' && i+1 < len(src) {
+			i++
+			switch src[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(src[i])
+			}
+			i++
+			continue
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return "", 0, newError(start, "unterminated string literal")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || unicode.IsLetter(rune(c))
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+// isRefStart reports whether c can start a .path config reference.
+func isRefStart(c byte) bool {
+	return isIdentStart(c)
+}
+
+// isRefChar reports whether c can appear in a .path config reference. List
+// elements and keys with special characters are reached by indexing the
+// reference, e.g. .servers[0] or .labels["app.kubernetes.io/name"].
+func isRefChar(c byte) bool {
+	return isIdentChar(c) || c == '.' || c == '-'
+}
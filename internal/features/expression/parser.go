@@ -0,0 +1,326 @@
+package expression
+
+// maxDepth bounds the nesting depth of an expression to keep evaluation
+// within predictable stack usage.
+const maxDepth = 64
+
+// node is an evaluable expression AST node.
+type node interface {
+	eval(env Env) (interface{}, error)
+	position() int
+}
+
+// parser is a recursive-descent parser producing an AST from tokens.
+type parser struct {
+	tokens []token
+	pos    int
+	depth  int
+}
+
+// parse parses a complete expression source into an AST.
+func parse(src string) (node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, newError(0, "empty expression")
+	}
+	n, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, newError(tok.pos, "unexpected %s", describe(tok))
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// isOp reports whether the next token is one of the given operators.
+func (p *parser) isOp(ops ...string) bool {
+	tok := p.peek()
+	if tok.kind != tokenOp {
+		return false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+// isKeyword reports whether the next token is the given identifier keyword.
+func (p *parser) isKeyword(word string) bool {
+	tok := p.peek()
+	return tok.kind == tokenIdent && tok.text == word
+}
+
+// expect consumes the given operator or fails.
+func (p *parser) expect(op string) (token, error) {
+	if !p.isOp(op) {
+		tok := p.peek()
+		return tok, newError(tok.pos, "expected %q, found %s", op, describe(tok))
+	}
+	return p.next(), nil
+}
+
+func (p *parser) parseExpr() (node, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxDepth {
+		return nil, newError(p.peek().pos, "expression nested too deeply (maximum depth %d)", maxDepth)
+	}
+	return p.parseTernary()
+}
+
+func (p *parser) parseTernary() (node, error) {
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp("?") {
+		return cond, nil
+	}
+	q := p.next()
+	then, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return &ternaryNode{pos: q.pos, cond: cond, then: then, otherwise: otherwise}, nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("||") {
+		op := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{pos: op.pos, op: op.text, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseEquality()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&&") {
+		op := p.next()
+		right, err := p.parseEquality()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{pos: op.pos, op: op.text, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseEquality() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("==", "!=") {
+		op := p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: op.pos, op: op.text, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("<", "<=", ">", ">=") || p.isKeyword("in") {
+		op := p.next()
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: op.pos, op: op.text, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("+", "-") {
+		op := p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: op.pos, op: op.text, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("*", "/", "%") {
+		op := p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{pos: op.pos, op: op.text, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("!", "-") {
+		op := p.next()
+		p.depth++
+		defer func() { p.depth-- }()
+		if p.depth > maxDepth {
+			return nil, newError(op.pos, "expression nested too deeply (maximum depth %d)", maxDepth)
+		}
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{pos: op.pos, op: op.text, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("[") {
+		open := p.next()
+		index, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		n = &indexNode{pos: open.pos, target: n, index: index}
+	}
+	return n, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber, tokenString:
+		return &literalNode{pos: tok.pos, value: tok.value}, nil
+	case tokenRef:
+		return &refNode{pos: tok.pos, path: tok.text}, nil
+	case tokenIdent:
+		switch tok.text {
+		case "true":
+			return &literalNode{pos: tok.pos, value: true}, nil
+		case "false":
+			return &literalNode{pos: tok.pos, value: false}, nil
+		case "null", "nil":
+			return &literalNode{pos: tok.pos, value: nil}, nil
+		}
+		if p.isOp("(") {
+			p.next()
+			args, err := p.parseList(")")
+			if err != nil {
+				return nil, err
+			}
+			return &callNode{pos: tok.pos, name: tok.text, args: args}, nil
+		}
+		return &identNode{pos: tok.pos, name: tok.text}, nil
+	case tokenOp:
+		switch tok.text {
+		case "(":
+			n, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		case "[":
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{pos: tok.pos, items: items}, nil
+		}
+	}
+	return nil, newError(tok.pos, "unexpected %s", describe(tok))
+}
+
+// parseList parses a comma-separated list of expressions up to the closing operator.
+func (p *parser) parseList(closing string) ([]node, error) {
+	var items []node
+	if p.isOp(closing) {
+		p.next()
+		return items, nil
+	}
+	for {
+		item, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if p.isOp(",") {
+			p.next()
+			continue
+		}
+		if _, err := p.expect(closing); err != nil {
+			return nil, err
+		}
+		return items, nil
+	}
+}
+
+// describe renders a token for error messages.
+func describe(tok token) string {
+	switch tok.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenRef:
+		return "reference ." + tok.text
+	default:
+		return "'" + tok.text + "'"
+	}
+}
@@ -20,13 +20,13 @@ func (g *ConcatGenerator) Type() string {
 
 // Generate implements the concat generator logic.
 // It replaces placeholders in the format string with values from specified configuration paths.
-func (g *ConcatGenerator) Generate(config map[string]interface{}, def Definition, resolver VariableResolver) error {
+func (g *ConcatGenerator) Generate(config map[string]interface{}, def Definition, resolver VariableResolver, paths util.Paths) error {
 	logger.Debug("  - Applying concat generator for target path '%s'", def.TargetPath)
 
 	// Build replacement arguments for string replacer
 	var replacerArgs []string
 	for placeholder, sourcePath := range def.Sources {
-		value, found := util.GetNestedValue(config, sourcePath)
+		value, found := paths.Get(config, sourcePath)
 		if !found {
 			return fmt.Errorf("concat generator: source path '%s' not found in configuration", sourcePath)
 		}
@@ -70,13 +70,21 @@ func (g *ConcatGenerator) Generate(config map[string]interface{}, def Definition
 		return fmt.Errorf("concat generator: unresolved placeholder(s) %s in format string — no matching source defined", strings.Join(unresolvedPlaceholders, ", "))
 	}
 
-	// Substitute any global variables in the final result
-	if resolver != nil {
+	// Substitute any global variables and expressions in the final result
+	if checked, ok := resolver.(CheckedVariableResolver); ok {
+		substituted, err := checked.SubstituteStringChecked(result)
+		if err != nil {
+			return fmt.Errorf("concat generator: %w", err)
+		}
+		result = substituted
+	} else if resolver != nil {
 		result = resolver.SubstituteString(result)
 	}
 
 	// Set the generated value in the configuration
-	util.SetNestedValue(config, def.TargetPath, result)
+	if err := paths.Set(config, def.TargetPath, result); err != nil {
+		return fmt.Errorf("concat generator: %w", err)
+	}
 
 	logger.Debug("    Generated value '%s' at path '%s'", result, def.TargetPath)
 	return nil
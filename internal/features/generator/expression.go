@@ -0,0 +1,66 @@
+package generator
+
+import (
+	"fmt"
+	"konfigo/internal/features/expression"
+	"konfigo/internal/logger"
+	"konfigo/internal/util"
+	"strings"
+)
+
+// ExpressionGeneratorType is the type identifier for the expression generator.
+const ExpressionGeneratorType = "expression"
+
+// ExpressionGenerator generates values by evaluating an expression.
+type ExpressionGenerator struct{}
+
+// Type returns the generator type.
+func (g *ExpressionGenerator) Type() string {
+	return ExpressionGeneratorType
+}
+
+// Generate implements the expression generator logic.
+// The expression can use variables by name and config values as .path
+// references; the result keeps its native type (string, number, bool, list or map).
+func (g *ExpressionGenerator) Generate(config map[string]interface{}, def Definition, resolver VariableResolver, paths util.Paths) error {
+	logger.Debug("  - Applying expression generator for target path '%s'", def.TargetPath)
+
+	var result interface{}
+	var err error
+	if evaluator, ok := resolver.(ExpressionEvaluator); ok {
+		result, err = evaluator.EvaluateExpression(def.Expression)
+	} else {
+		result, err = expression.Evaluate(def.Expression, expression.MapEnv{Config: config, Paths: paths})
+		result = expression.Result(result)
+	}
+	if err != nil {
+		return fmt.Errorf("expression generator: %w", err)
+	}
+
+	// Set the generated value in the configuration
+	if err := paths.Set(config, def.TargetPath, result); err != nil {
+		return fmt.Errorf("expression generator: %w", err)
+	}
+
+	logger.Debug("    Generated value '%v' at path '%s'", result, def.TargetPath)
+	return nil
+}
+
+// ValidateDefinition validates an expression generator definition.
+// The expression is compiled so that syntax errors are reported before any
+// generator runs.
+func (g *ExpressionGenerator) ValidateDefinition(def Definition) error {
+	if def.TargetPath == "" {
+		return fmt.Errorf("expression generator: targetPath is required and cannot be empty")
+	}
+	if strings.TrimSpace(def.Expression) == "" {
+		return fmt.Errorf("expression generator: expression is required and cannot be empty")
+	}
+	if len(def.Sources) > 0 {
+		return fmt.Errorf("expression generator: sources should not be specified (reference config values as .path in the expression)")
+	}
+	if _, err := expression.Compile(def.Expression); err != nil {
+		return fmt.Errorf("expression generator: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package generator
+
+import (
+	"konfigo/internal/util"
+	"strings"
+	"testing"
+)
+
+func TestExpressionGenerator_TypedResult(t *testing.T) {
+	config := map[string]interface{}{
+		"service": map[string]interface{}{"name": "api", "port": 8000},
+	}
+
+	defs := []Definition{
+		{Type: "expression", TargetPath: "service.adminPort", Expression: ".service.port + 1"},
+		{Type: "expression", TargetPath: "service.fqdn", Expression: `upper(.service.name) + ".internal"`},
+	}
+	if err := Apply(config, defs, nil, util.Paths{}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	service := config["service"].(map[string]interface{})
+	if service["adminPort"] != 8001 {
+		t.Errorf("expected adminPort = 8001 (int), got %#v", service["adminPort"])
+	}
+	if service["fqdn"] != "API.internal" {
+		t.Errorf("expected fqdn = 'API.internal', got %v", service["fqdn"])
+	}
+}
+
+func TestExpressionGenerator_SyntaxErrorReportedAtValidation(t *testing.T) {
+	defs := []Definition{{Type: "expression", TargetPath: "x", Expression: "1 + (2"}}
+	err := ValidateDefinitions(defs)
+	if err == nil || !strings.Contains(err.Error(), "position 7") {
+		t.Errorf("expected positioned syntax error, got %v", err)
+	}
+}
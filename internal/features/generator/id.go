@@ -31,7 +31,7 @@ func (g *IdGenerator) Type() string {
 // - "alpha:length": Alphabetic ID using only letters [a-zA-Z]
 // - "sequential": Sequential counter-based ID (starts from 1)
 // - "timestamp": Timestamp-based ID (unix timestamp + random suffix)
-func (g *IdGenerator) Generate(config map[string]interface{}, def Definition, resolver VariableResolver) error {
+func (g *IdGenerator) Generate(config map[string]interface{}, def Definition, resolver VariableResolver, paths util.Paths) error {
 	logger.Debug("  - Applying id generator for target path '%s'", def.TargetPath)
 
 	format := def.Format
@@ -73,7 +73,9 @@ func (g *IdGenerator) Generate(config map[string]interface{}, def Definition, re
 	}
 
 	// Set the generated value in the configuration
-	util.SetNestedValue(config, def.TargetPath, result)
+	if err := paths.Set(config, def.TargetPath, result); err != nil {
+		return fmt.Errorf("id generator: %w", err)
+	}
 
 	logger.Debug("    Generated ID '%s' at path '%s'", result, def.TargetPath)
 	return nil
@@ -1,6 +1,7 @@
 package generator
 
 import (
+	"konfigo/internal/util"
 	"testing"
 )
 
@@ -12,7 +13,7 @@ func TestSequentialId_NoInternalLeak(t *testing.T) {
 		{Type: "id", TargetPath: "other_id", Format: "sequential"},
 	}
 
-	err := Apply(config, defs, nil)
+	err := Apply(config, defs, nil, util.Paths{})
 	if err != nil {
 		t.Fatalf("Apply failed: %v", err)
 	}
@@ -40,7 +41,7 @@ func TestSequentialId_Increments(t *testing.T) {
 	}
 
 	// First apply
-	err := Apply(config, defs, nil)
+	err := Apply(config, defs, nil, util.Paths{})
 	if err != nil {
 		t.Fatalf("first Apply failed: %v", err)
 	}
@@ -55,7 +56,7 @@ func TestSimpleId_Length(t *testing.T) {
 		{Type: "id", TargetPath: "myid", Format: "simple:16"},
 	}
 
-	err := Apply(config, defs, nil)
+	err := Apply(config, defs, nil, util.Paths{})
 	if err != nil {
 		t.Fatalf("Apply failed: %v", err)
 	}
@@ -75,7 +76,7 @@ func TestPrefixId_HasPrefix(t *testing.T) {
 		{Type: "id", TargetPath: "myid", Format: "prefix:usr_:8"},
 	}
 
-	err := Apply(config, defs, nil)
+	err := Apply(config, defs, nil, util.Paths{})
 	if err != nil {
 		t.Fatalf("Apply failed: %v", err)
 	}
@@ -98,7 +99,7 @@ func TestNumericId_OnlyDigits(t *testing.T) {
 		{Type: "id", TargetPath: "myid", Format: "numeric:10"},
 	}
 
-	err := Apply(config, defs, nil)
+	err := Apply(config, defs, nil, util.Paths{})
 	if err != nil {
 		t.Fatalf("Apply failed: %v", err)
 	}
@@ -121,7 +122,7 @@ func TestIdGenerator_InvalidFormat(t *testing.T) {
 		{Type: "id", TargetPath: "myid", Format: "unknown_format"},
 	}
 
-	err := Apply(config, defs, nil)
+	err := Apply(config, defs, nil, util.Paths{})
 	if err == nil {
 		t.Fatal("expected error for invalid format, got nil")
 	}
@@ -39,7 +39,7 @@ func newCryptoRand() (*mrand.Rand, error) {
 // - "string:length": Random string of specified length using [a-zA-Z0-9]
 // - "bytes:length": Random bytes as hex string
 // - "uuid": UUID v4 format (8-4-4-4-12 hex digits)
-func (g *RandomGenerator) Generate(config map[string]interface{}, def Definition, resolver VariableResolver) error {
+func (g *RandomGenerator) Generate(config map[string]interface{}, def Definition, resolver VariableResolver, paths util.Paths) error {
 	logger.Debug("  - Applying random generator for target path '%s'", def.TargetPath)
 
 	format := def.Format
@@ -80,7 +80,9 @@ func (g *RandomGenerator) Generate(config map[string]interface{}, def Definition
 	}
 
 	// Set the generated value in the configuration
-	util.SetNestedValue(config, def.TargetPath, result)
+	if err := paths.Set(config, def.TargetPath, result); err != nil {
+		return fmt.Errorf("random generator: %w", err)
+	}
 
 	logger.Debug("    Generated random value at path '%s'", def.TargetPath)
 	return nil
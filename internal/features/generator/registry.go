@@ -3,10 +3,12 @@ package generator
 import (
 	"fmt"
 	"konfigo/internal/logger"
+	"konfigo/internal/util"
 )
 
-// Apply applies all generators to the configuration using the default registry.
-func Apply(config map[string]interface{}, definitions []Definition, resolver VariableResolver) error {
+// Apply applies all generators to the configuration using the default
+// registry. Paths are looked up with paths.
+func Apply(config map[string]interface{}, definitions []Definition, resolver VariableResolver, paths util.Paths) error {
 	if len(definitions) == 0 {
 		return nil
 	}
@@ -24,10 +26,10 @@ func Apply(config map[string]interface{}, definitions []Definition, resolver Var
 
 		generator, exists := registry.Get(def.Type)
 		if !exists {
-			return fmt.Errorf("unsupported generator type: %s", def.Type)
+			return registry.unsupportedType(def.Type)
 		}
 
-		if err := generator.Generate(config, def, resolver); err != nil {
+		if err := generate(config, generator, def, resolver, paths); err != nil {
 			return fmt.Errorf("generator '%s' failed: %w", def.Type, err)
 		}
 	}
@@ -38,7 +40,7 @@ func Apply(config map[string]interface{}, definitions []Definition, resolver Var
 }
 
 // ApplyWithRegistry applies generators using a custom registry.
-func ApplyWithRegistry(config map[string]interface{}, definitions []Definition, resolver VariableResolver, registry Registry) error {
+func ApplyWithRegistry(config map[string]interface{}, definitions []Definition, resolver VariableResolver, registry Registry, paths util.Paths) error {
 	if len(definitions) == 0 {
 		return nil
 	}
@@ -52,10 +54,10 @@ func ApplyWithRegistry(config map[string]interface{}, definitions []Definition,
 	for _, def := range definitions {
 		generator, exists := registry.Get(def.Type)
 		if !exists {
-			return fmt.Errorf("unsupported generator type: %s", def.Type)
+			return registry.unsupportedType(def.Type)
 		}
 
-		if err := generator.Generate(config, def, resolver); err != nil {
+		if err := generate(config, generator, def, resolver, paths); err != nil {
 			return fmt.Errorf("generator '%s' failed: %w", def.Type, err)
 		}
 	}
@@ -64,6 +66,59 @@ func ApplyWithRegistry(config map[string]interface{}, definitions []Definition,
 	return nil
 }
 
+// generate runs a generator for def.TargetPath or, when it is a pattern, once
+// for every target that matches it. The last segment of a pattern may name a
+// new key: "services.*.id" generates an id in every map matching "services.*".
+// A pattern that matches nothing is not an error.
+func generate(config map[string]interface{}, generator Generator, def Definition, resolver VariableResolver, paths util.Paths) error {
+	if !util.IsPattern(def.TargetPath) {
+		return generator.Generate(config, def, resolver, paths)
+	}
+	targets := targetPaths(config, def.TargetPath, paths)
+	if len(targets) == 0 {
+		logger.Debug("    No path matches '%s', skipping", def.TargetPath)
+		return nil
+	}
+	for _, target := range targets {
+		concrete := def
+		concrete.TargetPath = target
+		if err := generator.Generate(config, concrete, resolver, paths); err != nil {
+			return fmt.Errorf("target '%s': %w", target, err)
+		}
+	}
+	return nil
+}
+
+// targetPaths returns the concrete targets of a target path pattern. When
+// the last segment is literal, it is appended to every value matching the
+// rest of the pattern: a key to every map, an index or [+] to every array.
+func targetPaths(config map[string]interface{}, pattern string, paths util.Paths) []string {
+	segments, err := util.ParsePath(pattern)
+	if err != nil || len(segments) < 2 {
+		return paths.Expand(config, pattern)
+	}
+	last := segments[len(segments)-1:]
+	if util.IsPattern(util.FormatPath(last)) {
+		return paths.Expand(config, pattern)
+	}
+
+	var targets []string
+	for _, parent := range paths.Expand(config, util.FormatPath(segments[:len(segments)-1])) {
+		value, _ := paths.Get(config, parent)
+		switch value.(type) {
+		case map[string]interface{}:
+			if last[0].Kind == util.SegmentKey {
+				targets = append(targets, util.AppendKey(parent, last[0].Key))
+			}
+		case []interface{}:
+			if last[0].Kind != util.SegmentKey {
+				targets = append(targets, parent+util.FormatPath(last))
+			}
+		}
+	}
+	return targets
+}
+
 // ValidateDefinitions validates all generator definitions using a new registry.
 func ValidateDefinitions(definitions []Definition) error {
 	return ValidateDefinitionsWithRegistry(definitions, NewRegistry())
@@ -72,19 +127,31 @@ func ValidateDefinitions(definitions []Definition) error {
 // ValidateDefinitionsWithRegistry validates all generator definitions using the provided registry.
 func ValidateDefinitionsWithRegistry(definitions []Definition, registry Registry) error {
 	for i, def := range definitions {
-		generator, exists := registry.Get(def.Type)
-		if !exists {
-			return fmt.Errorf("definition %d: unsupported generator type: %s", i, def.Type)
+		if err := validateDefinition(registry, def); err != nil {
+			return fmt.Errorf("definition %d: %w", i, err)
 		}
+	}
 
-		if validator, ok := generator.(interface {
-			ValidateDefinition(Definition) error
-		}); ok {
-			if err := validator.ValidateDefinition(def); err != nil {
-				return fmt.Errorf("definition %d: %w", i, err)
-			}
-		}
+	return nil
+}
+
+// ValidateDefinition validates a single generator definition against the
+// built-in generators.
+func ValidateDefinition(def Definition) error {
+	return validateDefinition(NewRegistry(), def)
+}
+
+// validateDefinition validates a single generator definition against the registry.
+func validateDefinition(registry Registry, def Definition) error {
+	generator, exists := registry.Get(def.Type)
+	if !exists {
+		return registry.unsupportedType(def.Type)
 	}
 
+	if validator, ok := generator.(interface {
+		ValidateDefinition(Definition) error
+	}); ok {
+		return validator.ValidateDefinition(def)
+	}
 	return nil
 }
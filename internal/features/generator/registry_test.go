@@ -0,0 +1,76 @@
+package generator
+
+import (
+	"konfigo/internal/util"
+	"strings"
+	"testing"
+)
+
+func TestApply_TargetPathPattern(t *testing.T) {
+	config := map[string]interface{}{
+		"services": map[string]interface{}{
+			"api": map[string]interface{}{"port": 80},
+			"web": map[string]interface{}{"port": 81},
+		},
+		"name": "shop",
+	}
+	defs := []Definition{
+		{Type: "concat", TargetPath: "services.*.owner", Format: "{n}", Sources: map[string]string{"n": "name"}},
+	}
+	if err := Apply(config, defs, nil, util.Paths{}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	for _, svc := range []string{"api", "web"} {
+		got := config["services"].(map[string]interface{})[svc].(map[string]interface{})["owner"]
+		if got != "shop" {
+			t.Errorf("services.%s.owner = %v, want shop", svc, got)
+		}
+	}
+}
+
+func TestApply_TargetPathPatternError(t *testing.T) {
+	config := map[string]interface{}{
+		"services": map[string]interface{}{"api": map[string]interface{}{}},
+	}
+	defs := []Definition{
+		{Type: "concat", TargetPath: "services.*.url", Format: "{h}", Sources: map[string]string{"h": "missing.host"}},
+	}
+	err := Apply(config, defs, nil, util.Paths{})
+	if err == nil {
+		t.Fatal("expected an error for a missing source")
+	}
+	if !strings.Contains(err.Error(), "services.api.url") {
+		t.Errorf("error should name the concrete target, got: %v", err)
+	}
+}
+
+func TestApply_TargetPathAppend(t *testing.T) {
+	config := map[string]interface{}{
+		"services": map[string]interface{}{
+			"api": map[string]interface{}{"tags": []interface{}{"a"}},
+		},
+		"name": "shop",
+	}
+	defs := []Definition{
+		{Type: "concat", TargetPath: "services.*.tags[+]", Format: "{n}", Sources: map[string]string{"n": "name"}},
+		{Type: "concat", TargetPath: "servers[+]", Format: "{n}", Sources: map[string]string{"n": "name"}},
+	}
+	if err := Apply(config, defs, nil, util.Paths{}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	tags := config["services"].(map[string]interface{})["api"].(map[string]interface{})["tags"].([]interface{})
+	if len(tags) != 2 || tags[1] != "shop" {
+		t.Errorf("services.api.tags = %v, want [a shop]", tags)
+	}
+	servers, _ := config["servers"].([]interface{})
+	if len(servers) != 1 || servers[0] != "shop" {
+		t.Errorf("servers = %v, want [shop]", config["servers"])
+	}
+}
+
+func TestValidateDefinition_SuggestsType(t *testing.T) {
+	err := ValidateDefinition(Definition{Type: "concatt", TargetPath: "url"})
+	if err == nil || !strings.Contains(err.Error(), "did you mean 'concat'?") {
+		t.Errorf("expected a suggestion for 'concatt', got %v", err)
+	}
+}
@@ -26,7 +26,7 @@ func (g *TimestampGenerator) Type() string {
 // - "rfc3339": RFC3339 format (e.g., "2006-01-02T15:04:05Z07:00")
 // - "iso8601": ISO8601 format (e.g., "2006-01-02T15:04:05Z")
 // - Custom Go time format string
-func (g *TimestampGenerator) Generate(config map[string]interface{}, def Definition, resolver VariableResolver) error {
+func (g *TimestampGenerator) Generate(config map[string]interface{}, def Definition, resolver VariableResolver, paths util.Paths) error {
 	logger.Debug("  - Applying timestamp generator for target path '%s'", def.TargetPath)
 
 	format := def.Format
@@ -57,7 +57,9 @@ func (g *TimestampGenerator) Generate(config map[string]interface{}, def Definit
 	}
 
 	// Set the generated value in the configuration
-	util.SetNestedValue(config, def.TargetPath, result)
+	if err := paths.Set(config, def.TargetPath, result); err != nil {
+		return fmt.Errorf("timestamp generator: %w", err)
+	}
 
 	logger.Debug("    Generated timestamp '%s' at path '%s'", result, def.TargetPath)
 	return nil
@@ -2,18 +2,27 @@
 // It supports various types of generators that can create values based on existing configuration.
 package generator
 
+import (
+	"fmt"
+	"konfigo/internal/features/condition"
+	"konfigo/internal/util"
+)
+
 // Definition represents a generator configuration.
 type Definition struct {
 	Type       string            `yaml:"type" json:"type"`
 	TargetPath string            `yaml:"targetPath" json:"targetPath"`
 	Format     string            `yaml:"format" json:"format"`
 	Sources    map[string]string `yaml:"sources" json:"sources"`
+	Expression string            `yaml:"expression,omitempty" json:"expression,omitempty"`
+	// When skips the generator unless the condition holds.
+	When *condition.Condition `yaml:"when,omitempty" json:"when,omitempty"`
 }
 
 // Generator represents a function that can generate configuration values.
 type Generator interface {
 	// Generate applies the generator logic to the configuration.
-	Generate(config map[string]interface{}, def Definition, resolver VariableResolver) error
+	Generate(config map[string]interface{}, def Definition, resolver VariableResolver, paths util.Paths) error
 
 	// Type returns the generator type name.
 	Type() string
@@ -26,6 +35,21 @@ type VariableResolver interface {
 	SubstituteString(input string) string
 }
 
+// CheckedVariableResolver is implemented by resolvers that evaluate
+// ${{ expression }} placeholders and report expression errors.
+type CheckedVariableResolver interface {
+	// SubstituteStringChecked performs variable substitution on a string and
+	// returns an error when an expression fails.
+	SubstituteStringChecked(input string) (string, error)
+}
+
+// ExpressionEvaluator is implemented by resolvers that can evaluate a bare
+// expression against their variables and the configuration.
+type ExpressionEvaluator interface {
+	// EvaluateExpression compiles and evaluates an expression.
+	EvaluateExpression(source string) (interface{}, error)
+}
+
 // Registry holds registered generators by type.
 type Registry map[string]Generator
 
@@ -38,6 +62,7 @@ func NewRegistry() Registry {
 	registry[TimestampGeneratorType] = &TimestampGenerator{}
 	registry[RandomGeneratorType] = &RandomGenerator{}
 	registry[IdGeneratorType] = &IdGenerator{}
+	registry[ExpressionGeneratorType] = &ExpressionGenerator{}
 
 	return registry
 }
@@ -53,6 +78,12 @@ func (r Registry) Get(generatorType string) (Generator, bool) {
 	return gen, exists
 }
 
+// unsupportedType returns the error for a generator type that is not
+// registered, suggesting the registered type it is closest to.
+func (r Registry) unsupportedType(name string) error {
+	return fmt.Errorf("unsupported generator type: %s%s", name, util.DidYouMean(name, r.GetTypes()))
+}
+
 // GetTypes returns all registered generator types.
 func (r Registry) GetTypes() []string {
 	types := make([]string, 0, len(r))
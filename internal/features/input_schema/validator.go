@@ -2,11 +2,14 @@ package input_schema
 
 import (
 	"fmt"
+	"konfigo/internal/features/jsonschema"
 	"konfigo/internal/logger"
 	"reflect"
 )
 
-// Validate validates a configuration map against an input schema.
+// Validate validates a configuration map against an input schema. A schema
+// declaring "$schema" is a JSON Schema document and reports every violation;
+// any other schema is a sample document the input must match.
 func Validate(config map[string]interface{}, ref *Ref) error {
 	logger.Log("Validating against input schema: %s", ref.Path)
 
@@ -15,6 +18,17 @@ func Validate(config map[string]interface{}, ref *Ref) error {
 		return err
 	}
 
+	if jsonschema.IsJSONSchema(schemaMap) {
+		schema, err := jsonschema.Compile(schemaMap)
+		if err != nil {
+			return fmt.Errorf("input schema %s: %w", ref.Path, err)
+		}
+		if ref.Strict {
+			logger.Debug("Input schema %s is a JSON Schema, 'strict' is ignored", ref.Path)
+		}
+		return schema.Validate(config)
+	}
+
 	return compareStructure(config, schemaMap, "", ref.Strict)
 }
 
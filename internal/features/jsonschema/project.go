@@ -0,0 +1,93 @@
+package jsonschema
+
+// Project returns a copy of value that only keeps the object properties the
+// schema declares. An object keeps all of its properties when its schema
+// declares none, or allows more through additionalProperties; declared
+// properties are projected recursively, as are array items.
+func (s *Schema) Project(value interface{}) interface{} {
+	return project(value, s.root.flatten(nil))
+}
+
+// project projects v onto the combined nodes that apply to it.
+func project(v interface{}, nodes []*node) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		declared, open := false, false
+		for _, n := range nodes {
+			if len(n.properties) > 0 || len(n.patternProperties) > 0 {
+				declared = true
+			}
+			if n.additional != nil && (n.additional.always == nil || *n.additional.always) {
+				open = true
+			}
+		}
+		result := make(map[string]interface{}, len(val))
+		for key, child := range val {
+			subs := propertyNodes(nodes, key)
+			if len(subs) == 0 {
+				if !declared || open {
+					result[key] = child
+				}
+				continue
+			}
+			result[key] = project(child, subs)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(val))
+		for i, item := range val {
+			var subs []*node
+			for _, n := range nodes {
+				if i < len(n.prefixItems) {
+					subs = n.prefixItems[i].flatten(subs)
+				} else if n.items != nil {
+					subs = n.items.flatten(subs)
+				}
+			}
+			result[i] = project(item, subs)
+		}
+		return result
+	}
+	return v
+}
+
+// propertyNodes returns the nodes that apply to the property key of an
+// object described by nodes.
+func propertyNodes(nodes []*node, key string) []*node {
+	var subs []*node
+	for _, n := range nodes {
+		matched := false
+		if sub, ok := n.properties[key]; ok {
+			subs = sub.flatten(subs)
+			matched = true
+		}
+		for _, pp := range n.patternProperties {
+			if pp.pattern.MatchString(key) {
+				subs = pp.schema.flatten(subs)
+				matched = true
+			}
+		}
+		if !matched && n.additional != nil && n.additional.always == nil {
+			subs = n.additional.flatten(subs)
+		}
+	}
+	return subs
+}
+
+// flatten appends n and the nodes it always applies, through $ref and
+// allOf, to nodes.
+func (n *node) flatten(nodes []*node) []*node {
+	for _, seen := range nodes {
+		if seen == n {
+			return nodes
+		}
+	}
+	nodes = append(nodes, n)
+	if n.refNode != nil {
+		nodes = n.refNode.flatten(nodes)
+	}
+	for _, sub := range n.allOf {
+		nodes = sub.flatten(nodes)
+	}
+	return nodes
+}
@@ -0,0 +1,540 @@
+// Package jsonschema validates configuration against JSON Schema (draft
+// 2020-12) documents.
+//
+// Schemas are compiled once with Compile and can then validate any number of
+// values. Validation does not stop at the first problem: every violation is
+// reported with the JSON pointer of the offending value.
+//
+// Supported keywords:
+//   - any value: type, enum, const, allOf, anyOf, oneOf, not, if/then/else, $ref, $defs
+//   - objects: properties, patternProperties, additionalProperties, required,
+//     propertyNames, minProperties, maxProperties, dependentRequired, dependentSchemas
+//   - arrays: prefixItems, items, contains, minContains, maxContains,
+//     minItems, maxItems, uniqueItems
+//   - strings: minLength, maxLength, pattern (Go regexp syntax), format
+//   - numbers: minimum, maximum, exclusiveMinimum, exclusiveMaximum, multipleOf
+//
+// $ref must point into the same document ("#/$defs/port" or "#anchor").
+// Keywords that need annotation tracking (unevaluatedProperties,
+// unevaluatedItems) and dynamic references are rejected when compiling.
+//
+// Usage:
+//
+//	if jsonschema.IsJSONSchema(doc) {
+//		schema, err := jsonschema.Compile(doc)
+//		...
+//		if err := schema.Validate(config); err != nil { ... }
+//	}
+package jsonschema
+
+import (
+	"fmt"
+	"konfigo/internal/logger"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Draft202012 is the $schema URI of JSON Schema draft 2020-12.
+const Draft202012 = "https://json-schema.org/draft/2020-12/schema"
+
+// IsJSONSchema reports whether doc is a JSON Schema document, i.e. declares
+// its dialect with a "$schema" string.
+func IsJSONSchema(doc map[string]interface{}) bool {
+	dialect, ok := doc["$schema"].(string)
+	return ok && dialect != ""
+}
+
+// Schema is a compiled JSON Schema document.
+type Schema struct {
+	root *node
+}
+
+// node is a compiled schema object or boolean schema.
+type node struct {
+	// location is the JSON pointer of the schema object in its document.
+	location string
+	// always is set for the boolean schemas true and false.
+	always *bool
+
+	types    []string
+	enum     []interface{}
+	hasConst bool
+	constVal interface{}
+
+	allOf, anyOf, oneOf   []*node
+	not, ifs, then, elses *node
+	ref                   string
+	refNode               *node
+
+	properties        map[string]*node
+	patternProperties []patternNode
+	additional        *node
+	required          []string
+	propertyNames     *node
+	minProperties     *int
+	maxProperties     *int
+	dependentRequired map[string][]string
+	dependentSchemas  map[string]*node
+
+	prefixItems []*node
+	items       *node
+	contains    *node
+	minContains *int
+	maxContains *int
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+	format    string
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       *float64
+}
+
+// patternNode is a patternProperties entry.
+type patternNode struct {
+	pattern *regexp.Regexp
+	schema  *node
+}
+
+// annotations lists keywords that carry no assertions.
+var annotations = map[string]bool{
+	"$schema": true, "$id": true, "$comment": true, "$defs": true, "definitions": true,
+	"$anchor": true, "$vocabulary": true, "title": true, "description": true, "default": true,
+	"examples": true, "deprecated": true, "readOnly": true, "writeOnly": true,
+	"contentEncoding": true, "contentMediaType": true, "contentSchema": true,
+}
+
+// unsupported lists keywords whose assertions this package does not implement.
+var unsupported = map[string]bool{
+	"unevaluatedProperties": true, "unevaluatedItems": true,
+	"$dynamicRef": true, "$dynamicAnchor": true, "$recursiveRef": true, "$recursiveAnchor": true,
+}
+
+// compiler compiles the schema objects of one document.
+type compiler struct {
+	doc     map[string]interface{}
+	anchors map[string]string
+	// compiled holds the nodes compiled so far by location, so that
+	// recursive references resolve to the same node.
+	compiled map[string]*node
+	// draft7 enables the array form of items and additionalItems of earlier drafts.
+	draft7 bool
+}
+
+// Compile compiles a JSON Schema document.
+func Compile(doc map[string]interface{}) (*Schema, error) {
+	c := &compiler{doc: doc, anchors: make(map[string]string), compiled: make(map[string]*node)}
+	dialect, _ := doc["$schema"].(string)
+	if strings.TrimSuffix(dialect, "#") != Draft202012 {
+		logger.Debug("JSON Schema dialect %s is validated with draft 2020-12 rules", dialect)
+		c.draft7 = !strings.Contains(dialect, "2019-09")
+	}
+	c.findAnchors(doc, "")
+
+	root, err := c.compile(doc, "")
+	if err != nil {
+		return nil, err
+	}
+	// Resolving a reference may compile more schemas with references
+	for {
+		var pending []string
+		for location, n := range c.compiled {
+			if n.ref != "" && n.refNode == nil {
+				pending = append(pending, location)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+		sort.Strings(pending)
+		for _, location := range pending {
+			n := c.compiled[location]
+			if n.refNode, err = c.resolveRef(n.ref); err != nil {
+				return nil, fmt.Errorf("schema %s: %w", pointerOrRoot(location), err)
+			}
+		}
+	}
+	return &Schema{root: root}, nil
+}
+
+// findAnchors records the location of every $anchor in the document.
+func (c *compiler) findAnchors(v interface{}, location string) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if anchor, ok := val["$anchor"].(string); ok {
+			c.anchors[anchor] = location
+		}
+		for key, child := range val {
+			c.findAnchors(child, location+"/"+escapePointer(key))
+		}
+	case []interface{}:
+		for i, child := range val {
+			c.findAnchors(child, location+"/"+strconv.Itoa(i))
+		}
+	}
+}
+
+// resolveRef returns the node a local $ref points to, compiling it if needed.
+func (c *compiler) resolveRef(ref string) (*node, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("$ref '%s' is not supported: only references within the same document ('#...') are", ref)
+	}
+	location := ref[1:]
+	if location != "" && !strings.HasPrefix(location, "/") {
+		anchor, ok := c.anchors[location]
+		if !ok {
+			return nil, fmt.Errorf("$ref '%s': no $anchor '%s'", ref, location)
+		}
+		location = anchor
+	}
+	if n, ok := c.compiled[location]; ok {
+		return n, nil
+	}
+	target, err := lookupPointer(c.doc, location)
+	if err != nil {
+		return nil, fmt.Errorf("$ref '%s': %w", ref, err)
+	}
+	return c.compile(target, location)
+}
+
+// lookupPointer returns the value at a JSON pointer in doc.
+func lookupPointer(doc map[string]interface{}, pointer string) (interface{}, error) {
+	var current interface{} = doc
+	if pointer == "" {
+		return current, nil
+	}
+	for _, token := range strings.Split(pointer[1:], "/") {
+		token = unescapePointer(token)
+		switch val := current.(type) {
+		case map[string]interface{}:
+			next, ok := val[token]
+			if !ok {
+				return nil, fmt.Errorf("%s not found", pointer)
+			}
+			current = next
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(val) {
+				return nil, fmt.Errorf("%s not found", pointer)
+			}
+			current = val[i]
+		default:
+			return nil, fmt.Errorf("%s not found", pointer)
+		}
+	}
+	return current, nil
+}
+
+// compile compiles the schema v found at location.
+func (c *compiler) compile(v interface{}, location string) (*node, error) {
+	if n, ok := c.compiled[location]; ok {
+		return n, nil
+	}
+	n := &node{location: location}
+	c.compiled[location] = n
+
+	if b, ok := v.(bool); ok {
+		n.always = &b
+		return n, nil
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("schema %s: must be an object or a boolean, got %T", pointerOrRoot(location), v)
+	}
+
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := c.keyword(n, obj, key, location+"/"+escapePointer(key)); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", pointerOrRoot(location+"/"+escapePointer(key)), err)
+		}
+	}
+	return n, nil
+}
+
+// keyword compiles the keyword key of the schema object obj into n.
+func (c *compiler) keyword(n *node, obj map[string]interface{}, key, location string) error {
+	value := obj[key]
+	var err error
+	switch key {
+	case "type":
+		n.types, err = stringList(value)
+		for _, t := range n.types {
+			if !knownTypes[t] {
+				return fmt.Errorf("unknown type '%s'", t)
+			}
+		}
+	case "enum":
+		list, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("must be an array")
+		}
+		n.enum = list
+	case "const":
+		n.hasConst, n.constVal = true, value
+	case "allOf", "anyOf", "oneOf":
+		nodes, err := c.compileList(value, location)
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "allOf":
+			n.allOf = nodes
+		case "anyOf":
+			n.anyOf = nodes
+		default:
+			n.oneOf = nodes
+		}
+	case "not":
+		n.not, err = c.compile(value, location)
+	case "if":
+		n.ifs, err = c.compile(value, location)
+	case "then":
+		n.then, err = c.compile(value, location)
+	case "else":
+		n.elses, err = c.compile(value, location)
+	case "$ref":
+		ref, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("must be a string")
+		}
+		n.ref = ref
+	case "properties", "dependentSchemas":
+		nodes, err := c.compileMap(value, location)
+		if err != nil {
+			return err
+		}
+		if key == "properties" {
+			n.properties = nodes
+		} else {
+			n.dependentSchemas = nodes
+		}
+	case "patternProperties":
+		nodes, err := c.compileMap(value, location)
+		if err != nil {
+			return err
+		}
+		patterns := make([]string, 0, len(nodes))
+		for pattern := range nodes {
+			patterns = append(patterns, pattern)
+		}
+		sort.Strings(patterns)
+		for _, pattern := range patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+			}
+			n.patternProperties = append(n.patternProperties, patternNode{pattern: re, schema: nodes[pattern]})
+		}
+	case "additionalProperties":
+		n.additional, err = c.compile(value, location)
+	case "required":
+		n.required, err = stringList(value)
+	case "propertyNames":
+		n.propertyNames, err = c.compile(value, location)
+	case "dependentRequired":
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("must be an object")
+		}
+		n.dependentRequired = make(map[string][]string, len(m))
+		for prop, list := range m {
+			if n.dependentRequired[prop], err = stringList(list); err != nil {
+				return fmt.Errorf("%s: %w", prop, err)
+			}
+		}
+	case "prefixItems":
+		n.prefixItems, err = c.compileList(value, location)
+	case "items":
+		if _, isList := value.([]interface{}); isList && c.draft7 {
+			n.prefixItems, err = c.compileList(value, location)
+		} else {
+			n.items, err = c.compile(value, location)
+		}
+	case "additionalItems":
+		if !c.draft7 {
+			return fmt.Errorf("is replaced by 'items' in draft 2020-12")
+		}
+		n.items, err = c.compile(value, location)
+	case "contains":
+		n.contains, err = c.compile(value, location)
+	case "pattern":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("must be a string")
+		}
+		if n.pattern, err = regexp.Compile(s); err != nil {
+			return fmt.Errorf("invalid pattern '%s': %w", s, err)
+		}
+	case "format":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("must be a string")
+		}
+		n.format = s
+	case "uniqueItems":
+		b, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("must be a boolean")
+		}
+		n.uniqueItems = b
+	case "minProperties", "maxProperties", "minContains", "maxContains", "minItems", "maxItems", "minLength", "maxLength":
+		count, err := nonNegative(value)
+		if err != nil {
+			return err
+		}
+		*countField(n, key) = &count
+	case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf":
+		f, ok := toFloat(value)
+		if !ok {
+			return fmt.Errorf("must be a number")
+		}
+		if key == "multipleOf" && f <= 0 {
+			return fmt.Errorf("must be greater than 0")
+		}
+		*numberField(n, key) = &f
+	default:
+		if unsupported[key] {
+			return fmt.Errorf("keyword '%s' is not supported", key)
+		}
+		if !annotations[key] {
+			logger.Debug("JSON Schema %s: ignoring unknown keyword '%s'", pointerOrRoot(location), key)
+		}
+	}
+	return err
+}
+
+// knownTypes lists the JSON Schema type names.
+var knownTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true,
+	"number": true, "integer": true, "string": true,
+}
+
+// countField returns the field of n that holds the count keyword key.
+func countField(n *node, key string) **int {
+	switch key {
+	case "minProperties":
+		return &n.minProperties
+	case "maxProperties":
+		return &n.maxProperties
+	case "minContains":
+		return &n.minContains
+	case "maxContains":
+		return &n.maxContains
+	case "minItems":
+		return &n.minItems
+	case "maxItems":
+		return &n.maxItems
+	case "minLength":
+		return &n.minLength
+	}
+	return &n.maxLength
+}
+
+// numberField returns the field of n that holds the number keyword key.
+func numberField(n *node, key string) **float64 {
+	switch key {
+	case "minimum":
+		return &n.minimum
+	case "maximum":
+		return &n.maximum
+	case "exclusiveMinimum":
+		return &n.exclusiveMinimum
+	case "exclusiveMaximum":
+		return &n.exclusiveMaximum
+	}
+	return &n.multipleOf
+}
+
+// compileList compiles an array of schemas.
+func (c *compiler) compileList(value interface{}, location string) ([]*node, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be an array of schemas")
+	}
+	nodes := make([]*node, len(list))
+	for i, item := range list {
+		n, err := c.compile(item, location+"/"+strconv.Itoa(i))
+		if err != nil {
+			return nil, err
+		}
+		nodes[i] = n
+	}
+	return nodes, nil
+}
+
+// compileMap compiles an object whose values are schemas.
+func (c *compiler) compileMap(value interface{}, location string) (map[string]*node, error) {
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be an object of schemas")
+	}
+	nodes := make(map[string]*node, len(m))
+	for key, item := range m {
+		n, err := c.compile(item, location+"/"+escapePointer(key))
+		if err != nil {
+			return nil, err
+		}
+		nodes[key] = n
+	}
+	return nodes, nil
+}
+
+// stringList accepts a string or an array of strings.
+func stringList(value interface{}) ([]string, error) {
+	if s, ok := value.(string); ok {
+		return []string{s}, nil
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be a string or an array of strings")
+	}
+	result := make([]string, len(list))
+	for i, item := range list {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be an array of strings")
+		}
+		result[i] = s
+	}
+	return result, nil
+}
+
+// nonNegative accepts a non-negative integer.
+func nonNegative(value interface{}) (int, error) {
+	f, ok := toFloat(value)
+	if !ok || f < 0 || f != float64(int(f)) {
+		return 0, fmt.Errorf("must be a non-negative integer")
+	}
+	return int(f), nil
+}
+
+// escapePointer escapes a key for use in a JSON pointer.
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+// unescapePointer reverses escapePointer.
+func unescapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+}
+
+// pointerOrRoot returns pointer, or "(root)" for the empty pointer.
+func pointerOrRoot(pointer string) string {
+	if pointer == "" {
+		return "(root)"
+	}
+	return pointer
+}
@@ -0,0 +1,229 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mustCompile compiles a JSON schema document for a test.
+func mustCompile(t *testing.T, doc string) *Schema {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(doc), &m); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Compile(m)
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+	return s
+}
+
+// paths returns the "path keyword" pairs of violations.
+func paths(violations []Violation) []string {
+	var result []string
+	for _, v := range violations {
+		result = append(result, v.Path+" "+v.Keyword)
+	}
+	return result
+}
+
+func TestIsJSONSchema(t *testing.T) {
+	if !IsJSONSchema(map[string]interface{}{"$schema": Draft202012}) {
+		t.Error("a document with $schema should be a JSON Schema")
+	}
+	if IsJSONSchema(map[string]interface{}{"server": map[string]interface{}{"port": 8080}}) {
+		t.Error("a sample document is not a JSON Schema")
+	}
+}
+
+func TestValidate_ReportsEveryViolation(t *testing.T) {
+	s := mustCompile(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"server": {
+				"type": "object",
+				"properties": {
+					"port": {"type": "integer", "minimum": 1024, "maximum": 65535},
+					"mode": {"enum": ["http", "https"]}
+				},
+				"additionalProperties": false
+			},
+			"a/b": {"type": "string"}
+		}
+	}`)
+	config := map[string]interface{}{
+		"server": map[string]interface{}{"port": 80, "mode": "ftp", "debug": true},
+		"a/b":    1,
+	}
+	got := paths(s.Violations(config))
+	want := []string{
+		" required",
+		"/a~1b type",
+		"/server additionalProperties",
+		"/server/mode enum",
+		"/server/port minimum",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("violations = %v, want %v", got, want)
+	}
+
+	err := s.Validate(config)
+	if err == nil || !strings.Contains(err.Error(), "5 violations") || !strings.Contains(err.Error(), "/server/port: 80 is less than 1024") {
+		t.Errorf("Validate error = %v", err)
+	}
+	if err := s.Validate(map[string]interface{}{"name": "x", "server": map[string]interface{}{"port": 8080.0}}); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+}
+
+func TestValidate_Keywords(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		valid   []interface{}
+		invalid []interface{}
+	}{
+		{"integer", `{"type": "integer"}`, []interface{}{1, 2.0, int64(3)}, []interface{}{1.5, "1"}},
+		{"nullable", `{"type": ["string", "null"]}`, []interface{}{"a", nil}, []interface{}{true}},
+		{"const", `{"const": 1}`, []interface{}{1, 1.0}, []interface{}{2}},
+		{"pattern", `{"pattern": "^v[0-9]+$"}`, []interface{}{"v12", 3}, []interface{}{"12"}},
+		{"length", `{"minLength": 2, "maxLength": 3}`, []interface{}{"ab", "äöü"}, []interface{}{"a", "abcd"}},
+		{"multipleOf", `{"multipleOf": 0.1}`, []interface{}{0.3, 2}, []interface{}{0.35}},
+		{"exclusive", `{"exclusiveMinimum": 0, "exclusiveMaximum": 1}`, []interface{}{0.5}, []interface{}{0, 1}},
+		{"format", `{"format": "ipv4"}`, []interface{}{"10.0.0.1"}, []interface{}{"10.0.0", "::1"}},
+		{"oneOf", `{"oneOf": [{"type": "integer"}, {"minimum": 10}]}`, []interface{}{5, 10.5}, []interface{}{20, 1.5}},
+		{"anyOf", `{"anyOf": [{"type": "string"}, {"type": "boolean"}]}`, []interface{}{"a", true}, []interface{}{1}},
+		{"not", `{"not": {"type": "string"}}`, []interface{}{1}, []interface{}{"a"}},
+		{"if", `{"if": {"properties": {"tls": {"const": true}}, "required": ["tls"]}, "then": {"required": ["cert"]}}`,
+			[]interface{}{map[string]interface{}{"tls": false}, map[string]interface{}{"tls": true, "cert": "c"}},
+			[]interface{}{map[string]interface{}{"tls": true}}},
+		{"dependentRequired", `{"dependentRequired": {"user": ["password"]}}`,
+			[]interface{}{map[string]interface{}{}, map[string]interface{}{"user": "u", "password": "p"}},
+			[]interface{}{map[string]interface{}{"user": "u"}}},
+		{"patternProperties", `{"patternProperties": {"^x-": {"type": "string"}}, "additionalProperties": false}`,
+			[]interface{}{map[string]interface{}{"x-a": "1"}},
+			[]interface{}{map[string]interface{}{"x-a": 1}, map[string]interface{}{"y": "1"}}},
+		{"prefixItems", `{"prefixItems": [{"type": "string"}], "items": false}`,
+			[]interface{}{[]interface{}{"a"}},
+			[]interface{}{[]interface{}{1}, []interface{}{"a", "b"}}},
+		{"contains", `{"contains": {"const": "x"}, "maxContains": 1}`,
+			[]interface{}{[]interface{}{"x", "y"}},
+			[]interface{}{[]interface{}{"y"}, []interface{}{"x", "x"}}},
+		{"uniqueItems", `{"uniqueItems": true}`,
+			[]interface{}{[]interface{}{1, "1"}},
+			[]interface{}{[]interface{}{map[string]interface{}{"a": 1}, map[string]interface{}{"a": 1.0}}}},
+		{"false", `{"properties": {"legacy": false}}`,
+			[]interface{}{map[string]interface{}{}},
+			[]interface{}{map[string]interface{}{"legacy": 1}}},
+	}
+	for _, tt := range tests {
+		s := mustCompile(t, tt.schema)
+		for _, v := range tt.valid {
+			if err := s.Validate(v); err != nil {
+				t.Errorf("%s: %v should be valid: %v", tt.name, v, err)
+			}
+		}
+		for _, v := range tt.invalid {
+			if err := s.Validate(v); err == nil {
+				t.Errorf("%s: %v should be invalid", tt.name, v)
+			}
+		}
+	}
+}
+
+func TestValidate_Refs(t *testing.T) {
+	s := mustCompile(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"$defs": {
+			"node": {
+				"type": "object",
+				"properties": {"name": {"$ref": "#name"}, "children": {"type": "array", "items": {"$ref": "#/$defs/node"}}},
+				"required": ["name"]
+			},
+			"name": {"$anchor": "name", "type": "string", "minLength": 1}
+		},
+		"$ref": "#/$defs/node"
+	}`)
+	tree := map[string]interface{}{
+		"name": "root",
+		"children": []interface{}{
+			map[string]interface{}{"name": "a"},
+			map[string]interface{}{"name": "", "children": []interface{}{map[string]interface{}{}}},
+		},
+	}
+	got := paths(s.Violations(tree))
+	want := []string{"/children/1/children/0 required", "/children/1/name minLength"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("violations = %v, want %v", got, want)
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := map[string]string{
+		`{"$ref": "other.json#/x"}`:               "only references within the same document",
+		`{"$ref": "#/$defs/missing"}`:             "/$defs/missing not found",
+		`{"unevaluatedProperties": false}`:        "not supported",
+		`{"properties": {"a": {"type": "text"}}}`: "schema /properties/a/type: unknown type 'text'",
+		`{"pattern": "("}`:                        "invalid pattern",
+		`{"minLength": -1}`:                       "must be a non-negative integer",
+	}
+	for doc, want := range tests {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(doc), &m); err != nil {
+			t.Fatal(err)
+		}
+		m["$schema"] = Draft202012
+		_, err := Compile(m)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Compile(%s) error = %v, want it to contain %q", doc, err, want)
+		}
+	}
+}
+
+func TestCompile_Draft7Items(t *testing.T) {
+	s := mustCompile(t, `{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"items": [{"type": "string"}],
+		"additionalItems": {"type": "integer"}
+	}`)
+	if err := s.Validate([]interface{}{"a", 1, 2}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := s.Validate([]interface{}{1, "a"}); err == nil {
+		t.Error("expected item violations")
+	}
+}
+
+func TestProject(t *testing.T) {
+	s := mustCompile(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"properties": {
+			"server": {"$ref": "#/$defs/server"},
+			"labels": {"type": "object"},
+			"limits": {"properties": {"cpu": {}}, "additionalProperties": {"type": "integer"}},
+			"routes": {"items": {"properties": {"path": {}}}}
+		},
+		"$defs": {"server": {"properties": {"port": {}}}}
+	}`)
+	config := map[string]interface{}{
+		"server": map[string]interface{}{"port": 80, "debug": true},
+		"labels": map[string]interface{}{"team": "a"},
+		"limits": map[string]interface{}{"cpu": 1, "memory": 2},
+		"routes": []interface{}{map[string]interface{}{"path": "/", "internal": true}},
+		"secret": "x",
+	}
+	want := map[string]interface{}{
+		"server": map[string]interface{}{"port": 80},
+		"labels": map[string]interface{}{"team": "a"},
+		"limits": map[string]interface{}{"cpu": 1, "memory": 2},
+		"routes": []interface{}{map[string]interface{}{"path": "/"}},
+	}
+	if got := s.Project(config); !reflect.DeepEqual(got, want) {
+		t.Errorf("Project = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,438 @@
+package jsonschema
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Violation is a value that does not satisfy a schema keyword.
+type Violation struct {
+	// Path is the JSON pointer of the value, e.g. "/server/port".
+	Path string
+	// Keyword is the schema keyword that failed, e.g. "minimum".
+	Keyword string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s", pointerOrRoot(v.Path), v.Message)
+}
+
+// ValidationError lists every violation found by Schema.Validate.
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	if len(e.Violations) == 1 {
+		b.WriteString("1 violation:")
+	} else {
+		fmt.Fprintf(&b, "%d violations:", len(e.Violations))
+	}
+	for _, v := range e.Violations {
+		b.WriteString("\n  ")
+		b.WriteString(v.String())
+	}
+	return b.String()
+}
+
+// Validate validates value against the schema. It returns a *ValidationError
+// listing every violation, or nil if value is valid.
+func (s *Schema) Validate(value interface{}) error {
+	violations := s.Violations(value)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: violations}
+}
+
+// Violations returns every violation of the schema by value.
+func (s *Schema) Violations(value interface{}) []Violation {
+	var violations []Violation
+	s.root.validate(value, "", &violations)
+	return violations
+}
+
+// valid reports whether v satisfies n, without collecting violations.
+func (n *node) valid(v interface{}) bool {
+	var violations []Violation
+	n.validate(v, "", &violations)
+	return len(violations) == 0
+}
+
+// validate appends the violations of n by the value v at path to out.
+func (n *node) validate(v interface{}, path string, out *[]Violation) {
+	fail := func(keyword, format string, args ...interface{}) {
+		*out = append(*out, Violation{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if n.always != nil {
+		if !*n.always {
+			fail("false", "no value is allowed here")
+		}
+		return
+	}
+	if n.refNode != nil {
+		n.refNode.validate(v, path, out)
+	}
+
+	if len(n.types) > 0 && !matchesType(v, n.types) {
+		fail("type", "expected %s, got %s", strings.Join(n.types, " or "), typeName(v))
+		// The remaining keywords describe a different type
+		return
+	}
+	if n.enum != nil && !containsEqual(n.enum, v) {
+		fail("enum", "value %s is not one of %s", formatValue(v), formatValue(n.enum))
+	}
+	if n.hasConst && !equal(n.constVal, v) {
+		fail("const", "value %s must be %s", formatValue(v), formatValue(n.constVal))
+	}
+
+	n.validateApplicators(v, path, out, fail)
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		n.validateObject(val, path, out, fail)
+	case []interface{}:
+		n.validateArray(val, path, out, fail)
+	case string:
+		n.validateString(val, fail)
+	default:
+		if f, ok := toFloat(v); ok {
+			n.validateNumber(f, fail)
+		}
+	}
+}
+
+// validateApplicators checks allOf, anyOf, oneOf, not and if/then/else.
+func (n *node) validateApplicators(v interface{}, path string, out *[]Violation, fail func(string, string, ...interface{})) {
+	for _, sub := range n.allOf {
+		sub.validate(v, path, out)
+	}
+	if len(n.anyOf) > 0 {
+		matched := false
+		for _, sub := range n.anyOf {
+			if sub.valid(v) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fail("anyOf", "value does not match any of the %d anyOf schemas", len(n.anyOf))
+		}
+	}
+	if len(n.oneOf) > 0 {
+		var matches []string
+		for i, sub := range n.oneOf {
+			if sub.valid(v) {
+				matches = append(matches, strconv.Itoa(i))
+			}
+		}
+		switch len(matches) {
+		case 1:
+		case 0:
+			fail("oneOf", "value does not match any of the %d oneOf schemas", len(n.oneOf))
+		default:
+			fail("oneOf", "value matches oneOf schemas %s, want exactly one", strings.Join(matches, ", "))
+		}
+	}
+	if n.not != nil && n.not.valid(v) {
+		fail("not", "value must not match the 'not' schema")
+	}
+	if n.ifs != nil {
+		if n.ifs.valid(v) {
+			if n.then != nil {
+				n.then.validate(v, path, out)
+			}
+		} else if n.elses != nil {
+			n.elses.validate(v, path, out)
+		}
+	}
+}
+
+// validateObject checks the object keywords.
+func (n *node) validateObject(obj map[string]interface{}, path string, out *[]Violation, fail func(string, string, ...interface{})) {
+	for _, name := range n.required {
+		if _, ok := obj[name]; !ok {
+			fail("required", "missing required property '%s'", name)
+		}
+	}
+	if n.minProperties != nil && len(obj) < *n.minProperties {
+		fail("minProperties", "has %d properties, want at least %d", len(obj), *n.minProperties)
+	}
+	if n.maxProperties != nil && len(obj) > *n.maxProperties {
+		fail("maxProperties", "has %d properties, want at most %d", len(obj), *n.maxProperties)
+	}
+
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		for _, name := range n.dependentRequired[key] {
+			if _, ok := obj[name]; !ok {
+				fail("dependentRequired", "property '%s' requires property '%s'", key, name)
+			}
+		}
+		if dep, ok := n.dependentSchemas[key]; ok {
+			dep.validate(obj, path, out)
+		}
+	}
+
+	for _, key := range keys {
+		child := path + "/" + escapePointer(key)
+		if n.propertyNames != nil && !n.propertyNames.valid(key) {
+			fail("propertyNames", "property name '%s' is not allowed", key)
+		}
+		evaluated := false
+		if sub, ok := n.properties[key]; ok {
+			sub.validate(obj[key], child, out)
+			evaluated = true
+		}
+		for _, pp := range n.patternProperties {
+			if pp.pattern.MatchString(key) {
+				pp.schema.validate(obj[key], child, out)
+				evaluated = true
+			}
+		}
+		if !evaluated && n.additional != nil {
+			if n.additional.always != nil && !*n.additional.always {
+				fail("additionalProperties", "property '%s' is not allowed", key)
+			} else {
+				n.additional.validate(obj[key], child, out)
+			}
+		}
+	}
+}
+
+// validateArray checks the array keywords.
+func (n *node) validateArray(arr []interface{}, path string, out *[]Violation, fail func(string, string, ...interface{})) {
+	if n.minItems != nil && len(arr) < *n.minItems {
+		fail("minItems", "has %d items, want at least %d", len(arr), *n.minItems)
+	}
+	if n.maxItems != nil && len(arr) > *n.maxItems {
+		fail("maxItems", "has %d items, want at most %d", len(arr), *n.maxItems)
+	}
+	if n.uniqueItems {
+	outer:
+		for i := 1; i < len(arr); i++ {
+			for j := 0; j < i; j++ {
+				if equal(arr[i], arr[j]) {
+					fail("uniqueItems", "items %d and %d are equal", j, i)
+					break outer
+				}
+			}
+		}
+	}
+
+	for i, item := range arr {
+		child := path + "/" + strconv.Itoa(i)
+		if i < len(n.prefixItems) {
+			n.prefixItems[i].validate(item, child, out)
+		} else if n.items != nil {
+			if n.items.always != nil && !*n.items.always {
+				fail("items", "has %d items, want at most %d", len(arr), len(n.prefixItems))
+				break
+			}
+			n.items.validate(item, child, out)
+		}
+	}
+
+	if n.contains != nil {
+		count := 0
+		for _, item := range arr {
+			if n.contains.valid(item) {
+				count++
+			}
+		}
+		min := 1
+		if n.minContains != nil {
+			min = *n.minContains
+		}
+		if count < min {
+			fail("contains", "has %d items matching 'contains', want at least %d", count, min)
+		}
+		if n.maxContains != nil && count > *n.maxContains {
+			fail("maxContains", "has %d items matching 'contains', want at most %d", count, *n.maxContains)
+		}
+	}
+}
+
+// validateString checks the string keywords.
+func (n *node) validateString(s string, fail func(string, string, ...interface{})) {
+	length := utf8.RuneCountInString(s)
+	if n.minLength != nil && length < *n.minLength {
+		fail("minLength", "length %d is shorter than %d", length, *n.minLength)
+	}
+	if n.maxLength != nil && length > *n.maxLength {
+		fail("maxLength", "length %d is longer than %d", length, *n.maxLength)
+	}
+	if n.pattern != nil && !n.pattern.MatchString(s) {
+		fail("pattern", "%q does not match pattern '%s'", s, n.pattern.String())
+	}
+	if n.format != "" {
+		if check, ok := formats[n.format]; ok && !check(s) {
+			fail("format", "%q is not a valid %s", s, n.format)
+		}
+	}
+}
+
+// validateNumber checks the number keywords.
+func (n *node) validateNumber(f float64, fail func(string, string, ...interface{})) {
+	if n.minimum != nil && f < *n.minimum {
+		fail("minimum", "%v is less than %v", f, *n.minimum)
+	}
+	if n.maximum != nil && f > *n.maximum {
+		fail("maximum", "%v is greater than %v", f, *n.maximum)
+	}
+	if n.exclusiveMinimum != nil && f <= *n.exclusiveMinimum {
+		fail("exclusiveMinimum", "%v must be greater than %v", f, *n.exclusiveMinimum)
+	}
+	if n.exclusiveMaximum != nil && f >= *n.exclusiveMaximum {
+		fail("exclusiveMaximum", "%v must be less than %v", f, *n.exclusiveMaximum)
+	}
+	if n.multipleOf != nil {
+		q := f / *n.multipleOf
+		if math.IsInf(q, 0) || math.Abs(q-math.Round(q)) > 1e-9 {
+			fail("multipleOf", "%v is not a multiple of %v", f, *n.multipleOf)
+		}
+	}
+}
+
+// matchesType reports whether v is of one of the JSON types.
+func matchesType(v interface{}, types []string) bool {
+	actual := typeName(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeName returns the JSON type of v. Numbers without a fractional part are
+// integers, so that 8080 read from JSON matches "integer".
+func typeName(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case float32, float64:
+		f, _ := toFloat(val)
+		if f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	if _, ok := toFloat(v); ok {
+		return "integer"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// toFloat converts any Go number to a float64.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// equal compares two values as JSON: numbers by value, 1 and 1.0 are equal.
+func equal(a, b interface{}) bool {
+	if fa, ok := toFloat(a); ok {
+		fb, ok := toFloat(b)
+		return ok && fa == fb
+	}
+	switch va := a.(type) {
+	case map[string]interface{}:
+		vb, ok := b.(map[string]interface{})
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for k, x := range va {
+			y, ok := vb[k]
+			if !ok || !equal(x, y) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok || len(va) != len(vb) {
+			return false
+		}
+		for i := range va {
+			if !equal(va[i], vb[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// containsEqual reports whether list holds a value equal to v.
+func containsEqual(list []interface{}, v interface{}) bool {
+	for _, item := range list {
+		if equal(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatValue formats a value for a violation message.
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return strconv.Quote(val)
+	case []interface{}:
+		parts := make([]string, len(val))
+		for i, item := range val {
+			parts[i] = formatValue(item)
+		}
+		return "[" + strings.Join(parts, ", ") + "]"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -19,11 +19,11 @@ func (t *AddKeyPrefixTransformer) Type() string {
 
 // Transform implements the add key prefix transformation logic.
 // It adds a prefix to all keys in a map structure.
-func (t *AddKeyPrefixTransformer) Transform(config map[string]interface{}, def Definition) error {
+func (t *AddKeyPrefixTransformer) Transform(config map[string]interface{}, def Definition, paths util.Paths) error {
 	logger.Debug("  - Applying addKeyPrefix transform at path '%s' with prefix '%s'", def.Path, def.Prefix)
 
 	// Get the value from the specified path
-	value, found := util.GetNestedValue(config, def.Path)
+	value, found := paths.Get(config, def.Path)
 	if !found {
 		return fmt.Errorf("addKeyPrefix: path '%s' not found", def.Path)
 	}
@@ -42,7 +42,9 @@ func (t *AddKeyPrefixTransformer) Transform(config map[string]interface{}, def D
 	}
 
 	// Set the new map at the path
-	util.SetNestedValue(config, def.Path, newMap)
+	if err := paths.Set(config, def.Path, newMap); err != nil {
+		return fmt.Errorf("addKeyPrefix: %w", err)
+	}
 
 	logger.Debug("    Added prefix '%s' to %d keys", def.Prefix, len(mapValue))
 	return nil
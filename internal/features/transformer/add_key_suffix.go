@@ -19,11 +19,11 @@ func (t *AddKeySuffixTransformer) Type() string {
 
 // Transform implements the add key suffix transformation logic.
 // It adds a suffix to all keys in a map structure.
-func (t *AddKeySuffixTransformer) Transform(config map[string]interface{}, def Definition) error {
+func (t *AddKeySuffixTransformer) Transform(config map[string]interface{}, def Definition, paths util.Paths) error {
 	logger.Debug("  - Applying addKeySuffix transform at path '%s' with suffix '%s'", def.Path, def.Suffix)
 
 	// Get the value from the specified path
-	value, found := util.GetNestedValue(config, def.Path)
+	value, found := paths.Get(config, def.Path)
 	if !found {
 		return fmt.Errorf("addKeySuffix: path '%s' not found", def.Path)
 	}
@@ -42,7 +42,9 @@ func (t *AddKeySuffixTransformer) Transform(config map[string]interface{}, def D
 	}
 
 	// Set the new map at the path
-	util.SetNestedValue(config, def.Path, newMap)
+	if err := paths.Set(config, def.Path, newMap); err != nil {
+		return fmt.Errorf("addKeySuffix: %w", err)
+	}
 
 	logger.Debug("    Added suffix '%s' to %d keys", def.Suffix, len(mapValue))
 	return nil
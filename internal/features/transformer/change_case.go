@@ -4,9 +4,6 @@ import (
 	"fmt"
 	"konfigo/internal/logger"
 	"konfigo/internal/util"
-	"strings"
-
-	"github.com/iancoleman/strcase"
 )
 
 // ChangeCaseType is the type identifier for the change case transformer.
@@ -22,11 +19,11 @@ func (t *ChangeCaseTransformer) Type() string {
 
 // Transform implements the change case transformation logic.
 // It changes the case of a string value at the specified path.
-func (t *ChangeCaseTransformer) Transform(config map[string]interface{}, def Definition) error {
+func (t *ChangeCaseTransformer) Transform(config map[string]interface{}, def Definition, paths util.Paths) error {
 	logger.Debug("  - Applying changeCase transform at path '%s' to case '%s'", def.Path, def.Case)
 
 	// Get the value from the specified path
-	value, found := util.GetNestedValue(config, def.Path)
+	value, found := paths.Get(config, def.Path)
 	if !found {
 		return fmt.Errorf("changeCase: path '%s' not found", def.Path)
 	}
@@ -44,7 +41,9 @@ func (t *ChangeCaseTransformer) Transform(config map[string]interface{}, def Def
 	}
 
 	// Set the transformed value
-	util.SetNestedValue(config, def.Path, newValue)
+	if err := paths.Set(config, def.Path, newValue); err != nil {
+		return fmt.Errorf("changeCase: %w", err)
+	}
 
 	logger.Debug("    Changed case from '%s' to '%s'", strValue, newValue)
 	return nil
@@ -52,22 +51,7 @@ func (t *ChangeCaseTransformer) Transform(config map[string]interface{}, def Def
 
 // applyCase applies the specified case transformation to a string.
 func (t *ChangeCaseTransformer) applyCase(input, caseType string) (string, error) {
-	switch strings.ToLower(caseType) {
-	case "upper":
-		return strings.ToUpper(input), nil
-	case "lower":
-		return strings.ToLower(input), nil
-	case "snake":
-		return strcase.ToSnake(input), nil
-	case "camel":
-		return strcase.ToLowerCamel(input), nil
-	case "kebab":
-		return strcase.ToKebab(input), nil
-	case "pascal":
-		return strcase.ToCamel(input), nil
-	default:
-		return "", fmt.Errorf("unsupported case type '%s'. Supported: upper, lower, snake, camel, kebab, pascal", caseType)
-	}
+	return util.ChangeCase(input, caseType)
 }
 
 // ValidateDefinition validates a change case transformer definition.
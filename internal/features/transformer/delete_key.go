@@ -19,17 +19,19 @@ func (t *DeleteKeyTransformer) Type() string {
 
 // Transform implements the delete key transformation logic.
 // It removes a key from the configuration.
-func (t *DeleteKeyTransformer) Transform(config map[string]interface{}, def Definition) error {
+func (t *DeleteKeyTransformer) Transform(config map[string]interface{}, def Definition, paths util.Paths) error {
 	logger.Debug("  - Applying deleteKey transform at path '%s'", def.Path)
 
 	// Check if the path exists
-	_, found := util.GetNestedValue(config, def.Path)
+	_, found := paths.Get(config, def.Path)
 	if !found {
 		return fmt.Errorf("deleteKey: path '%s' not found", def.Path)
 	}
 
 	// Delete the value at the path
-	util.DeleteNestedValue(config, def.Path)
+	if err := paths.Delete(config, def.Path); err != nil {
+		return fmt.Errorf("deleteKey: %w", err)
+	}
 
 	logger.Debug("    Deleted key at path '%s'", def.Path)
 	return nil
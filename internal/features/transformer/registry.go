@@ -3,10 +3,12 @@ package transformer
 import (
 	"fmt"
 	"konfigo/internal/logger"
+	"konfigo/internal/util"
 )
 
-// Apply applies all transformations to the configuration using the default registry.
-func Apply(config map[string]interface{}, definitions []Definition, resolver VariableResolver) error {
+// Apply applies all transformations to the configuration using the default
+// registry. Paths are looked up with paths.
+func Apply(config map[string]interface{}, definitions []Definition, resolver VariableResolver, paths util.Paths) error {
 	if len(definitions) == 0 {
 		return nil
 	}
@@ -17,7 +19,10 @@ func Apply(config map[string]interface{}, definitions []Definition, resolver Var
 
 	for _, def := range definitions {
 		// Substitute variables first, then validate the resolved definition
-		processedDef := substituteInDefinition(def, resolver)
+		processedDef, err := substituteInDefinition(def, resolver)
+		if err != nil {
+			return fmt.Errorf("transformer '%s': %w", def.Type, err)
+		}
 
 		if err := validateSingleDefinition(registry, processedDef); err != nil {
 			return err
@@ -27,10 +32,10 @@ func Apply(config map[string]interface{}, definitions []Definition, resolver Var
 
 		transformer, exists := registry.Get(processedDef.Type)
 		if !exists {
-			return fmt.Errorf("unsupported transformer type: %s", processedDef.Type)
+			return registry.unsupportedType(processedDef.Type)
 		}
 
-		if err := transformer.Transform(config, processedDef); err != nil {
+		if err := transform(config, transformer, processedDef, paths); err != nil {
 			return fmt.Errorf("transformer '%s' failed: %w", processedDef.Type, err)
 		}
 	}
@@ -40,7 +45,7 @@ func Apply(config map[string]interface{}, definitions []Definition, resolver Var
 }
 
 // ApplyWithRegistry applies transformations using a custom registry.
-func ApplyWithRegistry(config map[string]interface{}, definitions []Definition, resolver VariableResolver, registry Registry) error {
+func ApplyWithRegistry(config map[string]interface{}, definitions []Definition, resolver VariableResolver, registry Registry, paths util.Paths) error {
 	if len(definitions) == 0 {
 		return nil
 	}
@@ -48,7 +53,10 @@ func ApplyWithRegistry(config map[string]interface{}, definitions []Definition,
 	logger.Debug("Applying %d transformations with custom registry...", len(definitions))
 
 	for _, def := range definitions {
-		processedDef := substituteInDefinition(def, resolver)
+		processedDef, err := substituteInDefinition(def, resolver)
+		if err != nil {
+			return fmt.Errorf("transformer '%s': %w", def.Type, err)
+		}
 
 		if err := validateSingleDefinition(registry, processedDef); err != nil {
 			return err
@@ -56,10 +64,10 @@ func ApplyWithRegistry(config map[string]interface{}, definitions []Definition,
 
 		transformer, exists := registry.Get(processedDef.Type)
 		if !exists {
-			return fmt.Errorf("unsupported transformer type: %s", processedDef.Type)
+			return registry.unsupportedType(processedDef.Type)
 		}
 
-		if err := transformer.Transform(config, processedDef); err != nil {
+		if err := transform(config, transformer, processedDef, paths); err != nil {
 			return fmt.Errorf("transformer '%s' failed: %w", processedDef.Type, err)
 		}
 	}
@@ -67,11 +75,33 @@ func ApplyWithRegistry(config map[string]interface{}, definitions []Definition,
 	return nil
 }
 
+// transform applies a transformer at def.Path or, when def.Path is a pattern
+// such as "services.*.name", at every path that matches it. A pattern that
+// matches nothing is not an error.
+func transform(config map[string]interface{}, transformer Transformer, def Definition, paths util.Paths) error {
+	if !util.IsPattern(def.Path) {
+		return transformer.Transform(config, def, paths)
+	}
+	matches := paths.Expand(config, def.Path)
+	if len(matches) == 0 {
+		logger.Debug("    No path matches '%s', skipping", def.Path)
+		return nil
+	}
+	for _, path := range matches {
+		concrete := def
+		concrete.Path = path
+		if err := transformer.Transform(config, concrete, paths); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // validateSingleDefinition validates a single transformer definition against the registry.
 func validateSingleDefinition(registry Registry, def Definition) error {
 	transformer, exists := registry.Get(def.Type)
 	if !exists {
-		return fmt.Errorf("unsupported transformer type: %s", def.Type)
+		return registry.unsupportedType(def.Type)
 	}
 	if validator, ok := transformer.(interface {
 		ValidateDefinition(Definition) error
@@ -83,6 +113,13 @@ func validateSingleDefinition(registry Registry, def Definition) error {
 	return nil
 }
 
+// ValidateDefinition validates a single transformer definition against the
+// built-in transformers. Fields holding ${VAR} placeholders are checked as
+// written, before substitution.
+func ValidateDefinition(def Definition) error {
+	return validateSingleDefinition(NewRegistry(), def)
+}
+
 // ValidateDefinitions validates all transformer definitions (pre-substitution, for structural checks only).
 func ValidateDefinitions(definitions []Definition) error {
 	registry := NewRegistry()
@@ -90,7 +127,7 @@ func ValidateDefinitions(definitions []Definition) error {
 	for i, def := range definitions {
 		_, exists := registry.Get(def.Type)
 		if !exists {
-			return fmt.Errorf("definition %d: unsupported transformer type: %s", i, def.Type)
+			return fmt.Errorf("definition %d: %w", i, registry.unsupportedType(def.Type))
 		}
 	}
 
@@ -98,9 +135,14 @@ func ValidateDefinitions(definitions []Definition) error {
 }
 
 // substituteInDefinition performs variable substitution on definition fields.
-func substituteInDefinition(def Definition, resolver VariableResolver) Definition {
+// Expression errors are reported when the resolver supports checked substitution.
+func substituteInDefinition(def Definition, resolver VariableResolver) (Definition, error) {
 	if resolver == nil {
-		return def
+		return def, nil
+	}
+
+	if checked, ok := resolver.(CheckedVariableResolver); ok {
+		return substituteInDefinitionChecked(def, checked)
 	}
 
 	// Create a copy and substitute variables
@@ -114,10 +156,51 @@ func substituteInDefinition(def Definition, resolver VariableResolver) Definitio
 	processed.Target = resolver.SubstituteString(def.Target)
 	processed.Case = resolver.SubstituteString(def.Case)
 
-	// Handle Value field if it's a string
+	// Handle Value field if it's a string, keeping the native type of
+	// whole-value placeholders when the resolver supports it
 	if s, ok := def.Value.(string); ok {
-		processed.Value = resolver.SubstituteString(s)
+		if typedResolver, ok := resolver.(TypedVariableResolver); ok {
+			processed.Value = typedResolver.SubstituteValue(s)
+		} else {
+			processed.Value = resolver.SubstituteString(s)
+		}
+	}
+
+	return processed, nil
+}
+
+// substituteInDefinitionChecked substitutes definition fields and fails on the
+// first field whose ${{ expression }} cannot be evaluated.
+func substituteInDefinitionChecked(def Definition, resolver CheckedVariableResolver) (Definition, error) {
+	processed := def
+	fields := []struct {
+		name string
+		dst  *string
+	}{
+		{"path", &processed.Path},
+		{"from", &processed.From},
+		{"to", &processed.To},
+		{"prefix", &processed.Prefix},
+		{"suffix", &processed.Suffix},
+		{"pattern", &processed.Pattern},
+		{"target", &processed.Target},
+		{"case", &processed.Case},
+	}
+	for _, field := range fields {
+		out, err := resolver.SubstituteStringChecked(*field.dst)
+		if err != nil {
+			return def, fmt.Errorf("field '%s': %w", field.name, err)
+		}
+		*field.dst = out
+	}
+
+	if s, ok := def.Value.(string); ok {
+		out, err := resolver.SubstituteValueChecked(s)
+		if err != nil {
+			return def, fmt.Errorf("field 'value': %w", err)
+		}
+		processed.Value = out
 	}
 
-	return processed
+	return processed, nil
 }
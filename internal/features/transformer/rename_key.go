@@ -19,25 +19,29 @@ func (t *RenameKeyTransformer) Type() string {
 
 // Transform implements the rename key transformation logic.
 // It moves a value from the 'From' path to the 'To' path and removes the original.
-func (t *RenameKeyTransformer) Transform(config map[string]interface{}, def Definition) error {
+func (t *RenameKeyTransformer) Transform(config map[string]interface{}, def Definition, paths util.Paths) error {
 	logger.Debug("  - Applying renameKey transform from '%s' to '%s'", def.From, def.To)
 
 	// Get the value from the source path
-	value, found := util.GetNestedValue(config, def.From)
+	value, found := paths.Get(config, def.From)
 	if !found {
 		return fmt.Errorf("renameKey: source path '%s' not found", def.From)
 	}
 
 	// Check if destination already has a value
-	if _, exists := util.GetNestedValue(config, def.To); exists {
+	if _, exists := paths.Get(config, def.To); exists {
 		logger.Warn("renameKey: destination path '%s' already exists, value will be overwritten", def.To)
 	}
 
 	// Set the value at the destination path
-	util.SetNestedValue(config, def.To, value)
+	if err := paths.Set(config, def.To, value); err != nil {
+		return fmt.Errorf("renameKey: %w", err)
+	}
 
 	// Remove the value from the source path
-	util.DeleteNestedValue(config, def.From)
+	if err := paths.Delete(config, def.From); err != nil {
+		return fmt.Errorf("renameKey: %w", err)
+	}
 
 	logger.Debug("    Renamed key from '%s' to '%s'", def.From, def.To)
 	return nil
@@ -19,25 +19,29 @@ func (t *ReplaceKeyTransformer) Type() string {
 
 // Transform implements the replace key transformation logic.
 // It takes the value from the target path and replaces the value at the main path, then deletes the target.
-func (t *ReplaceKeyTransformer) Transform(config map[string]interface{}, def Definition) error {
+func (t *ReplaceKeyTransformer) Transform(config map[string]interface{}, def Definition, paths util.Paths) error {
 	logger.Debug("  - Applying replaceKey transform at path '%s' with target '%s'", def.Path, def.Target)
 
 	// Get the value from the target path
-	targetValue, found := util.GetNestedValue(config, def.Target)
+	targetValue, found := paths.Get(config, def.Target)
 	if !found {
 		return fmt.Errorf("replaceKey: target path '%s' not found", def.Target)
 	}
 
 	// Check if destination path exists — warn if not, as this creates a new key
-	if _, exists := util.GetNestedValue(config, def.Path); !exists {
+	if _, exists := paths.Get(config, def.Path); !exists {
 		logger.Warn("replaceKey: destination path '%s' does not exist, creating new key", def.Path)
 	}
 
 	// Set the target value at the main path
-	util.SetNestedValue(config, def.Path, targetValue)
+	if err := paths.Set(config, def.Path, targetValue); err != nil {
+		return fmt.Errorf("replaceKey: %w", err)
+	}
 
 	// Delete the target path
-	util.DeleteNestedValue(config, def.Target)
+	if err := paths.Delete(config, def.Target); err != nil {
+		return fmt.Errorf("replaceKey: %w", err)
+	}
 
 	logger.Debug("    Replaced value at '%s' with value from '%s' and deleted target", def.Path, def.Target)
 	return nil
@@ -53,6 +57,10 @@ func (t *ReplaceKeyTransformer) ValidateDefinition(def Definition) error {
 		return fmt.Errorf("replaceKey transformer: 'target' is required")
 	}
 
+	if util.IsPattern(def.Path) || util.IsPattern(def.Target) {
+		return fmt.Errorf("replaceKey transformer: 'path' and 'target' cannot be patterns")
+	}
+
 	if def.Path == def.Target {
 		return fmt.Errorf("replaceKey transformer: 'path' and 'target' cannot be the same")
 	}
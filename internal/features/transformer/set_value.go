@@ -19,11 +19,13 @@ func (t *SetValueTransformer) Type() string {
 
 // Transform implements the set value transformation logic.
 // It sets a specific value at the specified path, creating the path if necessary.
-func (t *SetValueTransformer) Transform(config map[string]interface{}, def Definition) error {
+func (t *SetValueTransformer) Transform(config map[string]interface{}, def Definition, paths util.Paths) error {
 	logger.Debug("  - Applying setValue transform at path '%s'", def.Path)
 
 	// Set the value at the specified path
-	util.SetNestedValue(config, def.Path, def.Value)
+	if err := paths.Set(config, def.Path, def.Value); err != nil {
+		return fmt.Errorf("setValue: %w", err)
+	}
 
 	logger.Debug("    Set value '%v' at path '%s'", def.Value, def.Path)
 	return nil
@@ -20,11 +20,11 @@ func (t *TrimTransformer) Type() string {
 
 // Transform implements the trim transformation logic.
 // It trims the specified pattern or whitespace from a string value.
-func (t *TrimTransformer) Transform(config map[string]interface{}, def Definition) error {
+func (t *TrimTransformer) Transform(config map[string]interface{}, def Definition, paths util.Paths) error {
 	logger.Debug("  - Applying trim transform at path '%s'", def.Path)
 
 	// Get the value from the specified path
-	value, found := util.GetNestedValue(config, def.Path)
+	value, found := paths.Get(config, def.Path)
 	if !found {
 		return fmt.Errorf("trim: path '%s' not found", def.Path)
 	}
@@ -53,7 +53,9 @@ func (t *TrimTransformer) Transform(config map[string]interface{}, def Definitio
 	}
 
 	// Set the trimmed value
-	util.SetNestedValue(config, def.Path, newValue)
+	if err := paths.Set(config, def.Path, newValue); err != nil {
+		return fmt.Errorf("trim: %w", err)
+	}
 
 	logger.Debug("    Trimmed value from '%s' to '%s'", strValue, newValue)
 	return nil
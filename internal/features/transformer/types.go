@@ -2,6 +2,12 @@
 // It supports various types of transformations including key renaming, case changes, and value setting.
 package transformer
 
+import (
+	"fmt"
+	"konfigo/internal/features/condition"
+	"konfigo/internal/util"
+)
+
 // Definition represents a transformation configuration.
 type Definition struct {
 	Type    string      `yaml:"type" json:"type"`
@@ -14,12 +20,14 @@ type Definition struct {
 	Pattern string      `yaml:"pattern" json:"pattern"`
 	Target  string      `yaml:"target" json:"target"`
 	Value   interface{} `yaml:"value" json:"value"`
+	// When skips the transform unless the condition holds.
+	When *condition.Condition `yaml:"when,omitempty" json:"when,omitempty"`
 }
 
 // Transformer represents a function that can transform configuration values.
 type Transformer interface {
 	// Transform applies the transformation logic to the configuration.
-	Transform(config map[string]interface{}, def Definition) error
+	Transform(config map[string]interface{}, def Definition, paths util.Paths) error
 
 	// Type returns the transformer type name.
 	Type() string
@@ -31,6 +39,26 @@ type VariableResolver interface {
 	SubstituteString(input string) string
 }
 
+// TypedVariableResolver is implemented by resolvers that can substitute a
+// whole-value placeholder with the variable's native type.
+type TypedVariableResolver interface {
+	// SubstituteValue performs variable substitution, returning a typed value
+	// when the input consists of exactly one placeholder.
+	SubstituteValue(input string) interface{}
+}
+
+// CheckedVariableResolver is implemented by resolvers that evaluate
+// ${{ expression }} placeholders and report expression errors.
+type CheckedVariableResolver interface {
+	// SubstituteStringChecked performs variable substitution on a string and
+	// returns an error when an expression fails.
+	SubstituteStringChecked(input string) (string, error)
+
+	// SubstituteValueChecked behaves like SubstituteStringChecked, returning
+	// a typed value when the input consists of exactly one placeholder.
+	SubstituteValueChecked(input string) (interface{}, error)
+}
+
 // Registry holds registered transformers by type.
 type Registry map[string]Transformer
 
@@ -62,6 +90,12 @@ func (r Registry) Get(transformerType string) (Transformer, bool) {
 	return transformer, exists
 }
 
+// unsupportedType returns the error for a transformer type that is not
+// registered, suggesting the registered type it is closest to.
+func (r Registry) unsupportedType(name string) error {
+	return fmt.Errorf("unsupported transformer type: %s%s", name, util.DidYouMean(name, r.GetTypes()))
+}
+
 // GetTypes returns all registered transformer types.
 func (r Registry) GetTypes() []string {
 	types := make([]string, 0, len(r))
@@ -0,0 +1,42 @@
+package validator
+
+import (
+	"fmt"
+	"konfigo/internal/util"
+	"regexp"
+)
+
+// knownTypes holds the type names a rule may check for, after normalizeTypeName.
+var knownTypes = map[string]bool{
+	"bool": true, "int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"float32": true, "float64": true, "number": true, "string": true, "slice": true, "map": true,
+}
+
+// ValidateDefinition checks a validation group for mistakes that would make
+// it fail or never match, without a configuration to validate.
+func ValidateDefinition(group Group) error {
+	if group.Path == "" {
+		return fmt.Errorf("'path' is required")
+	}
+	if _, err := util.ParsePath(group.Path); err != nil {
+		return fmt.Errorf("path '%s': %w", group.Path, err)
+	}
+
+	rule := group.Rules
+	if rule.Type != "" && !knownTypes[normalizeTypeName(rule.Type)] {
+		return fmt.Errorf("path '%s': unknown type '%s'", group.Path, rule.Type)
+	}
+	if rule.Min != nil && rule.Max != nil && *rule.Min > *rule.Max {
+		return fmt.Errorf("path '%s': min %v is greater than max %v", group.Path, *rule.Min, *rule.Max)
+	}
+	if rule.MinLength != nil && *rule.MinLength < 0 {
+		return fmt.Errorf("path '%s': minLength must not be negative", group.Path)
+	}
+	if rule.Regex != "" {
+		if _, err := regexp.Compile(rule.Regex); err != nil {
+			return fmt.Errorf("path '%s': invalid regex: %w", group.Path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateDefinition(t *testing.T) {
+	min, max := 10.0, 1.0
+	negative := -1
+	tests := []struct {
+		name    string
+		group   Group
+		wantErr string
+	}{
+		{"valid", Group{Path: "services.*.port", Rules: Rule{Type: "integer", Regex: "^[0-9]+$"}}, ""},
+		{"missing path", Group{Rules: Rule{Required: true}}, "'path' is required"},
+		{"unknown type", Group{Path: "port", Rules: Rule{Type: "text"}}, "unknown type 'text'"},
+		{"min above max", Group{Path: "port", Rules: Rule{Min: &min, Max: &max}}, "greater than max"},
+		{"negative minLength", Group{Path: "name", Rules: Rule{MinLength: &negative}}, "minLength"},
+		{"bad regex", Group{Path: "name", Rules: Rule{Regex: "("}}, "invalid regex"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDefinition(tt.group)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"konfigo/internal/util"
 )
 
-// Apply applies all validation groups to the configuration.
-func Apply(config map[string]interface{}, groups []Group) error {
+// Apply applies all validation groups to the configuration. A group path may
+// be a pattern such as "services.*.port"; its rules then apply to every
+// matching path, and errors name the concrete path. Paths are looked up with
+// paths.
+func Apply(config map[string]interface{}, groups []Group, paths util.Paths) error {
 	if len(groups) == 0 {
 		return nil
 	}
@@ -17,7 +20,21 @@ func Apply(config map[string]interface{}, groups []Group) error {
 	registry := NewRegistry()
 
 	for _, group := range groups {
-		val, found := util.GetNestedValue(config, group.Path)
+		if util.IsPattern(group.Path) {
+			matches := paths.Expand(config, group.Path)
+			if group.Rules.Required && len(matches) == 0 {
+				return fmt.Errorf("path pattern '%s' is required but matches no path", group.Path)
+			}
+			for _, path := range matches {
+				val, _ := paths.Get(config, path)
+				if err := validate(registry, val, path, group.Rules); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		val, found := paths.Get(config, group.Path)
 
 		// Check required first
 		if group.Rules.Required && !found {
@@ -29,15 +46,21 @@ func Apply(config map[string]interface{}, groups []Group) error {
 			continue
 		}
 
-		logger.Debug("  - Validating path '%s'", group.Path)
-
-		// Apply each validation rule
-		for _, validator := range registry.GetValidators() {
-			if err := validator.Validate(val, group.Path, group.Rules); err != nil {
-				return err
-			}
+		if err := validate(registry, val, group.Path, group.Rules); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
+
+// validate applies every registered validator to the value at path.
+func validate(registry *Registry, val interface{}, path string, rules Rule) error {
+	logger.Debug("  - Validating path '%s'", path)
+	for _, validator := range registry.GetValidators() {
+		if err := validator.Validate(val, path, rules); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package validator
+
+import (
+	"konfigo/internal/util"
+	"strings"
+	"testing"
+)
+
+func TestApply_PathPattern(t *testing.T) {
+	min := 1024.0
+	config := map[string]interface{}{
+		"services": map[string]interface{}{
+			"api": map[string]interface{}{"port": 8080},
+			"web": map[string]interface{}{"port": 80},
+		},
+	}
+	groups := []Group{{Path: "services.*.port", Rules: Rule{Type: "number", Min: &min}}}
+
+	err := Apply(config, groups, util.Paths{})
+	if err == nil {
+		t.Fatal("expected a validation error for services.web.port")
+	}
+	if !strings.Contains(err.Error(), "services.web.port") {
+		t.Errorf("error should name the concrete path, got: %v", err)
+	}
+
+	required := []Group{{Path: "**.password", Rules: Rule{Required: true}}}
+	if err := Apply(config, required, util.Paths{}); err == nil {
+		t.Error("expected an error for a required pattern that matches nothing")
+	}
+}
@@ -1,9 +1,13 @@
 package validator
 
+import "konfigo/internal/features/condition"
+
 // Group represents a validation group with a path and rules.
 type Group struct {
 	Path  string `yaml:"path" json:"path"`
 	Rules Rule   `yaml:"rules" json:"rules"`
+	// When skips the group unless the condition holds.
+	When *condition.Condition `yaml:"when,omitempty" json:"when,omitempty"`
 }
 
 // Rule represents validation rules that can be applied to a value.
@@ -0,0 +1,207 @@
+package variables
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SourceKind identifies where a variable's value came from.
+type SourceKind string
+
+const (
+	// SourceEnv is a KONFIGO_VAR_ environment variable.
+	SourceEnv SourceKind = "env"
+	// SourceVarsFile is a -V vars file.
+	SourceVarsFile SourceKind = "vars-file"
+	// SourceSchemaValue is the value field of a schema definition.
+	SourceSchemaValue SourceKind = "schema"
+	// SourceSchemaEnv is the environment variable named by fromEnv.
+	SourceSchemaEnv SourceKind = "fromEnv"
+	// SourceSchemaPath is the config path named by fromPath.
+	SourceSchemaPath SourceKind = "fromPath"
+	// SourceSchemaDefault is the defaultValue of a schema definition.
+	SourceSchemaDefault SourceKind = "default"
+)
+
+// Source describes where a variable's value came from. Detail names the
+// environment variable, config path or file when there is one.
+type Source struct {
+	Kind   SourceKind `json:"kind"`
+	Detail string     `json:"detail,omitempty"`
+}
+
+// String returns the source as "kind" or "kind detail".
+func (s Source) String() string {
+	if s.Detail == "" {
+		return string(s.Kind)
+	}
+	return string(s.Kind) + " " + s.Detail
+}
+
+// Info describes a resolved variable.
+type Info struct {
+	Name        string      `json:"name"`
+	Value       interface{} `json:"value"`
+	Source      Source      `json:"source"`
+	Description string      `json:"description,omitempty"`
+}
+
+// Variables returns every resolved variable, sorted by name.
+func (r *DefaultResolver) Variables() []Info {
+	descriptions := make(map[string]string, len(r.definitions))
+	for _, def := range r.definitions {
+		descriptions[def.Name] = def.Description
+	}
+
+	infos := make([]Info, 0, len(r.vars))
+	for name := range r.vars {
+		infos = append(infos, Info{
+			Name:        name,
+			Value:       r.value(name),
+			Source:      r.sources[name],
+			Description: descriptions[name],
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+// value returns the native value of a variable without inferring types.
+func (r *DefaultResolver) value(name string) interface{} {
+	if v, ok := r.typed[name]; ok {
+		return v
+	}
+	return r.vars[name]
+}
+
+// Validate checks every resolved variable against the type, required, enum
+// and pattern constraints of its schema definition. All violations are
+// reported together.
+func (r *DefaultResolver) Validate() error {
+	var violations []string
+	for _, def := range r.definitions {
+		if err := r.validateDefinition(def); err != nil {
+			violations = append(violations, err.Error())
+		}
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d variable(s) failed validation: %s", len(violations), strings.Join(violations, "; "))
+}
+
+// validateDefinition checks a single variable against its definition.
+func (r *DefaultResolver) validateDefinition(def Definition) error {
+	str, ok := r.vars[def.Name]
+	if !ok {
+		return nil
+	}
+	source := r.sources[def.Name]
+
+	if def.Required && str == "" {
+		return fmt.Errorf("variable '%s' is required but is empty (from %s)", def.Name, source)
+	}
+	if def.Type != "" {
+		if err := checkType(def.Type, r.value(def.Name), str); err != nil {
+			return fmt.Errorf("variable '%s' (from %s): %w", def.Name, source, err)
+		}
+	}
+	if len(def.Enum) > 0 && !inEnum(str, def.Enum) {
+		allowed := make([]string, len(def.Enum))
+		for i, e := range def.Enum {
+			allowed[i] = fmt.Sprintf("%v", e)
+		}
+		return fmt.Errorf("variable '%s' (from %s): value '%s' is not one of [%s]", def.Name, source, str, strings.Join(allowed, ", "))
+	}
+	if def.Pattern != "" {
+		re, err := regexp.Compile(def.Pattern)
+		if err != nil {
+			return fmt.Errorf("variable '%s': invalid pattern '%s': %w", def.Name, def.Pattern, err)
+		}
+		if !re.MatchString(str) {
+			return fmt.Errorf("variable '%s' (from %s): value '%s' does not match pattern '%s'", def.Name, source, str, def.Pattern)
+		}
+	}
+	return nil
+}
+
+// checkType reports whether a variable's value has the declared type. Values
+// from strings (environment variables, schema values) only need to parse as
+// the type; lists and maps must come from a vars file or fromPath.
+func checkType(typeName string, value interface{}, str string) error {
+	var ok bool
+	switch typeName {
+	case "string":
+		return nil
+	case "int":
+		switch v := value.(type) {
+		case int, int64, uint64:
+			ok = true
+		case float64: // JSON numbers
+			ok = v == math.Trunc(v)
+		case string:
+			_, err := strconv.ParseInt(str, 10, 64)
+			ok = err == nil
+		}
+	case "float":
+		switch value.(type) {
+		case int, int64, uint64, float64:
+			ok = true
+		case string:
+			_, err := strconv.ParseFloat(str, 64)
+			ok = err == nil
+		}
+	case "bool":
+		switch value.(type) {
+		case bool:
+			ok = true
+		case string:
+			_, err := strconv.ParseBool(str)
+			ok = err == nil
+		}
+	case "list":
+		_, ok = value.([]interface{})
+	case "map":
+		_, ok = value.(map[string]interface{})
+	default:
+		return fmt.Errorf("unknown type '%s' (expected string, int, float, bool, list or map)", typeName)
+	}
+	if !ok {
+		return fmt.Errorf("value '%s' is not of type %s", str, typeName)
+	}
+	return nil
+}
+
+// inEnum reports whether str equals the string form of one of the allowed values.
+func inEnum(str string, allowed []interface{}) bool {
+	for _, a := range allowed {
+		if fmt.Sprintf("%v", a) == str {
+			return true
+		}
+	}
+	return false
+}
+
+// definitionTypes holds the types a variable definition may declare.
+var definitionTypes = map[string]bool{"string": true, "int": true, "float": true, "bool": true, "list": true, "map": true}
+
+// ValidateDefinition checks a variable definition for mistakes that do not
+// depend on its value: a missing name, an unknown type or an invalid pattern.
+func ValidateDefinition(def Definition) error {
+	if def.Name == "" {
+		return fmt.Errorf("'name' is required")
+	}
+	if def.Type != "" && !definitionTypes[def.Type] {
+		return fmt.Errorf("variable '%s': unknown type '%s' (expected string, int, float, bool, list or map)", def.Name, def.Type)
+	}
+	if def.Pattern != "" {
+		if _, err := regexp.Compile(def.Pattern); err != nil {
+			return fmt.Errorf("variable '%s': invalid pattern '%s': %w", def.Name, def.Pattern, err)
+		}
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package variables
 
 import (
 	"fmt"
+	"konfigo/internal/features/expression"
 	"konfigo/internal/logger"
 	"konfigo/internal/util"
 	"os"
@@ -11,17 +12,31 @@ import (
 // DefaultResolver is the default implementation of the Resolver interface.
 type DefaultResolver struct {
 	vars map[string]string
+	// typed holds the native value of variables from vars files or fromPath,
+	// strings included. Other variables arrive untyped and are inferred.
+	typed map[string]interface{}
+	// references resolves ${.path} self-references. It defaults to a lookup in
+	// the configuration the resolver was created with.
+	references ReferenceLookup
+	// sources records where each variable's value came from.
+	sources map[string]Source
+	// definitions holds the schema definitions, in declaration order.
+	definitions []Definition
 }
 
 // NewResolver creates a new variable resolver, processing sources in the correct order of precedence.
-func NewResolver(envVars map[string]string, varsFromFile map[string]interface{}, schemaVars []Definition, config map[string]interface{}) (*DefaultResolver, error) {
+// fromPath variables and ${.path} references are looked up in config with paths.
+func NewResolver(envVars map[string]string, varsFromFile map[string]interface{}, schemaVars []Definition, config map[string]interface{}, paths util.Paths) (*DefaultResolver, error) {
 	resolved := make(map[string]string)
+	typed := make(map[string]interface{})
+	sources := make(map[string]Source)
 
 	// 1. Highest precedence: Variables from KONFIGO_VAR_ environment variables.
 	if envVars != nil {
 		logger.Debug("Loading variables from environment (KONFIGO_VAR_...) (highest priority)")
 		for k, v := range envVars {
 			resolved[k] = v
+			sources[k] = Source{Kind: SourceEnv, Detail: "KONFIGO_VAR_" + k}
 		}
 	}
 
@@ -34,6 +49,10 @@ func NewResolver(envVars map[string]string, varsFromFile map[string]interface{},
 				continue
 			}
 			resolved[k] = fmt.Sprintf("%v", v)
+			sources[k] = Source{Kind: SourceVarsFile}
+			if v != nil {
+				typed[k] = v
+			}
 		}
 	}
 
@@ -52,40 +71,367 @@ func NewResolver(envVars map[string]string, varsFromFile map[string]interface{},
 
 		var val string
 		var found bool
+		var source Source
 
 		if varDef.FromEnv != "" {
 			val, found = os.LookupEnv(varDef.FromEnv)
+			source = Source{Kind: SourceSchemaEnv, Detail: varDef.FromEnv}
 		} else if varDef.FromPath != "" {
-			if v, ok := util.GetNestedValue(config, varDef.FromPath); ok {
+			if v, ok := paths.Get(config, varDef.FromPath); ok {
 				val = fmt.Sprintf("%v", v)
 				found = true
+				if v != nil {
+					typed[varDef.Name] = v
+				}
 			}
+			source = Source{Kind: SourceSchemaPath, Detail: varDef.FromPath}
 		} else if varDef.Value != "" {
 			val = varDef.Value
 			found = true
+			source = Source{Kind: SourceSchemaValue}
 		}
 
 		if !found {
 			if varDef.DefaultValue != "" {
 				val = varDef.DefaultValue
+				source = Source{Kind: SourceSchemaDefault}
+			} else if varDef.Required {
+				return nil, fmt.Errorf("variable '%s' is required but no value was provided", varDef.Name)
 			} else {
 				return nil, fmt.Errorf("variable '%s' could not be resolved and has no default value", varDef.Name)
 			}
 		}
 		resolved[varDef.Name] = val
+		sources[varDef.Name] = source
 	}
 
-	return &DefaultResolver{vars: resolved}, nil
+	return &DefaultResolver{
+		vars:  resolved,
+		typed: typed,
+		references: func(path string) (interface{}, bool) {
+			return paths.Get(config, path)
+		},
+		sources:     sources,
+		definitions: schemaVars,
+	}, nil
+}
+
+// WithReferenceLookup returns a copy of the resolver that resolves ${.path}
+// self-references through lookup.
+func (r *DefaultResolver) WithReferenceLookup(lookup ReferenceLookup) Resolver {
+	clone := *r
+	clone.references = lookup
+	return &clone
+}
+
+// lookup returns the string form of a variable or ${.path} reference.
+func (r *DefaultResolver) lookup(name string) (string, bool) {
+	if isReference(name) {
+		val, ok := r.lookupReference(name)
+		if !ok {
+			return "", false
+		}
+		if val == nil {
+			return "", true
+		}
+		return fmt.Sprintf("%v", val), true
+	}
+	val, ok := r.vars[name]
+	return val, ok
+}
+
+// lookupReference resolves a ${.path} reference against the configuration.
+func (r *DefaultResolver) lookupReference(name string) (interface{}, bool) {
+	if r.references == nil {
+		return nil, false
+	}
+	return r.references(strings.TrimPrefix(name, "."))
 }
 
 // SubstituteString performs ${VAR} replacement on a single string.
+// Unresolved placeholders and escaped $${VAR} sequences are left as-is so that
+// a later substitution pass over the final configuration can handle them.
 func (r *DefaultResolver) SubstituteString(input string) string {
-	return VarRegex.ReplaceAllStringFunc(input, func(match string) string {
-		varName := strings.TrimSuffix(strings.TrimPrefix(match, "${"), "}")
-		if val, ok := r.vars[varName]; ok {
+	out, _, _ := r.expand(input, false)
+	return out
+}
+
+// SubstituteStringChecked behaves like SubstituteString, but returns an error
+// when a ${{ expression }} placeholder fails to compile or evaluate.
+func (r *DefaultResolver) SubstituteStringChecked(input string) (string, error) {
+	out, _, err := r.expand(input, false)
+	return out, err
+}
+
+// SubstituteValue behaves like SubstituteString, but a string that consists of
+// exactly one placeholder is replaced by the variable's native value.
+func (r *DefaultResolver) SubstituteValue(input string) interface{} {
+	if val, ok, _ := r.expandWhole(input); ok {
+		return val
+	}
+	return r.SubstituteString(input)
+}
+
+// SubstituteValueChecked behaves like SubstituteValue, but returns an error
+// when a ${{ expression }} placeholder fails to compile or evaluate.
+func (r *DefaultResolver) SubstituteValueChecked(input string) (interface{}, error) {
+	val, ok, unresolved := r.expandWhole(input)
+	if ok {
+		return val, nil
+	}
+	if err := expressionError(unresolved); err != nil {
+		return nil, err
+	}
+	return r.SubstituteStringChecked(input)
+}
+
+// EvaluateExpression compiles and evaluates a single expression (without the
+// surrounding ${{ }}) against the resolver's variables and configuration.
+func (r *DefaultResolver) EvaluateExpression(source string) (interface{}, error) {
+	val, err := expression.Evaluate(source, resolverEnv{r})
+	if err != nil {
+		return nil, err
+	}
+	return expression.Result(val), nil
+}
+
+// Expand performs ${VAR} replacement on a single string, renders escaped
+// $${VAR} sequences literally and reports every placeholder it could not resolve.
+func (r *DefaultResolver) Expand(input string) (string, []Unresolved) {
+	out, unresolved, _ := r.expand(input, true)
+	return out, unresolved
+}
+
+// ExpandValue behaves like Expand, but a string that consists of exactly one
+// placeholder is replaced by the variable's native value: values from vars
+// files and fromPath keep their type, and the types of environment and schema
+// values are inferred with util.InferType.
+// Appending |string to the placeholder (${PORT|string}) forces a string result.
+func (r *DefaultResolver) ExpandValue(input string) (interface{}, []Unresolved) {
+	if val, ok, unresolved := r.expandWhole(input); ok || len(unresolved) > 0 {
+		return val, unresolved
+	}
+	return r.Expand(input)
+}
+
+// expandWhole resolves input when it is a single, unescaped placeholder that
+// has not opted out of typing. It reports ok=false when input is anything else
+// or when the placeholder could not be resolved.
+func (r *DefaultResolver) expandWhole(input string) (interface{}, bool, []Unresolved) {
+	if loc := ExprRegex.FindStringSubmatchIndex(input); loc != nil && loc[0] == 0 && loc[1] == len(input) {
+		if loc[3] > loc[2] {
+			return nil, false, nil
+		}
+		val, err := r.EvaluateExpression(input[loc[4]:loc[5]])
+		if err != nil {
+			return input, false, []Unresolved{expressionUnresolved(input, err)}
+		}
+		return val, true, nil
+	}
+
+	loc := VarRegex.FindStringSubmatchIndex(input)
+	if loc == nil || loc[0] != 0 || loc[1] != len(input) {
+		return nil, false, nil
+	}
+	sub := VarRegex.FindStringSubmatch(input)
+	escape, name, op, operand, forceString := sub[1], sub[2], sub[3], sub[4], sub[5]
+	if escape != "" || forceString != "" {
+		return nil, false, nil
+	}
+
+	val, ok := r.lookup(name)
+	isSet := ok && val != ""
+
+	switch op {
+	case ":-":
+		if isSet {
+			return r.typedValue(name), true, nil
+		}
+		return util.InferType(operand), true, nil
+	case ":+":
+		if isSet {
+			return util.InferType(operand), true, nil
+		}
+		return "", true, nil
+	case ":?":
+		if isSet {
+			return r.typedValue(name), true, nil
+		}
+		return input, false, []Unresolved{requiredUnresolved(input, name, operand)}
+	}
+
+	if ok {
+		return r.typedValue(name), true, nil
+	}
+	return nil, false, nil
+}
+
+// typedValue returns the native value of a resolved variable or reference.
+// Maps and lists are copied so that each substitution gets an independent value.
+func (r *DefaultResolver) typedValue(name string) interface{} {
+	if isReference(name) {
+		v, _ := r.lookupReference(name)
+		if s, ok := v.(string); ok {
+			return s
+		}
+		if copied, err := util.DeepCopyValue(v); err == nil {
+			return copied
+		}
+		return v
+	}
+	if v, ok := r.typed[name]; ok {
+		if copied, err := util.DeepCopyValue(v); err == nil {
+			return copied
+		}
+		return v
+	}
+	return util.InferType(r.vars[name])
+}
+
+// expand implements placeholder expansion. ${{ expression }} placeholders are
+// evaluated first and the text between them is expanded with expandVars, so
+// the result of an expression is never expanded again. When unescape is
+// false, escaped placeholders are preserved verbatim. Failed expressions are
+// left in place, reported as required and returned as an error.
+func (r *DefaultResolver) expand(input string, unescape bool) (string, []Unresolved, error) {
+	if !strings.Contains(input, "${") {
+		return input, nil, nil
+	}
+
+	locs := ExprRegex.FindAllStringSubmatchIndex(input, -1)
+	if locs == nil {
+		out, unresolved := r.expandVars(input, unescape)
+		return out, unresolved, nil
+	}
+
+	var b strings.Builder
+	var unresolved []Unresolved
+	last := 0
+	for _, loc := range locs {
+		out, missing := r.expandVars(input[last:loc[0]], unescape)
+		b.WriteString(out)
+		unresolved = append(unresolved, missing...)
+		last = loc[1]
+
+		match := input[loc[0]:loc[1]]
+		if loc[3] > loc[2] {
+			if unescape {
+				match = match[1:]
+			}
+			b.WriteString(match)
+			continue
+		}
+
+		val, err := r.EvaluateExpression(input[loc[4]:loc[5]])
+		if err != nil {
+			unresolved = append(unresolved, expressionUnresolved(match, err))
+			b.WriteString(match)
+			continue
+		}
+		b.WriteString(expression.Format(val))
+	}
+	out, missing := r.expandVars(input[last:], unescape)
+	b.WriteString(out)
+	unresolved = append(unresolved, missing...)
+
+	return b.String(), unresolved, expressionError(unresolved)
+}
+
+// expandVars expands ${VAR} and ${.path} placeholders in input.
+func (r *DefaultResolver) expandVars(input string, unescape bool) (string, []Unresolved) {
+	if !strings.Contains(input, "${") {
+		return input, nil
+	}
+
+	var unresolved []Unresolved
+	out := VarRegex.ReplaceAllStringFunc(input, func(match string) string {
+		sub := VarRegex.FindStringSubmatch(match)
+		escape, name, op, operand := sub[1], sub[2], sub[3], sub[4]
+
+		if escape != "" {
+			if unescape {
+				return match[1:]
+			}
+			return match
+		}
+
+		val, ok := r.lookup(name)
+		isSet := ok && val != ""
+
+		switch op {
+		case ":-":
+			if isSet {
+				return val
+			}
+			return operand
+		case ":+":
+			if isSet {
+				return operand
+			}
+			return ""
+		case ":?":
+			if isSet {
+				return val
+			}
+			unresolved = append(unresolved, requiredUnresolved(match, name, operand))
+			return match
+		}
+
+		if ok {
 			return val
 		}
 		// Leave unresolved variables as-is
+		unresolved = append(unresolved, Unresolved{Placeholder: match})
 		return match
 	})
+
+	return out, unresolved
+}
+
+// requiredUnresolved builds the report entry for an unsatisfied ${VAR:?message} marker.
+func requiredUnresolved(placeholder, name, message string) Unresolved {
+	if message == "" {
+		message = fmt.Sprintf("variable '%s' is required", name)
+	}
+	return Unresolved{Placeholder: placeholder, Message: message, Required: true}
+}
+
+// expressionUnresolved builds the report entry for a failed ${{ expression }}.
+func expressionUnresolved(placeholder string, err error) Unresolved {
+	return Unresolved{Placeholder: placeholder, Message: err.Error(), Required: true, err: err}
+}
+
+// expressionError returns the first expression error among unresolved entries.
+func expressionError(unresolved []Unresolved) error {
+	for _, u := range unresolved {
+		if u.err != nil {
+			return fmt.Errorf("%s: %w", u.Placeholder, u.err)
+		}
+	}
+	return nil
+}
+
+// ExpressionEnv returns the resolver's variables and config references as an
+// expression environment, as seen by ${{ }} placeholders.
+func (r *DefaultResolver) ExpressionEnv() expression.Env {
+	return resolverEnv{r}
+}
+
+// resolverEnv exposes a resolver's variables and config references to expressions.
+// Variables keep their native type when they came from a vars file or fromPath;
+// all other variables are strings.
+type resolverEnv struct {
+	r *DefaultResolver
+}
+
+func (e resolverEnv) Lookup(name string) (interface{}, bool) {
+	if v, ok := e.r.typed[name]; ok {
+		return v, true
+	}
+	v, ok := e.r.vars[name]
+	return v, ok
+}
+
+func (e resolverEnv) Reference(path string) (interface{}, bool) {
+	return e.r.lookupReference("." + path)
 }
@@ -0,0 +1,220 @@
+package variables
+
+import (
+	"konfigo/internal/util"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestResolver(t *testing.T, vars map[string]string) *DefaultResolver {
+	t.Helper()
+	r, err := NewResolver(vars, nil, nil, nil, util.Paths{})
+	if err != nil {
+		t.Fatalf("NewResolver failed: %v", err)
+	}
+	return r
+}
+
+func TestSubstituteString_ShellOperators(t *testing.T) {
+	r := newTestResolver(t, map[string]string{"HOST": "db.local", "EMPTY": ""})
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"${HOST}", "db.local"},
+		{"${HOST:-fallback}", "db.local"},
+		{"${MISSING:-fallback}", "fallback"},
+		{"${EMPTY:-fallback}", "fallback"},
+		{"${HOST:+set}", "set"},
+		{"${MISSING:+set}", ""},
+		{"${EMPTY:+set}", ""},
+		{"${HOST:?host required}", "db.local"},
+		{"${MISSING}", "${MISSING}"},
+		{"$${HOST}", "$${HOST}"},
+		{"http://${HOST}:${PORT:-5432}/", "http://db.local:5432/"},
+	}
+	for _, tt := range tests {
+		if got := r.SubstituteString(tt.input); got != tt.want {
+			t.Errorf("SubstituteString(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestExpand_UnescapesAndReportsUnresolved(t *testing.T) {
+	r := newTestResolver(t, map[string]string{"HOST": "db.local"})
+
+	got, unresolved := r.Expand("$${HOST} ${HOST} ${MISSING} ${TOKEN:?token is required}")
+	if want := "${HOST} db.local ${MISSING} ${TOKEN:?token is required}"; got != want {
+		t.Errorf("Expand output = %q, want %q", got, want)
+	}
+	if len(unresolved) != 2 {
+		t.Fatalf("expected 2 unresolved placeholders, got %d: %v", len(unresolved), unresolved)
+	}
+	if unresolved[0].Placeholder != "${MISSING}" || unresolved[0].Required {
+		t.Errorf("unexpected first unresolved entry: %+v", unresolved[0])
+	}
+	if !unresolved[1].Required || unresolved[1].Message != "token is required" {
+		t.Errorf("unexpected required entry: %+v", unresolved[1])
+	}
+}
+
+func TestSubstitute_RequiredMarkerFails(t *testing.T) {
+	r := newTestResolver(t, nil)
+	config := map[string]interface{}{
+		"api": map[string]interface{}{"key": "${API_KEY:?set KONFIGO_VAR_API_KEY}"},
+	}
+	_, err := Substitute(config, r, false, util.Paths{})
+	if err == nil {
+		t.Fatal("expected error for unsatisfied required marker, got nil")
+	}
+	if !strings.Contains(err.Error(), "api.key") || !strings.Contains(err.Error(), "set KONFIGO_VAR_API_KEY") {
+		t.Errorf("error should name path and message, got: %v", err)
+	}
+}
+
+func TestSubstitute_StrictListsEveryPlaceholder(t *testing.T) {
+	r := newTestResolver(t, nil)
+	config := map[string]interface{}{
+		"db":    map[string]interface{}{"host": "${DB_HOST}"},
+		"hosts": []interface{}{"${HOST_A}", "static"},
+	}
+
+	if _, err := Substitute(config, r, false, util.Paths{}); err != nil {
+		t.Fatalf("non-strict substitution should not fail, got: %v", err)
+	}
+
+	_, err := Substitute(config, r, true, util.Paths{})
+	if err == nil {
+		t.Fatal("expected strict substitution to fail, got nil")
+	}
+	for _, want := range []string{"db.host: ${DB_HOST}", "hosts[0]: ${HOST_A}"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("strict error should contain %q, got: %v", want, err)
+		}
+	}
+}
+
+func TestSubstitute_WholeValuePlaceholdersKeepNativeType(t *testing.T) {
+	varsFromFile := map[string]interface{}{
+		"ZIP":      "01234",
+		"VERSION":  "1.10",
+		"REPLICAS": 3,
+		"HOSTS":    []interface{}{"a", "b"},
+		"LIMITS":   map[string]interface{}{"cpu": "500m"},
+	}
+	r, err := NewResolver(map[string]string{"PORT": "8080", "DEBUG": "true", "RATIO": "inf"}, varsFromFile, nil, nil, util.Paths{})
+	if err != nil {
+		t.Fatalf("NewResolver failed: %v", err)
+	}
+
+	config := map[string]interface{}{
+		"port":      "${PORT}",
+		"debug":     "${DEBUG}",
+		"replicas":  "${REPLICAS}",
+		"zip":       "${ZIP}",
+		"ratio":     "${RATIO}",
+		"version":   "${VERSION}",
+		"hosts":     "${HOSTS}",
+		"limits":    "${LIMITS}",
+		"timeout":   "${TIMEOUT:-30}",
+		"portStr":   "${PORT|string}",
+		"address":   "localhost:${PORT}",
+		"fallback":  "${MISSING:-fallback|string}",
+		"untouched": "${MISSING}",
+	}
+	got, err := Substitute(config, r, false, util.Paths{})
+	if err != nil {
+		t.Fatalf("Substitute failed: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"port":      8080,
+		"debug":     true,
+		"replicas":  3,
+		"zip":       "01234",
+		"ratio":     "inf",
+		"version":   "1.10",
+		"hosts":     []interface{}{"a", "b"},
+		"limits":    map[string]interface{}{"cpu": "500m"},
+		"timeout":   30,
+		"portStr":   "8080",
+		"address":   "localhost:8080",
+		"fallback":  "fallback",
+		"untouched": "${MISSING}",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Substitute() = %#v, want %#v", got, want)
+	}
+}
+
+func TestSubstituteStringChecked_ExpressionErrors(t *testing.T) {
+	r := newTestResolver(t, map[string]string{"ENV": "prod"})
+
+	got, err := r.SubstituteStringChecked("svc-${{ lower(ENV) }}-${MISSING}")
+	if err != nil {
+		t.Fatalf("SubstituteStringChecked failed: %v", err)
+	}
+	if got != "svc-prod-${MISSING}" {
+		t.Errorf("SubstituteStringChecked = %q", got)
+	}
+
+	if _, err := r.SubstituteStringChecked("${{ ENV * 2 }}"); err == nil || !strings.Contains(err.Error(), "position 6") {
+		t.Errorf("expected positioned expression error, got %v", err)
+	}
+
+	// The unchecked variant leaves a failed expression in place for a later pass.
+	if got := r.SubstituteString("${{ ENV * 2 }}"); got != "${{ ENV * 2 }}" {
+		t.Errorf("SubstituteString = %q", got)
+	}
+}
+
+func TestValidate_ReportsEveryViolation(t *testing.T) {
+	defs := []Definition{
+		{Name: "PORT", Type: "int", DefaultValue: "8080"},
+		{Name: "ENV", Enum: []interface{}{"dev", "prod"}, Value: "dev"},
+		{Name: "REGION", Pattern: `^[a-z]+-[a-z]+-\d$`, Value: "eu-west-1"},
+		{Name: "TAGS", Type: "list", Value: "x"},
+		{Name: "TOKEN", Required: true, Value: "x"},
+	}
+	r, err := NewResolver(map[string]string{"TOKEN": ""}, map[string]interface{}{"ENV": "staging", "TAGS": []interface{}{"a"}}, defs, nil, util.Paths{})
+	if err != nil {
+		t.Fatalf("NewResolver failed: %v", err)
+	}
+	err = r.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"2 variable(s)", "'ENV' (from vars-file): value 'staging' is not one of [dev, prod]", "'TOKEN' is required but is empty (from env KONFIGO_VAR_TOKEN)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+
+	if r.sources["PORT"].Kind != SourceSchemaDefault || r.sources["REGION"].Kind != SourceSchemaValue {
+		t.Errorf("unexpected sources: %v", r.sources)
+	}
+}
+
+func TestValidateDefinition(t *testing.T) {
+	if err := ValidateDefinition(Definition{Name: "PORT", Type: "int", Pattern: `^\d+$`}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for def, want := range map[*Definition]string{
+		{Type: "int"}:                  "'name' is required",
+		{Name: "PORT", Type: "number"}: "unknown type 'number'",
+		{Name: "ENV", Pattern: "("}:    "invalid pattern",
+	} {
+		if err := ValidateDefinition(*def); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ValidateDefinition(%+v) = %v, want error containing %q", *def, err, want)
+		}
+	}
+}
+
+func TestNewResolver_RequiredWithoutValue(t *testing.T) {
+	_, err := NewResolver(nil, nil, []Definition{{Name: "API_KEY", Required: true, FromEnv: "KONFIGO_TEST_UNSET_API_KEY"}}, nil, util.Paths{})
+	if err == nil || !strings.Contains(err.Error(), "variable 'API_KEY' is required") {
+		t.Errorf("expected required error, got %v", err)
+	}
+}
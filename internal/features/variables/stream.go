@@ -0,0 +1,47 @@
+package variables
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// SubstituteStream copies r to w line by line, expanding placeholders in each
+// line with resolver. Everything outside placeholders, including line endings,
+// is copied unchanged and escaped $${VAR} sequences are unescaped.
+//
+// Every placeholder that could not be resolved is returned, with its Path set
+// to name:line. Placeholders cannot span lines.
+func SubstituteStream(r io.Reader, w io.Writer, resolver Resolver, name string) ([]Unresolved, error) {
+	reader := bufio.NewReader(r)
+	writer := bufio.NewWriter(w)
+	var unresolved []Unresolved
+
+	for lineNum := 1; ; lineNum++ {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return unresolved, fmt.Errorf("failed to read %s: %w", name, readErr)
+		}
+
+		if line != "" {
+			out, missing := resolver.Expand(line)
+			for _, u := range missing {
+				u.Path = name + ":" + strconv.Itoa(lineNum)
+				unresolved = append(unresolved, u)
+			}
+			if _, err := writer.WriteString(out); err != nil {
+				return unresolved, fmt.Errorf("failed to write output for %s: %w", name, err)
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if err := writer.Flush(); err != nil {
+		return unresolved, fmt.Errorf("failed to write output for %s: %w", name, err)
+	}
+	return unresolved, nil
+}
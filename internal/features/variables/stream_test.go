@@ -0,0 +1,26 @@
+package variables
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSubstituteStream_PreservesTextAndReportsLines(t *testing.T) {
+	r := newTestResolver(t, map[string]string{"SCHEMA": "app", "OWNER": "admin"})
+	input := "-- seed for ${SCHEMA}\r\nINSERT INTO ${SCHEMA}.users VALUES ('$1', '${OWNER}');\n\nSELECT '$${SCHEMA}', '${MISSING}';"
+
+	var out bytes.Buffer
+	unresolved, err := SubstituteStream(strings.NewReader(input), &out, r, "seed.sql")
+	if err != nil {
+		t.Fatalf("SubstituteStream failed: %v", err)
+	}
+
+	want := "-- seed for app\r\nINSERT INTO app.users VALUES ('$1', 'admin');\n\nSELECT '${SCHEMA}', '${MISSING}';"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if len(unresolved) != 1 || unresolved[0].Path != "seed.sql:4" || unresolved[0].Placeholder != "${MISSING}" {
+		t.Errorf("unexpected unresolved placeholders: %v", unresolved)
+	}
+}
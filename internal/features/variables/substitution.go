@@ -1,23 +1,208 @@
 package variables
 
 import (
+	"fmt"
 	"konfigo/internal/logger"
 	"konfigo/internal/util"
+	"sort"
+	"strings"
 )
 
 // Substitute performs ${VAR} replacement on the entire configuration map.
-func Substitute(config map[string]interface{}, resolver Resolver) map[string]interface{} {
+// Values that consist of a single placeholder keep the variable's native type.
+//
+// ${.path} self-references are resolved against the configuration being
+// substituted, so a reference to a value that itself contains placeholders
+// sees the fully substituted result. Values are resolved in dependency order
+// and a reference cycle fails the substitution, naming the chain of paths.
+//
+// Required markers (${VAR:?message}) that cannot be satisfied always fail the
+// substitution. Other unresolved placeholders are left in place, unless strict
+// is true, in which case every unresolved placeholder is reported together
+// with the config path of the value that contains it.
+//
+// References are looked up with paths.
+func Substitute(config map[string]interface{}, resolver Resolver, strict bool, paths util.Paths) (map[string]interface{}, error) {
 	if config == nil {
-		return nil
+		return nil, nil
 	}
 	logger.Debug("Performing variable substitution...")
-	replacerFunc := func(s string) string {
-		return resolver.SubstituteString(s)
+
+	root, err := util.DeepCopyMap(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy configuration for substitution: %w", err)
+	}
+
+	s := &substitution{
+		root:   root,
+		lookup: paths,
+		leaves: make(map[string]*stringLeaf),
+	}
+	s.resolver = resolver.WithReferenceLookup(s.lookupReference)
+	s.index(root, "")
+	sort.Strings(s.paths)
+
+	for _, path := range s.paths {
+		s.resolve(s.leaves[path])
+	}
+
+	if len(s.cycles) > 0 {
+		return nil, fmt.Errorf("reference cycle detected: %s", strings.Join(s.cycles, "; "))
+	}
+
+	var failures []string
+	for _, u := range s.unresolved {
+		if u.Required || strict {
+			failures = append(failures, u.String())
+		} else {
+			logger.Debug("  - Leaving unresolved placeholder %s", u.String())
+		}
+	}
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return nil, fmt.Errorf("%d unresolved variable placeholder(s): %s", len(failures), strings.Join(failures, "; "))
+	}
+
+	return root, nil
+}
+
+// leafState tracks the resolution progress of a string value.
+type leafState int
+
+const (
+	leafPending leafState = iota
+	leafResolving
+	leafResolved
+)
+
+// stringLeaf is a string value in the configuration that may contain placeholders.
+type stringLeaf struct {
+	path  string
+	raw   string
+	set   func(interface{})
+	state leafState
+}
+
+// substitution holds the state of a single Substitute call.
+type substitution struct {
+	resolver   Resolver
+	root       map[string]interface{}
+	lookup     util.Paths
+	leaves     map[string]*stringLeaf
+	paths      []string
+	stack      []string
+	cycles     []string
+	unresolved []Unresolved
+}
+
+// index records every string value of the configuration together with a setter
+// that replaces it in place.
+func (s *substitution) index(data interface{}, path string) {
+	switch v := data.(type) {
+	case map[string]interface{}:
+		for key, val := range v {
+			childPath := util.AppendKey(path, key)
+			if str, ok := val.(string); ok {
+				m, k := v, key
+				s.addLeaf(childPath, str, func(nv interface{}) { m[k] = nv })
+				continue
+			}
+			s.index(val, childPath)
+		}
+	case []interface{}:
+		for i, val := range v {
+			childPath := util.AppendIndex(path, i)
+			if str, ok := val.(string); ok {
+				sl, idx := v, i
+				s.addLeaf(childPath, str, func(nv interface{}) { sl[idx] = nv })
+				continue
+			}
+			s.index(val, childPath)
+		}
+	}
+}
+
+// addLeaf registers a string value for resolution.
+func (s *substitution) addLeaf(path, raw string, set func(interface{})) {
+	s.leaves[path] = &stringLeaf{path: path, raw: raw, set: set}
+	s.paths = append(s.paths, path)
+}
+
+// resolve substitutes a single string value, resolving the values it
+// references first.
+func (s *substitution) resolve(leaf *stringLeaf) {
+	switch leaf.state {
+	case leafResolved:
+		return
+	case leafResolving:
+		s.recordCycle(leaf.path)
+		return
+	}
+
+	leaf.state = leafResolving
+	s.stack = append(s.stack, leaf.path)
+
+	value, missing := s.resolver.ExpandValue(leaf.raw)
+	for _, u := range missing {
+		u.Path = leaf.path
+		s.unresolved = append(s.unresolved, u)
 	}
+	leaf.set(value)
+
+	s.stack = s.stack[:len(s.stack)-1]
+	leaf.state = leafResolved
+}
+
+// recordCycle records the reference chain that leads back to path. A chain
+// is recorded once, however often the reference is looked up.
+func (s *substitution) recordCycle(path string) {
+	start := 0
+	for i, p := range s.stack {
+		if p == path {
+			start = i
+			break
+		}
+	}
+	chain := strings.Join(append(append([]string{}, s.stack[start:]...), path), " -> ")
+	for _, c := range s.cycles {
+		if c == chain {
+			return
+		}
+	}
+	s.cycles = append(s.cycles, chain)
+}
+
+// lookupReference resolves a ${.path} reference. The string value at or
+// above path that holds it, or every string value below path, is resolved
+// before the value is returned.
+func (s *substitution) lookupReference(path string) (interface{}, bool) {
+	path = s.lookup.Resolve(s.root, path)
+	if leaf := s.holder(path); leaf != nil {
+		s.resolve(leaf)
+		if leaf.state != leafResolved {
+			return nil, false
+		}
+	} else {
+		for _, p := range s.paths {
+			if strings.HasPrefix(p, path+".") || strings.HasPrefix(p, path+"[") {
+				s.resolve(s.leaves[p])
+			}
+		}
+	}
+	return s.lookup.Get(s.root, path)
+}
 
-	result := util.WalkAndReplace(config, replacerFunc)
-	if result == nil {
-		return nil
+// holder returns the string value at path or at its closest ancestor, which
+// may resolve to a map or list that holds path, or nil.
+func (s *substitution) holder(path string) *stringLeaf {
+	for {
+		if leaf, ok := s.leaves[path]; ok {
+			return leaf
+		}
+		parent, ok := util.ParentPath(path)
+		if !ok {
+			return nil
+		}
+		path = parent
 	}
-	return result.(map[string]interface{})
 }
@@ -0,0 +1,168 @@
+package variables
+
+import (
+	"konfigo/internal/util"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSubstitute_SelfReferences(t *testing.T) {
+	r := newTestResolver(t, map[string]string{"ENV": "prod"})
+	config := map[string]interface{}{
+		"database": map[string]interface{}{
+			"host": "db.${ENV}.internal",
+			"port": 5432,
+		},
+		"dsn":     "postgres://${.database.host}:${.database.port}/app",
+		"port":    "${.database.port}",
+		"replica": "${.dsn}?replica=true",
+		"copy":    "${.database}",
+	}
+
+	got, err := Substitute(config, r, true, util.Paths{})
+	if err != nil {
+		t.Fatalf("Substitute failed: %v", err)
+	}
+
+	if got["dsn"] != "postgres://db.prod.internal:5432/app" {
+		t.Errorf("dsn = %v", got["dsn"])
+	}
+	if got["replica"] != "postgres://db.prod.internal:5432/app?replica=true" {
+		t.Errorf("replica = %v", got["replica"])
+	}
+	if got["port"] != 5432 {
+		t.Errorf("port = %#v, want native int", got["port"])
+	}
+	want := map[string]interface{}{"host": "db.prod.internal", "port": 5432}
+	if !reflect.DeepEqual(got["copy"], want) {
+		t.Errorf("copy = %#v, want %#v", got["copy"], want)
+	}
+}
+
+func TestSubstitute_ReferenceKeyCase(t *testing.T) {
+	r := newTestResolver(t, map[string]string{"ENV": "prod"})
+	config := map[string]interface{}{
+		"Database": map[string]interface{}{"Host": "db.${ENV}.internal"},
+		"DSN":      "postgres://${.database.host}/app",
+	}
+
+	got, err := Substitute(config, r, true, util.Paths{})
+	if err != nil {
+		t.Fatalf("Substitute failed: %v", err)
+	}
+	if got["DSN"] != "postgres://db.prod.internal/app" {
+		t.Errorf("DSN = %v, want the substituted host", got["DSN"])
+	}
+
+	_, err = Substitute(config, r, true, util.Paths{CaseSensitive: true})
+	if err == nil || !strings.Contains(err.Error(), "${.database.host}") {
+		t.Errorf("case-sensitive references should not match Database.Host, got %v", err)
+	}
+}
+
+func TestSubstitute_ReferenceCycleNamesChain(t *testing.T) {
+	r := newTestResolver(t, nil)
+	config := map[string]interface{}{
+		"a": "${.b}",
+		"b": "x-${.c}",
+		"c": "${.a}",
+	}
+
+	_, err := Substitute(config, r, false, util.Paths{})
+	if err == nil {
+		t.Fatal("expected cycle error, got nil")
+	}
+	if !strings.Contains(err.Error(), "a -> b -> c -> a") {
+		t.Errorf("error should name the reference chain, got: %v", err)
+	}
+	if strings.Count(err.Error(), "a -> b -> c -> a") != 1 {
+		t.Errorf("the cycle should be reported once, got: %v", err)
+	}
+}
+
+func TestSubstitute_ReferenceIntoSubstitutedValue(t *testing.T) {
+	varsFromFile := map[string]interface{}{"LIMITS": map[string]interface{}{"cpu": "500m"}}
+	r, err := NewResolver(nil, varsFromFile, nil, nil, util.Paths{})
+	if err != nil {
+		t.Fatalf("NewResolver failed: %v", err)
+	}
+	config := map[string]interface{}{
+		"b": "${.z.cpu}",
+		"z": "${LIMITS}",
+	}
+
+	got, err := Substitute(config, r, true, util.Paths{})
+	if err != nil {
+		t.Fatalf("Substitute failed: %v", err)
+	}
+	if got["b"] != "500m" {
+		t.Errorf("b = %#v, want 500m", got["b"])
+	}
+}
+
+func TestSubstitute_MissingReferenceIsUnresolved(t *testing.T) {
+	r := newTestResolver(t, nil)
+	config := map[string]interface{}{"url": "${.missing.host}"}
+
+	_, err := Substitute(config, r, true, util.Paths{})
+	if err == nil || !strings.Contains(err.Error(), "url: ${.missing.host}") {
+		t.Errorf("expected unresolved reference error, got: %v", err)
+	}
+}
+
+func TestSubstitute_UnresolvedSortedByPath(t *testing.T) {
+	r := newTestResolver(t, nil)
+	// a is resolved after c, which it references
+	config := map[string]interface{}{
+		"a": "${M1}-${.c}",
+		"c": "${M2}",
+	}
+
+	_, err := Substitute(config, r, true, util.Paths{})
+	if err == nil || !strings.Contains(err.Error(), "a: ${M1}; c: ${M2}") {
+		t.Errorf("unresolved placeholders should be listed by path, got: %v", err)
+	}
+}
+
+func TestSubstitute_Expressions(t *testing.T) {
+	r := newTestResolver(t, map[string]string{"ENV": "prod", "REGION": "eu"})
+	config := map[string]interface{}{
+		"name":     `${{ upper(ENV) + "-" + REGION }}`,
+		"replicas": `${{ ENV == "prod" ? 3 : 1 }}`,
+		"base":     8000,
+		"port":     "${{ .base + 80 }}",
+		"url":      "http://${.name}:${{ .port }}/",
+		"literal":  "$${{ ENV }}",
+	}
+
+	got, err := Substitute(config, r, true, util.Paths{})
+	if err != nil {
+		t.Fatalf("Substitute failed: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"name":     "PROD-eu",
+		"replicas": 3,
+		"base":     8000,
+		"port":     8080,
+		"url":      "http://PROD-eu:8080/",
+		"literal":  "${{ ENV }}",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Substitute = %#v, want %#v", got, want)
+	}
+}
+
+func TestSubstitute_ExpressionErrorReportsPosition(t *testing.T) {
+	r := newTestResolver(t, nil)
+	config := map[string]interface{}{"bad": "${{ 1 + }}"}
+
+	_, err := Substitute(config, r, false, util.Paths{})
+	if err == nil {
+		t.Fatal("expected expression error")
+	}
+	if !strings.Contains(err.Error(), "bad: ${{ 1 + }}") || !strings.Contains(err.Error(), "position 6") {
+		t.Errorf("error should name the path and position, got: %v", err)
+	}
+}
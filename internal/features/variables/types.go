@@ -1,23 +1,110 @@
 package variables
 
 import (
+	"fmt"
 	"regexp"
+	"strings"
 )
 
-// VarRegex is the regular expression for matching variable placeholders like ${VAR_NAME}.
-var VarRegex = regexp.MustCompile(`\$\{[A-Za-z0-9_]+\}`)
+// VarRegex is the regular expression for matching variable placeholders.
+// Supported forms follow shell parameter expansion:
+//   - ${VAR}          value of VAR
+//   - ${VAR:-default} value of VAR, or default when VAR is unset or empty
+//   - ${VAR:?message} value of VAR, or an error carrying message when VAR is unset or empty
+//   - ${VAR:+alt}     alt when VAR is set and non-empty, otherwise an empty string
+//   - $${VAR}         escaped placeholder, rendered literally as ${VAR}
+//   - ${.path.to.key} self-reference to another value of the merged config
+//
+// Any of the forms above may end with |string (e.g. ${PORT|string}) to keep
+// a whole-value placeholder from being converted to the variable's native type.
+// Computed ${{ expression }} placeholders are matched by ExprRegex.
+//
+// Submatches: 1 = escape '$', 2 = variable name, 3 = operator, 4 = operand,
+// 5 = string opt-out.
+var VarRegex = regexp.MustCompile(`(\$?)\$\{([A-Za-z0-9_]+|\.[^}:|]+)(?:(:[-?+])([^}]*?))?(\|string)?\}`)
+
+// ExprRegex is the regular expression for matching ${{ expression }}
+// placeholders, which are evaluated with the expression package. A leading
+// '$' escapes the placeholder ($${{ ... }} renders as ${{ ... }}).
+//
+// Submatches: 1 = escape '$', 2 = expression source.
+var ExprRegex = regexp.MustCompile(`(?s)(\$?)\$\{\{(.*?)\}\}`)
+
+// ReferenceLookup resolves a dot-separated config path for ${.path} references.
+type ReferenceLookup func(path string) (interface{}, bool)
+
+// isReference reports whether a placeholder name is a ${.path} self-reference.
+func isReference(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
 
 // Definition defines a variable that can be used for substitution.
+//
+// Type, Required, Enum and Pattern constrain the resolved value, whichever
+// source it came from; they are checked by (*DefaultResolver).Validate.
 type Definition struct {
 	Name         string `yaml:"name" json:"name"`
 	Value        string `yaml:"value,omitempty" json:"value,omitempty"`
 	FromEnv      string `yaml:"fromEnv,omitempty" json:"fromEnv,omitempty"`
 	FromPath     string `yaml:"fromPath,omitempty" json:"fromPath,omitempty"`
 	DefaultValue string `yaml:"defaultValue,omitempty" json:"defaultValue,omitempty"`
+
+	// Type is one of string, int, float, bool, list or map.
+	Type string `yaml:"type,omitempty" json:"type,omitempty"`
+	// Required rejects values that are missing or empty.
+	Required bool `yaml:"required,omitempty" json:"required,omitempty"`
+	// Enum lists the allowed values, compared by their string form.
+	Enum []interface{} `yaml:"enum,omitempty" json:"enum,omitempty"`
+	// Pattern is a regular expression the string form of the value must match.
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	// Description documents the variable; it is shown by `konfigo vars`.
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
 }
 
 // Resolver interface defines the contract for variable resolution.
 type Resolver interface {
 	// SubstituteString performs variable substitution on a single string.
+	// Unresolved placeholders and escaped placeholders are left in place.
 	SubstituteString(input string) string
+
+	// Expand performs variable substitution on a single string, unescapes
+	// $${VAR} sequences and reports every placeholder it could not resolve.
+	Expand(input string) (string, []Unresolved)
+
+	// ExpandValue behaves like Expand, but a string consisting of exactly one
+	// placeholder is replaced by the variable's native (typed) value.
+	ExpandValue(input string) (interface{}, []Unresolved)
+
+	// WithReferenceLookup returns a resolver that resolves ${.path}
+	// self-references through lookup.
+	WithReferenceLookup(lookup ReferenceLookup) Resolver
+}
+
+// Unresolved describes a placeholder that could not be resolved.
+type Unresolved struct {
+	// Path is the configuration path of the value holding the placeholder.
+	// It is empty when the placeholder was not found inside a config value.
+	Path string
+	// Placeholder is the placeholder text as written, e.g. ${DB_HOST}.
+	Placeholder string
+	// Message is set for required markers (${VAR:?message}).
+	Message string
+	// Required is true when the placeholder used the ${VAR:?message} form
+	// or is a ${{ expression }} that failed to evaluate.
+	Required bool
+
+	// err is the expression error for failed ${{ expression }} placeholders.
+	err error
+}
+
+// String returns a human readable description of the unresolved placeholder.
+func (u Unresolved) String() string {
+	s := u.Placeholder
+	if u.Path != "" {
+		s = fmt.Sprintf("%s: %s", u.Path, u.Placeholder)
+	}
+	if u.Message != "" {
+		s += " (" + u.Message + ")"
+	}
+	return s
 }
@@ -0,0 +1,99 @@
+package merger
+
+import "konfigo/internal/logger"
+
+// ReplaceMarker is the key of a map whose value replaces the existing value
+// wholesale instead of being deep-merged into it:
+//
+//	server:
+//	  $replace: {host: example.com}
+//
+// A map with DeleteMarker set to true removes the key it is assigned to.
+const ReplaceMarker = "$replace"
+
+// applyDirective handles a $delete or $replace directive, or a null in
+// null-deletes mode, for the source key srcKey. dstKey is the matching key in
+// dst, if exists is true. It reports whether srcVal was a directive, and fails
+// when the directive changes a value whose override policy is PolicyError.
+func applyDirective(dst map[string]interface{}, dstKey string, exists bool, srcKey string, srcVal interface{}, path string, opts *Options) (bool, error) {
+	if isDeleteMarked(srcVal) || (srcVal == nil && opts.NullDeletes) {
+		if exists {
+			if apply, err := opts.checkOverride(path, dst[dstKey], nil, true); !apply {
+				return true, err
+			}
+			logger.Debug("  - Deleting key: %s", path)
+			delete(dst, dstKey)
+			if opts.Observer != nil {
+				opts.Observer.Delete(path)
+			}
+		}
+		return true, nil
+	}
+
+	m, ok := srcVal.(map[string]interface{})
+	if !ok {
+		return false, nil
+	}
+	replacement, ok := m[ReplaceMarker]
+	if !ok {
+		return false, nil
+	}
+	replacement = stripValue(replacement, opts.NullDeletes)
+	if len(m) > 1 {
+		logger.Debug("  - Ignoring keys next to %s at %s", ReplaceMarker, path)
+	}
+	if exists {
+		if apply, err := opts.checkOverride(path, dst[dstKey], replacement, false); !apply {
+			return true, err
+		}
+		delete(dst, dstKey)
+	}
+	logger.Debug("  - Replacing value of key: %s", path)
+	opts.set(dst, srcKey, path, replacement)
+	return true, nil
+}
+
+// StripDirectives removes merge directives left in a merged configuration.
+// Keys assigned a $delete map are removed, $replace wrappers are unwrapped and
+// leftover $delete markers are dropped. When nullDeletes is true, keys with
+// null values are removed as well.
+func StripDirectives(config map[string]interface{}, nullDeletes bool) {
+	stripMap(config, nullDeletes)
+}
+
+// stripMap strips directives from m in place.
+func stripMap(m map[string]interface{}, nullDeletes bool) {
+	for key, val := range m {
+		if key == DeleteMarker {
+			delete(m, key)
+			continue
+		}
+		if isDeleteMarked(val) || (val == nil && nullDeletes) {
+			delete(m, key)
+			continue
+		}
+		m[key] = stripValue(val, nullDeletes)
+	}
+}
+
+// stripValue returns v with directives stripped; maps and arrays are updated in place.
+func stripValue(v interface{}, nullDeletes bool) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if replacement, ok := val[ReplaceMarker]; ok {
+			return stripValue(replacement, nullDeletes)
+		}
+		stripMap(val, nullDeletes)
+		return val
+	case []interface{}:
+		result := val[:0]
+		for _, item := range val {
+			if isDeleteMarked(item) {
+				continue
+			}
+			result = append(result, stripValue(item, nullDeletes))
+		}
+		return result
+	}
+	return v
+}
@@ -0,0 +1,54 @@
+package merger
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeWithOptions_Directives(t *testing.T) {
+	dst := map[string]interface{}{
+		"debug":  map[string]interface{}{"enabled": true},
+		"server": map[string]interface{}{"host": "localhost", "port": 80, "tls": map[string]interface{}{"cert": "a.pem"}},
+		"Cache":  map[string]interface{}{"ttl": 60},
+		"keep":   "value",
+	}
+	src := map[string]interface{}{
+		"debug":   map[string]interface{}{DeleteMarker: true},
+		"server":  map[string]interface{}{ReplaceMarker: map[string]interface{}{"host": "example.com"}},
+		"cache":   map[string]interface{}{DeleteMarker: true},
+		"missing": map[string]interface{}{DeleteMarker: true},
+		"added":   map[string]interface{}{"nested": map[string]interface{}{DeleteMarker: true}, "x": 1},
+	}
+	MergeWithOptions(dst, src, Options{})
+	StripDirectives(dst, false)
+
+	want := map[string]interface{}{
+		"server": map[string]interface{}{"host": "example.com"},
+		"keep":   "value",
+		"added":  map[string]interface{}{"x": 1},
+	}
+	if !reflect.DeepEqual(dst, want) {
+		t.Errorf("directives:\n got %v\nwant %v", dst, want)
+	}
+}
+
+func TestMergeWithOptions_NullDeletes(t *testing.T) {
+	base := func() map[string]interface{} {
+		return map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": 2, "d": 3}}
+	}
+	src := map[string]interface{}{"a": nil, "b": map[string]interface{}{"c": nil}}
+
+	dst := base()
+	MergeWithOptions(dst, src, Options{CaseSensitive: true, NullDeletes: true})
+	StripDirectives(dst, true)
+	if want := map[string]interface{}{"b": map[string]interface{}{"d": 3}}; !reflect.DeepEqual(dst, want) {
+		t.Errorf("null deletes: got %v, want %v", dst, want)
+	}
+
+	dst = base()
+	MergeWithOptions(dst, src, Options{CaseSensitive: true})
+	StripDirectives(dst, false)
+	if want := map[string]interface{}{"a": nil, "b": map[string]interface{}{"c": nil, "d": 3}}; !reflect.DeepEqual(dst, want) {
+		t.Errorf("nulls kept: got %v, want %v", dst, want)
+	}
+}
@@ -0,0 +1,140 @@
+package merger
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// keyIndex maps the lowercase form of a map's keys to the keys themselves,
+// so that case-insensitive lookups do not scan the map.
+type keyIndex map[string]string
+
+// newKeyIndex indexes the keys of m. When keys differ only in case, the
+// smallest one is indexed so that lookups are deterministic.
+func newKeyIndex(m map[string]interface{}) keyIndex {
+	index := make(keyIndex, len(m))
+	for k := range m {
+		lower := strings.ToLower(k)
+		if existing, ok := index[lower]; !ok || k < existing {
+			index[lower] = k
+		}
+	}
+	return index
+}
+
+// lookup returns the key of the indexed map that matches key ignoring case.
+func (ix keyIndex) lookup(key string) (string, bool) {
+	k, ok := ix[strings.ToLower(key)]
+	return k, ok
+}
+
+// add indexes key, replacing the key that matched it before.
+func (ix keyIndex) add(key string) {
+	ix[strings.ToLower(key)] = key
+}
+
+// update re-indexes the lowercase form of keys[0] after m changed, using the
+// first of keys that is still present in m.
+func (ix keyIndex) update(m map[string]interface{}, keys ...string) {
+	delete(ix, strings.ToLower(keys[0]))
+	for _, k := range keys {
+		if _, ok := m[k]; ok {
+			ix.add(k)
+			return
+		}
+	}
+}
+
+// valueSet holds the elements of an array by structural hash, so that
+// membership tests do not compare against every element.
+type valueSet struct {
+	buckets map[uint64][]interface{}
+}
+
+// newValueSet creates a set with room for n values.
+func newValueSet(n int) *valueSet {
+	return &valueSet{buckets: make(map[uint64][]interface{}, n)}
+}
+
+// add adds v to the set and reports whether it was not present yet. Values
+// are equal when they are reflect.DeepEqual.
+func (s *valueSet) add(v interface{}) bool {
+	h := valueHash(v)
+	for _, existing := range s.buckets[h] {
+		if reflect.DeepEqual(existing, v) {
+			return false
+		}
+	}
+	s.buckets[h] = append(s.buckets[h], v)
+	return true
+}
+
+// valueHash returns a structural hash of v. Values that are reflect.DeepEqual
+// have the same hash; map keys are hashed in sorted order.
+func valueHash(v interface{}) uint64 {
+	h := fnv.New64a()
+	writeHash(h, v)
+	return h.Sum64()
+}
+
+// writeHash writes a type tag and the content of v to h.
+func writeHash(h hash.Hash64, v interface{}) {
+	var buf [9]byte
+	writeUint := func(tag byte, n uint64) {
+		buf[0] = tag
+		binary.LittleEndian.PutUint64(buf[1:], n)
+		h.Write(buf[:])
+	}
+	writeString := func(tag byte, s string) {
+		writeUint(tag, uint64(len(s)))
+		h.Write([]byte(s))
+	}
+
+	switch val := v.(type) {
+	case nil:
+		h.Write([]byte{'n'})
+	case bool:
+		if val {
+			writeUint('b', 1)
+		} else {
+			writeUint('b', 0)
+		}
+	case string:
+		writeString('s', val)
+	case int:
+		writeUint('i', uint64(val))
+	case int64:
+		writeUint('I', uint64(val))
+	case uint64:
+		writeUint('u', val)
+	case float64:
+		if val == 0 {
+			val = 0 // -0 equals 0
+		}
+		writeUint('f', math.Float64bits(val))
+	case []interface{}:
+		writeUint('a', uint64(len(val)))
+		for _, item := range val {
+			writeHash(h, item)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		writeUint('m', uint64(len(keys)))
+		for _, k := range keys {
+			writeString('k', k)
+			writeHash(h, val[k])
+		}
+	default:
+		writeString('?', fmt.Sprintf("%T:%v", val, val))
+	}
+}
@@ -11,6 +11,10 @@
 // - Immutable paths are protected from being overwritten
 // - Arrays are replaced entirely by default, or merged by union with -m flag
 // - Maps are merged recursively
+// - Schema merge rules select a different strategy for matching paths
+// - Sources can delete keys ($delete: true) or replace subtrees ($replace: {...})
+// - Override policies allow, warn about, reject or ignore changes to set values
+// - Replacing a value with one of a different shape (map, array, scalar) is reported
 //
 // Usage:
 //
@@ -22,123 +26,294 @@ package merger
 
 import (
 	"konfigo/internal/logger"
+	"konfigo/internal/util"
 	"reflect"
 	"strings"
 )
 
+// Options controls how Merge combines values.
+type Options struct {
+	// CaseSensitive matches keys exactly instead of ignoring case.
+	CaseSensitive bool
+	// ImmutablePaths are protected from being overwritten once set.
+	ImmutablePaths map[string]struct{}
+	// MergeArrays merges arrays by union with deduplication instead of replacing them.
+	MergeArrays bool
+	// Rules select a merge strategy per path. The first matching rule wins;
+	// paths without a rule are merged deeply.
+	Rules []Rule
+	// NullDeletes treats a null source value as a $delete directive.
+	NullDeletes bool
+	// Observer, if set, is told about every value the merge writes or deletes.
+	Observer Observer
+	// Policies select what happens when a value that is already set is
+	// changed. The first matching rule wins; paths without a rule allow
+	// overrides, except immutable paths, which keep their first value.
+	Policies []PolicyRule
+	// OnOverride, if set, is called for every change of a value that is already set.
+	OnOverride func(Override)
+	// OnShapeConflict, if set, is called when a source replaces a value with
+	// one of a different shape; an error stops the merge. When it is not
+	// set, conflicts are logged as warnings.
+	OnShapeConflict func(ShapeConflict) error
+}
+
+// Observer is notified of the changes a merge makes to the destination map.
+// Paths are dot-separated and use the source's key casing.
+type Observer interface {
+	// Set is called when value (a scalar, array or whole subtree) is stored at path.
+	Set(path string, value interface{})
+	// Delete is called when the key at path is removed.
+	Delete(path string)
+}
+
+// set stores value under key and notifies the observer.
+func (o *Options) set(dst map[string]interface{}, key, path string, value interface{}) {
+	dst[key] = value
+	if o.Observer != nil {
+		o.Observer.Set(path, value)
+	}
+}
+
+// applyRule stores the result of a merge rule under key. Values kept by
+// keep-first are not reported as changed.
+func (o *Options) applyRule(dst map[string]interface{}, key, path string, rule Rule, dstVal, srcVal interface{}) error {
+	value, err := applyStrategy(rule, dstVal, srcVal, path, o)
+	if err != nil {
+		return err
+	}
+	if rule.Strategy == StrategyKeepFirst {
+		dst[key] = value
+		return nil
+	}
+	o.set(dst, key, path, value)
+	return nil
+}
+
+// setNew stores the value of a path that is not set yet (see newValue).
+func (o *Options) setNew(dst map[string]interface{}, key, path string, srcVal interface{}) error {
+	value, err := o.newValue(path, srcVal)
+	if err != nil {
+		return err
+	}
+	o.set(dst, key, path, value)
+	return nil
+}
+
 // Merge recursively merges a source map into a destination map, respecting immutable paths.
 // When mergeArrays is true, arrays are merged by union with deduplication instead of replaced.
 func Merge(dst, src map[string]interface{}, caseSensitive bool, immutablePaths map[string]struct{}, mergeArrays bool) {
-	if caseSensitive {
-		mergeCaseSensitive(dst, src, "", immutablePaths, mergeArrays)
-	} else {
-		mergeCaseInsensitive(dst, src, "", immutablePaths, mergeArrays)
+	// Without override policies the merge cannot fail.
+	_ = MergeWithOptions(dst, src, Options{
+		CaseSensitive:  caseSensitive,
+		ImmutablePaths: immutablePaths,
+		MergeArrays:    mergeArrays,
+	})
+}
+
+// MergeWithOptions recursively merges a source map into a destination map
+// using the given options. It returns an *OverrideError when src overrides a
+// path whose policy is PolicyError, or the error of OnShapeConflict; dst may
+// then be partially merged.
+func MergeWithOptions(dst, src map[string]interface{}, opts Options) error {
+	return mergeMaps(dst, src, "", &opts)
+}
+
+// mergeMaps merges src into dst at path, matching keys according to opts.
+func mergeMaps(dst, src map[string]interface{}, path string, opts *Options) error {
+	if opts.CaseSensitive {
+		return mergeCaseSensitive(dst, src, path, opts)
+	}
+	return mergeCaseInsensitive(dst, src, path, opts)
+}
+
+// skipImmutable reports whether the change of an immutable path is ignored.
+// Immutable paths keep their first value unless an override policy covers them.
+func (o *Options) skipImmutable(path string, previous, value interface{}) bool {
+	if _, ok := PolicyFor(o.Policies, path, o.CaseSensitive); ok {
+		return false
 	}
+	logger.Debug("  - Skipping overwrite of immutable key: %s", path)
+	if !reflect.DeepEqual(previous, value) {
+		o.reportOverride(Override{Path: path, Previous: previous, Value: value, Policy: PolicyFirstWins, Ignored: true})
+	}
+	return true
 }
 
 // mergeCaseSensitive performs a merge where keys are matched exactly.
-func mergeCaseSensitive(dst, src map[string]interface{}, path string, immutablePaths map[string]struct{}, mergeArrays bool) {
+func mergeCaseSensitive(dst, src map[string]interface{}, path string, opts *Options) error {
+	immutablePaths := opts.ImmutablePaths
 	for key, srcVal := range src {
-		currentPath := key
-		if path != "" {
-			currentPath = path + "." + key
-		}
+		currentPath := util.AppendKey(path, key)
+		dstVal, exists := dst[key]
 
 		// Check for immutability (exact match or child of immutable path)
-		if isPathImmutable(currentPath, immutablePaths) {
-			if _, exists := dst[key]; exists {
-				logger.Debug("  - Skipping overwrite of immutable key: %s", currentPath)
-				continue
+		if exists && isPathImmutable(currentPath, immutablePaths) && opts.skipImmutable(currentPath, dstVal, srcVal) {
+			continue
+		}
+
+		handled, err := applyDirective(dst, key, exists, key, srcVal, currentPath, opts)
+		if err != nil {
+			return err
+		}
+		if handled {
+			continue
+		}
+		if !exists {
+			if err := opts.setNew(dst, key, currentPath, srcVal); err != nil {
+				return err
 			}
+			continue
 		}
 
-		if dstVal, ok := dst[key]; ok {
+		rule := opts.ruleFor(currentPath)
+		if rule == nil {
 			if dstMap, dstOk := dstVal.(map[string]interface{}); dstOk {
 				if srcMap, srcOk := srcVal.(map[string]interface{}); srcOk {
-					mergeCaseSensitive(dstMap, srcMap, currentPath, immutablePaths, mergeArrays)
+					if err := mergeCaseSensitive(dstMap, srcMap, currentPath, opts); err != nil {
+						return err
+					}
 					continue
 				}
 			}
-			if mergeArrays {
-				if dstSlice, dstOk := dstVal.([]interface{}); dstOk {
-					if srcSlice, srcOk := srcVal.([]interface{}); srcOk {
-						dst[key] = mergeSlices(dstSlice, srcSlice)
-						continue
-					}
+		}
+		if rule == nil || rule.Strategy != StrategyKeepFirst {
+			apply, err := opts.checkOverride(currentPath, dstVal, srcVal, false)
+			if err != nil {
+				return err
+			}
+			if !apply {
+				continue
+			}
+		}
+
+		if rule != nil {
+			if err := opts.applyRule(dst, key, currentPath, *rule, dstVal, srcVal); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := opts.checkShape(currentPath, dstVal, srcVal); err != nil {
+			return err
+		}
+		if opts.MergeArrays {
+			if dstSlice, dstOk := dstVal.([]interface{}); dstOk {
+				if srcSlice, srcOk := srcVal.([]interface{}); srcOk {
+					opts.set(dst, key, currentPath, mergeSlices(dstSlice, srcSlice))
+					continue
 				}
 			}
 		}
-		dst[key] = srcVal
+		opts.set(dst, key, currentPath, srcVal)
 	}
+	return nil
 }
 
 // mergeCaseInsensitive performs a merge that ignores key casing for matching.
-func mergeCaseInsensitive(dst, src map[string]interface{}, path string, immutablePaths map[string]struct{}, mergeArrays bool) {
+func mergeCaseInsensitive(dst, src map[string]interface{}, path string, opts *Options) error {
+	immutablePaths := opts.ImmutablePaths
+	index := newKeyIndex(dst)
 	for srcKey, srcVal := range src {
-		existingDstKey, found := findCaseInsensitiveKey(dst, srcKey)
-		currentPath := srcKey
-		if path != "" {
-			currentPath = path + "." + srcKey
-		}
+		existingDstKey, found := index.lookup(srcKey)
+		currentPath := util.AppendKey(path, srcKey)
 
 		// Check for immutability (exact match or child of immutable path)
 		if found {
-			immutableCheckPath := existingDstKey
-			if path != "" {
-				immutableCheckPath = path + "." + existingDstKey
-			}
-			if isImmutableCaseInsensitive(immutableCheckPath, immutablePaths) {
-				logger.Debug("  - Skipping overwrite of immutable key: %s", immutableCheckPath)
+			immutableCheckPath := util.AppendKey(path, existingDstKey)
+			if isImmutableCaseInsensitive(immutableCheckPath, immutablePaths) && opts.skipImmutable(immutableCheckPath, dst[existingDstKey], srcVal) {
 				continue
 			}
 		}
 
+		// Still check immutability for case-insensitive paths
+		if !found && isImmutableCaseInsensitive(currentPath, immutablePaths) {
+			logger.Debug("  - Skipping overwrite of immutable key: %s", currentPath)
+			continue
+		}
+
+		handled, err := applyDirective(dst, existingDstKey, found, srcKey, srcVal, currentPath, opts)
+		if err != nil {
+			return err
+		}
+		if handled {
+			index.update(dst, srcKey, existingDstKey)
+			continue
+		}
+
 		if !found {
-			// Still check immutability for case-insensitive paths
-			if isImmutableCaseInsensitive(currentPath, immutablePaths) {
-				logger.Debug("  - Skipping overwrite of immutable key: %s", currentPath)
-				continue
+			if err := opts.setNew(dst, srcKey, currentPath, srcVal); err != nil {
+				return err
 			}
-			dst[srcKey] = srcVal
+			index.add(srcKey)
 			continue
 		}
 
 		dstVal := dst[existingDstKey]
+		rule := opts.ruleFor(currentPath)
+		dstMap, dstOk := dstVal.(map[string]interface{})
+		srcMap, srcOk := srcVal.(map[string]interface{})
+		deepMerge := rule == nil && dstOk && srcOk
+
+		if !deepMerge && (rule == nil || rule.Strategy != StrategyKeepFirst) {
+			apply, err := opts.checkOverride(currentPath, dstVal, srcVal, false)
+			if err != nil {
+				return err
+			}
+			if !apply {
+				continue
+			}
+		}
+		if rule == nil && !deepMerge {
+			if err := opts.checkShape(currentPath, dstVal, srcVal); err != nil {
+				return err
+			}
+		}
+
 		if existingDstKey != srcKey {
 			logger.Debug("  - Key casing changed: '%s' -> '%s' (case-insensitive merge)", existingDstKey, srcKey)
 		}
 		delete(dst, existingDstKey)
+		index.add(srcKey)
 
-		dstMap, dstOk := dstVal.(map[string]interface{})
-		srcMap, srcOk := srcVal.(map[string]interface{})
+		if rule != nil {
+			if err := opts.applyRule(dst, srcKey, currentPath, *rule, dstVal, srcVal); err != nil {
+				return err
+			}
+			continue
+		}
 
-		if dstOk && srcOk {
-			mergeCaseInsensitive(dstMap, srcMap, currentPath, immutablePaths, mergeArrays)
+		if deepMerge {
 			dst[srcKey] = dstMap
-		} else if mergeArrays {
+			if err := mergeCaseInsensitive(dstMap, srcMap, currentPath, opts); err != nil {
+				return err
+			}
+		} else if opts.MergeArrays {
 			dstSlice, dstSliceOk := dstVal.([]interface{})
 			srcSlice, srcSliceOk := srcVal.([]interface{})
 			if dstSliceOk && srcSliceOk {
-				dst[srcKey] = mergeSlices(dstSlice, srcSlice)
+				opts.set(dst, srcKey, currentPath, mergeSlices(dstSlice, srcSlice))
 			} else {
-				dst[srcKey] = srcVal
+				opts.set(dst, srcKey, currentPath, srcVal)
 			}
 		} else {
-			dst[srcKey] = srcVal
+			opts.set(dst, srcKey, currentPath, srcVal)
 		}
 	}
+	return nil
 }
 
 // isPathImmutable checks if a path is immutable by exact match or if it is a child of an immutable path.
+// Immutable paths may be patterns such as "services.*.port".
 func isPathImmutable(path string, immutablePaths map[string]struct{}) bool {
 	if immutablePaths == nil {
 		return false
 	}
+	path = elementPattern(path)
 	if _, ok := immutablePaths[path]; ok {
 		return true
 	}
 	for ip := range immutablePaths {
-		if strings.HasPrefix(path, ip+".") {
+		if util.MatchPathOrAncestor(ip, path, true) {
 			return true
 		}
 	}
@@ -151,41 +326,29 @@ func isImmutableCaseInsensitive(path string, immutablePaths map[string]struct{})
 	if immutablePaths == nil {
 		return false
 	}
-	lowerPath := strings.ToLower(path)
+	path = elementPattern(path)
 	for ip := range immutablePaths {
-		lowerIP := strings.ToLower(ip)
-		if lowerIP == lowerPath || strings.HasPrefix(lowerPath, lowerIP+".") {
+		if util.MatchPathOrAncestor(ip, path, false) {
 			return true
 		}
 	}
 	return false
 }
 
-// maxSliceMergeSize is the threshold above which array union deduplication
-// falls back to append-only (no dedup) to avoid O(n*m) performance.
-const maxSliceMergeSize = 1000
-
-// mergeSlices returns the union of two slices, deduplicating elements using reflect.DeepEqual.
-// For slices larger than maxSliceMergeSize, deduplication is skipped to avoid O(n*m) cost.
+// mergeSlices returns the union of two slices: dst followed by the elements
+// of src that are not already present. Elements are compared structurally
+// (reflect.DeepEqual) through a hash set, so the cost is linear in the total
+// size of both slices.
 func mergeSlices(dst, src []interface{}) []interface{} {
 	result := make([]interface{}, len(dst), len(dst)+len(src))
 	copy(result, dst)
 
-	if len(dst)*len(src) > maxSliceMergeSize*maxSliceMergeSize {
-		logger.Debug("  - Array merge: skipping deduplication for large slices (%d x %d)", len(dst), len(src))
-		result = append(result, src...)
-		return result
+	seen := newValueSet(len(dst) + len(src))
+	for _, dv := range dst {
+		seen.add(dv)
 	}
-
 	for _, sv := range src {
-		found := false
-		for _, dv := range result {
-			if reflect.DeepEqual(dv, sv) {
-				found = true
-				break
-			}
-		}
-		if !found {
+		if seen.add(sv) {
 			result = append(result, sv)
 		}
 	}
@@ -193,7 +356,8 @@ func mergeSlices(dst, src []interface{}) []interface{} {
 }
 
 // findCaseInsensitiveKey iterates over a map's keys and returns the original key
-// if a case-insensitive match is found.
+// if a case-insensitive match is found. Merges of whole maps use a keyIndex
+// instead; this is for single lookups.
 func findCaseInsensitiveKey(m map[string]interface{}, key string) (string, bool) {
 	for k := range m {
 		if strings.EqualFold(k, key) {
@@ -1,6 +1,7 @@
 package merger
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 )
@@ -167,3 +168,121 @@ func TestMerge_NestedArraysMerged(t *testing.T) {
 		t.Errorf("nested array merge: got %v, want %v", got, want)
 	}
 }
+
+func TestMerge_ImmutablePattern(t *testing.T) {
+	for _, caseSensitive := range []bool{true, false} {
+		dst := map[string]interface{}{
+			"services": map[string]interface{}{
+				"api": map[string]interface{}{"port": 80, "host": "a"},
+			},
+		}
+		src := map[string]interface{}{
+			"services": map[string]interface{}{
+				"api": map[string]interface{}{"port": 8080, "host": "b"},
+			},
+		}
+		Merge(dst, src, caseSensitive, map[string]struct{}{"services.*.port": {}}, false)
+
+		api := dst["services"].(map[string]interface{})["api"].(map[string]interface{})
+		if api["port"] != 80 {
+			t.Errorf("caseSensitive=%v: services.api.port = %v, want 80 (immutable)", caseSensitive, api["port"])
+		}
+		if api["host"] != "b" {
+			t.Errorf("caseSensitive=%v: services.api.host = %v, want b", caseSensitive, api["host"])
+		}
+	}
+}
+
+func TestMergeSlices_StructuralDedup(t *testing.T) {
+	dst := []interface{}{
+		map[string]interface{}{"name": "a", "ports": []interface{}{80, 443}},
+		1, 1.0, "1", nil,
+	}
+	src := []interface{}{
+		map[string]interface{}{"ports": []interface{}{80, 443}, "name": "a"},
+		map[string]interface{}{"name": "a", "ports": []interface{}{443, 80}},
+		1, 1.0, "1", nil, true,
+	}
+	got := mergeSlices(dst, src)
+	want := append(append([]interface{}{}, dst...), src[1], true)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeSlices = %v, want %v", got, want)
+	}
+}
+
+func TestMergeSlices_LargeSlicesDeduplicated(t *testing.T) {
+	dst := make([]interface{}, 0, 5000)
+	src := make([]interface{}, 0, 5000)
+	for i := 0; i < 5000; i++ {
+		dst = append(dst, fmt.Sprintf("flag-%d", i))
+		src = append(src, fmt.Sprintf("flag-%d", i+2500))
+	}
+	if got := mergeSlices(dst, src); len(got) != 7500 {
+		t.Errorf("len(mergeSlices) = %d, want 7500", len(got))
+	}
+}
+
+func TestMerge_CaseInsensitiveIndexFollowsChanges(t *testing.T) {
+	dst := map[string]interface{}{"Host": "a", "Port": 80, "Debug": true}
+	src := map[string]interface{}{
+		"host":  "b",
+		"PORT":  map[string]interface{}{ReplaceMarker: 8080},
+		"debug": map[string]interface{}{DeleteMarker: true},
+		"NEW":   1,
+	}
+	Merge(dst, src, false, nil, false)
+	want := map[string]interface{}{"host": "b", "PORT": 8080, "NEW": 1}
+	if !reflect.DeepEqual(dst, want) {
+		t.Errorf("case-insensitive merge = %v, want %v", dst, want)
+	}
+}
+
+// flagFile returns a feature-flag style configuration with n flags, each a
+// map with a few keys and a list of targets.
+func flagFile(n int, prefix string) map[string]interface{} {
+	flags := make(map[string]interface{}, n)
+	targets := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		flags[fmt.Sprintf("%sFlag%d", prefix, i)] = map[string]interface{}{
+			"Enabled": i%2 == 0,
+			"Rollout": float64(i % 100),
+			"Owner":   fmt.Sprintf("team-%d", i%10),
+		}
+		targets = append(targets, map[string]interface{}{"id": fmt.Sprintf("%s-%d", prefix, i), "weight": i})
+	}
+	return map[string]interface{}{"flags": flags, "targets": targets}
+}
+
+func benchmarkMerge(b *testing.B, n int, caseSensitive bool) {
+	src := flagFile(n, "")
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		dst := flagFile(n, "")
+		b.StartTimer()
+		Merge(dst, src, caseSensitive, nil, true)
+	}
+}
+
+// The merge benchmarks grow the input tenfold; the time per operation should
+// grow roughly tenfold as well.
+func BenchmarkMerge_CaseInsensitive_1k(b *testing.B)   { benchmarkMerge(b, 1000, false) }
+func BenchmarkMerge_CaseInsensitive_10k(b *testing.B)  { benchmarkMerge(b, 10000, false) }
+func BenchmarkMerge_CaseInsensitive_100k(b *testing.B) { benchmarkMerge(b, 100000, false) }
+func BenchmarkMerge_CaseSensitive_100k(b *testing.B)   { benchmarkMerge(b, 100000, true) }
+
+func benchmarkMergeSlices(b *testing.B, n int) {
+	dst := make([]interface{}, 0, n)
+	src := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		dst = append(dst, map[string]interface{}{"id": i, "name": fmt.Sprintf("item-%d", i)})
+		src = append(src, map[string]interface{}{"id": i + n/2, "name": fmt.Sprintf("item-%d", i+n/2)})
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mergeSlices(dst, src)
+	}
+}
+
+func BenchmarkMergeSlices_1k(b *testing.B)   { benchmarkMergeSlices(b, 1000) }
+func BenchmarkMergeSlices_10k(b *testing.B)  { benchmarkMergeSlices(b, 10000) }
+func BenchmarkMergeSlices_100k(b *testing.B) { benchmarkMergeSlices(b, 100000) }
@@ -0,0 +1,126 @@
+package merger
+
+import (
+	"fmt"
+	"konfigo/internal/logger"
+	"konfigo/internal/util"
+	"reflect"
+)
+
+// Policy names what happens when a later source changes a value that is already set.
+type Policy string
+
+const (
+	// PolicyAllow lets later sources override the value. This is the default.
+	PolicyAllow Policy = "allow"
+	// PolicyWarn lets later sources override the value and logs a warning.
+	PolicyWarn Policy = "warn"
+	// PolicyError fails the merge when a later source overrides the value.
+	PolicyError Policy = "error"
+	// PolicyFirstWins keeps the first value set and ignores later ones.
+	PolicyFirstWins Policy = "first-wins"
+)
+
+// policies lists the valid policies.
+var policies = map[Policy]bool{
+	PolicyAllow:     true,
+	PolicyWarn:      true,
+	PolicyError:     true,
+	PolicyFirstWins: true,
+}
+
+// PolicyRule selects the override policy for the paths matching Path and
+// every path below them. Path is a path pattern (see util.MatchPath).
+type PolicyRule struct {
+	Path   string `yaml:"path" json:"path"`
+	Policy Policy `yaml:"policy" json:"policy"`
+}
+
+// ValidatePolicies checks that every policy rule has a path and a known policy.
+func ValidatePolicies(rules []PolicyRule) error {
+	for i, rule := range rules {
+		if rule.Path == "" {
+			return fmt.Errorf("override rule %d: 'path' is required", i)
+		}
+		if !policies[rule.Policy] {
+			return fmt.Errorf("override rule for '%s': unknown policy '%s' (expected allow, warn, error or first-wins)", rule.Path, rule.Policy)
+		}
+	}
+	return nil
+}
+
+// PolicyFor returns the policy of the first rule matching path or one of its
+// ancestors. It reports false when no rule matches.
+func PolicyFor(rules []PolicyRule, path string, caseSensitive bool) (Policy, bool) {
+	for _, rule := range rules {
+		if util.MatchPathOrAncestor(rule.Path, path, caseSensitive) {
+			return rule.Policy, true
+		}
+	}
+	return "", false
+}
+
+// Override describes a value that a source changed after an earlier source set it.
+type Override struct {
+	Path     string      `json:"path"`
+	Previous interface{} `json:"previous"`
+	Value    interface{} `json:"value,omitempty"`
+	Deleted  bool        `json:"deleted,omitempty"`
+	Policy   Policy      `json:"policy"`
+	// Ignored is true when the change was not applied (first-wins or immutable).
+	Ignored bool `json:"ignored,omitempty"`
+}
+
+// OverrideError is returned when a source overrides a path whose policy is PolicyError.
+type OverrideError struct {
+	Path     string
+	Previous interface{}
+	Value    interface{}
+	Deleted  bool
+}
+
+func (e *OverrideError) Error() string {
+	if e.Deleted {
+		return fmt.Sprintf("path '%s' is set to %v and cannot be deleted (override policy: error)", e.Path, e.Previous)
+	}
+	return fmt.Sprintf("path '%s' is set to %v and cannot be overridden with %v (override policy: error)", e.Path, e.Previous, e.Value)
+}
+
+// checkOverride applies the override policy of path to a change of the value
+// previous to value, or to its deletion, and reports the change to
+// OnOverride. It returns whether the change should be applied. Changes that
+// leave the value as it is are not overrides.
+func (o *Options) checkOverride(path string, previous, value interface{}, deleted bool) (bool, error) {
+	if !deleted && reflect.DeepEqual(previous, value) {
+		return true, nil
+	}
+	policy, ok := PolicyFor(o.Policies, path, o.CaseSensitive)
+	if !ok {
+		policy = PolicyAllow
+	}
+
+	if policy == PolicyError {
+		return false, &OverrideError{Path: path, Previous: previous, Value: value, Deleted: deleted}
+	}
+	o.reportOverride(Override{Path: path, Previous: previous, Value: value, Deleted: deleted, Policy: policy, Ignored: policy == PolicyFirstWins})
+
+	switch policy {
+	case PolicyWarn:
+		if deleted {
+			logger.Warn("Deleting '%s' (was %v)", path, previous)
+		} else {
+			logger.Warn("Overriding '%s': %v -> %v", path, previous, value)
+		}
+	case PolicyFirstWins:
+		logger.Debug("  - Keeping first value of %s (first-wins)", path)
+		return false, nil
+	}
+	return true, nil
+}
+
+// reportOverride passes an override to OnOverride, if set.
+func (o *Options) reportOverride(override Override) {
+	if o.OnOverride != nil {
+		o.OnOverride(override)
+	}
+}
@@ -0,0 +1,129 @@
+package merger
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMergeWithOptions_Policies(t *testing.T) {
+	for _, caseSensitive := range []bool{true, false} {
+		dst := map[string]interface{}{
+			"server":   map[string]interface{}{"host": "localhost", "port": 80},
+			"region":   "eu",
+			"app":      map[string]interface{}{"name": "shop", "debug": true},
+			"replicas": 1,
+		}
+		src := map[string]interface{}{
+			"server":   map[string]interface{}{"port": 8080, "host": "localhost"},
+			"region":   "us",
+			"app":      map[string]interface{}{"name": "store", "debug": map[string]interface{}{DeleteMarker: true}},
+			"replicas": 3,
+		}
+		var overrides []Override
+		err := MergeWithOptions(dst, src, Options{
+			CaseSensitive: caseSensitive,
+			Policies: []PolicyRule{
+				{Path: "server.*", Policy: PolicyWarn},
+				{Path: "region", Policy: PolicyFirstWins},
+				{Path: "app", Policy: PolicyAllow},
+			},
+			OnOverride: func(o Override) { overrides = append(overrides, o) },
+		})
+		if err != nil {
+			t.Fatalf("MergeWithOptions (caseSensitive=%v): unexpected error: %v", caseSensitive, err)
+		}
+
+		want := map[string]interface{}{
+			"server":   map[string]interface{}{"host": "localhost", "port": 8080},
+			"region":   "eu",
+			"app":      map[string]interface{}{"name": "store"},
+			"replicas": 3,
+		}
+		if !reflect.DeepEqual(dst, want) {
+			t.Errorf("MergeWithOptions (caseSensitive=%v):\n got %v\nwant %v", caseSensitive, dst, want)
+		}
+
+		sort.Slice(overrides, func(i, j int) bool { return overrides[i].Path < overrides[j].Path })
+		wantOverrides := []Override{
+			{Path: "app.debug", Previous: true, Deleted: true, Policy: PolicyAllow},
+			{Path: "app.name", Previous: "shop", Value: "store", Policy: PolicyAllow},
+			{Path: "region", Previous: "eu", Value: "us", Policy: PolicyFirstWins, Ignored: true},
+			{Path: "replicas", Previous: 1, Value: 3, Policy: PolicyAllow},
+			{Path: "server.port", Previous: 80, Value: 8080, Policy: PolicyWarn},
+		}
+		if !reflect.DeepEqual(overrides, wantOverrides) {
+			t.Errorf("overrides (caseSensitive=%v):\n got %+v\nwant %+v", caseSensitive, overrides, wantOverrides)
+		}
+	}
+}
+
+func TestMergeWithOptions_PolicyError(t *testing.T) {
+	dst := map[string]interface{}{"db": map[string]interface{}{"password": "secret"}}
+	src := map[string]interface{}{"db": map[string]interface{}{"password": "hunter2"}}
+	err := MergeWithOptions(dst, src, Options{
+		CaseSensitive: true,
+		Policies:      []PolicyRule{{Path: "db", Policy: PolicyError}},
+	})
+
+	var overrideErr *OverrideError
+	if !errors.As(err, &overrideErr) {
+		t.Fatalf("expected *OverrideError, got %v", err)
+	}
+	if overrideErr.Path != "db.password" {
+		t.Errorf("OverrideError.Path = %q, want %q", overrideErr.Path, "db.password")
+	}
+
+	// Setting the same value again is not an override
+	dst = map[string]interface{}{"db": map[string]interface{}{"password": "secret"}}
+	src = map[string]interface{}{"db": map[string]interface{}{"password": "secret", "user": "admin"}}
+	if err := MergeWithOptions(dst, src, Options{Policies: []PolicyRule{{Path: "db", Policy: PolicyError}}}); err != nil {
+		t.Errorf("unexpected error for unchanged value: %v", err)
+	}
+}
+
+func TestMergeWithOptions_ImmutablePolicy(t *testing.T) {
+	immutable := map[string]struct{}{"app.name": {}, "region": {}}
+	dst := map[string]interface{}{"app": map[string]interface{}{"name": "shop"}}
+	src := map[string]interface{}{"app": map[string]interface{}{"name": "store"}}
+
+	// Immutable paths without a policy keep their first value and are reported as ignored
+	var overrides []Override
+	err := MergeWithOptions(dst, src, Options{
+		CaseSensitive:  true,
+		ImmutablePaths: immutable,
+		OnOverride:     func(o Override) { overrides = append(overrides, o) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Override{{Path: "app.name", Previous: "shop", Value: "store", Policy: PolicyFirstWins, Ignored: true}}
+	if !reflect.DeepEqual(overrides, want) {
+		t.Errorf("overrides:\n got %+v\nwant %+v", overrides, want)
+	}
+
+	// A policy covering an immutable path takes precedence
+	dst = map[string]interface{}{"region": "eu"}
+	src = map[string]interface{}{"region": "us"}
+	err = MergeWithOptions(dst, src, Options{
+		CaseSensitive:  true,
+		ImmutablePaths: immutable,
+		Policies:       []PolicyRule{{Path: "region", Policy: PolicyError}},
+	})
+	if err == nil {
+		t.Error("expected an error for the immutable path covered by an error policy")
+	}
+}
+
+func TestValidatePolicies(t *testing.T) {
+	if err := ValidatePolicies([]PolicyRule{{Path: "a", Policy: PolicyWarn}}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidatePolicies([]PolicyRule{{Path: "a", Policy: "deny"}}); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+	if err := ValidatePolicies([]PolicyRule{{Policy: PolicyError}}); err == nil {
+		t.Error("expected an error for a missing path")
+	}
+}
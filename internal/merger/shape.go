@@ -0,0 +1,51 @@
+package merger
+
+import "konfigo/internal/logger"
+
+// Shape is the structural kind of a configuration value.
+type Shape string
+
+const (
+	// ShapeMap is a map of keys to values.
+	ShapeMap Shape = "map"
+	// ShapeArray is a list of values.
+	ShapeArray Shape = "array"
+	// ShapeScalar is a string, number or boolean.
+	ShapeScalar Shape = "scalar"
+)
+
+// ShapeOf returns the shape of v. Null has no shape and fits any value.
+func ShapeOf(v interface{}) Shape {
+	switch v.(type) {
+	case nil:
+		return ""
+	case map[string]interface{}:
+		return ShapeMap
+	case []interface{}:
+		return ShapeArray
+	}
+	return ShapeScalar
+}
+
+// ShapeConflict describes a source replacing a value with one of a different
+// shape, such as a map with a scalar.
+type ShapeConflict struct {
+	Path     string
+	Previous interface{}
+	Value    interface{}
+}
+
+// checkShape reports a change of shape from previous to value at path to
+// OnShapeConflict, or logs a warning when it is not set. Merges that replace
+// values through a merge rule or $replace are intended and not checked.
+func (o *Options) checkShape(path string, previous, value interface{}) error {
+	from, to := ShapeOf(previous), ShapeOf(value)
+	if from == "" || to == "" || from == to {
+		return nil
+	}
+	if o.OnShapeConflict == nil {
+		logger.Warn("Shape conflict at '%s': %s replaced by %s", path, from, to)
+		return nil
+	}
+	return o.OnShapeConflict(ShapeConflict{Path: path, Previous: previous, Value: value})
+}
@@ -0,0 +1,123 @@
+package merger
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestShapeOf(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  Shape
+	}{
+		{map[string]interface{}{}, ShapeMap},
+		{[]interface{}{1}, ShapeArray},
+		{"off", ShapeScalar},
+		{42, ShapeScalar},
+		{false, ShapeScalar},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := ShapeOf(tt.value); got != tt.want {
+			t.Errorf("ShapeOf(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMerge_ShapeConflicts(t *testing.T) {
+	for _, caseSensitive := range []bool{true, false} {
+		dst := map[string]interface{}{
+			"logging": map[string]interface{}{"level": "debug"},
+			"ports":   []interface{}{80},
+			"name":    "a",
+			"unset":   nil,
+		}
+		src := map[string]interface{}{
+			"logging": "off",
+			"ports":   []interface{}{81},
+			"name":    map[string]interface{}{"first": "b"},
+			"unset":   map[string]interface{}{"x": 1},
+		}
+		var conflicts []string
+		opts := Options{
+			CaseSensitive: caseSensitive,
+			OnShapeConflict: func(c ShapeConflict) error {
+				conflicts = append(conflicts, c.Path)
+				return nil
+			},
+		}
+		if err := MergeWithOptions(dst, src, opts); err != nil {
+			t.Fatalf("caseSensitive=%v: unexpected error: %v", caseSensitive, err)
+		}
+		if len(conflicts) != 2 {
+			t.Errorf("caseSensitive=%v: conflicts = %v, want logging and name", caseSensitive, conflicts)
+		}
+		if dst["logging"] != "off" {
+			t.Errorf("caseSensitive=%v: logging = %v, want the later value", caseSensitive, dst["logging"])
+		}
+	}
+}
+
+func TestMerge_ShapeConflictError(t *testing.T) {
+	strict := errors.New("strict")
+	dst := map[string]interface{}{"logging": map[string]interface{}{"level": "debug"}}
+	src := map[string]interface{}{"logging": "off"}
+	err := MergeWithOptions(dst, src, Options{
+		CaseSensitive:   true,
+		OnShapeConflict: func(ShapeConflict) error { return strict },
+	})
+	if err != strict {
+		t.Fatalf("expected the OnShapeConflict error, got %v", err)
+	}
+	if want := map[string]interface{}{"level": "debug"}; !reflect.DeepEqual(dst["logging"], want) {
+		t.Errorf("logging = %v, want it unchanged", dst["logging"])
+	}
+}
+
+func TestMerge_ShapeChangeByRuleIsNotAConflict(t *testing.T) {
+	dst := map[string]interface{}{"logging": map[string]interface{}{"level": "debug"}}
+	src := map[string]interface{}{"logging": map[string]interface{}{ReplaceMarker: "off"}}
+	err := MergeWithOptions(dst, src, Options{
+		CaseSensitive:   true,
+		OnShapeConflict: func(c ShapeConflict) error { return errors.New("unexpected conflict at " + c.Path) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dst["logging"] != "off" {
+		t.Errorf("logging = %v, want off", dst["logging"])
+	}
+}
+
+func TestMerge_ShapeConflictInKeyedElement(t *testing.T) {
+	strict := errors.New("strict")
+	for _, caseSensitive := range []bool{true, false} {
+		dst := map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "sidecar"},
+				map[string]interface{}{"name": "app", "resources": map[string]interface{}{"cpu": 1}},
+			},
+		}
+		src := map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "app", "resources": "none"},
+			},
+		}
+		var paths []string
+		err := MergeWithOptions(dst, src, Options{
+			CaseSensitive: caseSensitive,
+			Rules:         []Rule{{Path: "containers", Strategy: StrategyKeyedMerge, Key: "name"}},
+			OnShapeConflict: func(c ShapeConflict) error {
+				paths = append(paths, c.Path)
+				return strict
+			},
+		})
+		if err != strict {
+			t.Fatalf("caseSensitive=%v: expected the OnShapeConflict error, got %v", caseSensitive, err)
+		}
+		if !reflect.DeepEqual(paths, []string{"containers[1].resources"}) {
+			t.Errorf("caseSensitive=%v: conflict paths = %v, want containers[1].resources", caseSensitive, paths)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package merger
+
+import (
+	"fmt"
+	"konfigo/internal/logger"
+	"konfigo/internal/util"
+	"strings"
+)
+
+// Strategy names how the value at a path is combined with the value already merged.
+type Strategy string
+
+const (
+	// StrategyDeep merges maps recursively; arrays are replaced, or merged by
+	// union when MergeArrays is set. This is the default.
+	StrategyDeep Strategy = "deep"
+	// StrategyReplace replaces the existing value, even when both are maps.
+	StrategyReplace Strategy = "replace"
+	// StrategyAppend appends the new array's elements to the existing array.
+	StrategyAppend Strategy = "append"
+	// StrategyPrepend puts the new array's elements before the existing ones.
+	StrategyPrepend Strategy = "prepend"
+	// StrategyUnion appends the new elements that are not already present.
+	StrategyUnion Strategy = "union"
+	// StrategyKeyedMerge merges arrays of maps element by element, matching
+	// elements by the value of Rule.Key.
+	StrategyKeyedMerge Strategy = "keyed-merge"
+	// StrategyKeepFirst keeps the first value set and ignores later ones.
+	StrategyKeepFirst Strategy = "keep-first"
+)
+
+// strategies lists the valid strategies.
+var strategies = map[Strategy]bool{
+	StrategyDeep:       true,
+	StrategyReplace:    true,
+	StrategyAppend:     true,
+	StrategyPrepend:    true,
+	StrategyUnion:      true,
+	StrategyKeyedMerge: true,
+	StrategyKeepFirst:  true,
+}
+
+// Rule selects the merge strategy for the paths matching Path, a path
+// pattern (see util.MatchPath) such as "services.*.ports".
+type Rule struct {
+	Path     string   `yaml:"path" json:"path"`
+	Strategy Strategy `yaml:"strategy" json:"strategy"`
+	// Key is the identity field of array elements for keyed-merge.
+	Key string `yaml:"key,omitempty" json:"key,omitempty"`
+}
+
+// ValidateRules checks that every rule has a path and a known strategy, and
+// that keyed-merge rules name their key.
+func ValidateRules(rules []Rule) error {
+	for i, rule := range rules {
+		if rule.Path == "" {
+			return fmt.Errorf("merge rule %d: 'path' is required", i)
+		}
+		if !strategies[rule.Strategy] {
+			return fmt.Errorf("merge rule for '%s': unknown strategy '%s' (expected deep, replace, append, prepend, union, keyed-merge or keep-first)", rule.Path, rule.Strategy)
+		}
+		if rule.Strategy == StrategyKeyedMerge && rule.Key == "" {
+			return fmt.Errorf("merge rule for '%s': keyed-merge requires 'key'", rule.Path)
+		}
+		if rule.Strategy != StrategyKeyedMerge && rule.Key != "" {
+			return fmt.Errorf("merge rule for '%s': 'key' is only supported by keyed-merge", rule.Path)
+		}
+	}
+	return nil
+}
+
+// ruleFor returns the first rule matching path, or nil when the path is
+// merged with the default deep strategy.
+func (o *Options) ruleFor(path string) *Rule {
+	path = elementPattern(path)
+	for i := range o.Rules {
+		if util.MatchPath(o.Rules[i].Path, path, o.CaseSensitive) {
+			if o.Rules[i].Strategy == StrategyDeep {
+				return nil
+			}
+			return &o.Rules[i]
+		}
+	}
+	return nil
+}
+
+// elementPattern returns path without the array indices of keyed-merge
+// elements, which rule and immutable paths leave out: the path
+// containers[1].env is matched as containers.env.
+func elementPattern(path string) string {
+	if !strings.Contains(path, "[") {
+		return path
+	}
+	segments, err := util.ParsePath(path)
+	if err != nil {
+		return path
+	}
+	kept := segments[:0]
+	for _, s := range segments {
+		if s.Kind != util.SegmentIndex {
+			kept = append(kept, s)
+		}
+	}
+	return util.FormatPath(kept)
+}
+
+// newValue returns the value stored for a path that is not set yet. Arrays
+// under a keyed-merge rule are normalized so that duplicate keys are merged
+// and deletion markers do not leak into the result.
+func (o *Options) newValue(path string, v interface{}) (interface{}, error) {
+	rule := o.ruleFor(path)
+	if rule == nil || rule.Strategy != StrategyKeyedMerge {
+		return v, nil
+	}
+	if s, ok := v.([]interface{}); ok {
+		return mergeKeyed(nil, s, rule.Key, path, o)
+	}
+	return v, nil
+}
+
+// applyStrategy combines the existing value dst with src at path according to
+// rule and returns the value to store. Array strategies replace the value when
+// either side is not an array. Only keyed-merge can fail, with the error of
+// an element merge.
+func applyStrategy(rule Rule, dst, src interface{}, path string, opts *Options) (interface{}, error) {
+	switch rule.Strategy {
+	case StrategyReplace:
+		return src, nil
+	case StrategyKeepFirst:
+		logger.Debug("  - Keeping first value of %s (keep-first)", path)
+		return dst, nil
+	}
+
+	dstSlice, dstOk := dst.([]interface{})
+	srcSlice, srcOk := src.([]interface{})
+	if !dstOk || !srcOk {
+		logger.Debug("  - Merge strategy %s at %s needs two arrays; replacing value", rule.Strategy, path)
+		return src, nil
+	}
+
+	switch rule.Strategy {
+	case StrategyAppend:
+		result := make([]interface{}, 0, len(dstSlice)+len(srcSlice))
+		return append(append(result, dstSlice...), srcSlice...), nil
+	case StrategyPrepend:
+		result := make([]interface{}, 0, len(dstSlice)+len(srcSlice))
+		return append(append(result, srcSlice...), dstSlice...), nil
+	case StrategyUnion:
+		return mergeSlices(dstSlice, srcSlice), nil
+	case StrategyKeyedMerge:
+		return mergeKeyed(dstSlice, srcSlice, rule.Key, path, opts)
+	}
+	return src, nil
+}
+
+// DeleteMarker is the key that, set to true on an element of a keyed-merge
+// array, removes the element with the same identity key.
+const DeleteMarker = "$delete"
+
+// mergeKeyed merges two arrays of maps by the value of key. A src element
+// whose key matches a dst element is deep-merged into it and a src element
+// marked with DeleteMarker removes it. All other src elements are appended,
+// so existing elements keep their position and new ones follow in src order.
+// It fails with the error of an element merge, such as a shape conflict.
+func mergeKeyed(dst, src []interface{}, key, path string, opts *Options) ([]interface{}, error) {
+	result := make([]interface{}, len(dst), len(dst)+len(src))
+	copy(result, dst)
+
+	// The array is reported to the observer and checked against override
+	// policies as a whole, not element by element.
+	elementOpts := *opts
+	elementOpts.Observer = nil
+	elementOpts.Policies = nil
+	elementOpts.OnOverride = nil
+
+	// positions maps identity keys to the first element that has them.
+	// Deleted elements are only marked, so positions stay valid, and are
+	// dropped at the end.
+	positions := make(map[string]int, len(dst)+len(src))
+	for i, dv := range result {
+		if id, ok := elementKey(dv, key, opts.CaseSensitive); ok {
+			if _, seen := positions[id]; !seen {
+				positions[id] = i
+			}
+		}
+	}
+	deleted := make(map[int]bool)
+	// copied marks the elements of result that are copies, which can be
+	// merged into without changing the dst or src arrays they came from.
+	copied := make(map[int]bool)
+
+	for _, sv := range src {
+		id, ok := elementKey(sv, key, opts.CaseSensitive)
+		if !ok {
+			if isDeleteMarked(sv) {
+				logger.Debug("  - Ignoring %s element without '%s' at %s", DeleteMarker, key, path)
+				continue
+			}
+			result = append(result, sv)
+			continue
+		}
+
+		index, found := positions[id]
+		switch {
+		case isDeleteMarked(sv):
+			if !found {
+				logger.Debug("  - No element with %s=%s to delete at %s", key, id, path)
+				continue
+			}
+			logger.Debug("  - Deleting element with %s=%s at %s", key, id, path)
+			deleted[index] = true
+			delete(positions, id)
+		case found:
+			if !copied[index] {
+				element, err := util.DeepCopyMap(result[index].(map[string]interface{}))
+				if err != nil {
+					return nil, fmt.Errorf("failed to copy element with %s=%s at %s: %w", key, id, path, err)
+				}
+				result[index] = element
+				copied[index] = true
+			}
+			element, err := util.DeepCopyMap(withoutDeleteMarker(sv))
+			if err != nil {
+				return nil, fmt.Errorf("failed to copy element with %s=%s at %s: %w", key, id, path, err)
+			}
+			elementPath := util.AppendIndex(path, index)
+			if err := mergeMaps(result[index].(map[string]interface{}), element, elementPath, &elementOpts); err != nil {
+				return nil, err
+			}
+		default:
+			positions[id] = len(result)
+			result = append(result, withoutDeleteMarker(sv))
+		}
+	}
+
+	if len(deleted) == 0 {
+		return result, nil
+	}
+	kept := result[:0]
+	for i, v := range result {
+		if !deleted[i] {
+			kept = append(kept, v)
+		}
+	}
+	return kept, nil
+}
+
+// isDeleteMarked reports whether v is a map with DeleteMarker set to true.
+func isDeleteMarked(v interface{}) bool {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	marked, _ := m[DeleteMarker].(bool)
+	return marked
+}
+
+// withoutDeleteMarker returns the element map without a DeleteMarker key.
+func withoutDeleteMarker(v interface{}) map[string]interface{} {
+	m := v.(map[string]interface{})
+	if _, ok := m[DeleteMarker]; !ok {
+		return m
+	}
+	stripped := make(map[string]interface{}, len(m)-1)
+	for k, val := range m {
+		if k != DeleteMarker {
+			stripped[k] = val
+		}
+	}
+	return stripped
+}
+
+// elementKey returns the string form of an array element's identity key.
+func elementKey(v interface{}, key string, caseSensitive bool) (string, bool) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	if !caseSensitive {
+		if k, found := findCaseInsensitiveKey(m, key); found {
+			key = k
+		}
+	}
+	id, ok := m[key]
+	if !ok || id == nil {
+		return "", false
+	}
+	return fmt.Sprintf("%v", id), true
+}
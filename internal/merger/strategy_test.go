@@ -0,0 +1,173 @@
+package merger
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeWithOptions_Strategies(t *testing.T) {
+	dst := map[string]interface{}{
+		"allowedHosts": []interface{}{"a.local", "b.local"},
+		"plugins":      []interface{}{"auth"},
+		"hooks":        []interface{}{"post"},
+		"tags":         []interface{}{"x", "y"},
+		"server":       map[string]interface{}{"host": "localhost", "port": 80},
+		"owner":        "base",
+		"db":           map[string]interface{}{"pool": map[string]interface{}{"min": 1}},
+	}
+	src := map[string]interface{}{
+		"allowedHosts": []interface{}{"c.local"},
+		"plugins":      []interface{}{"auth", "metrics"},
+		"hooks":        []interface{}{"pre"},
+		"tags":         []interface{}{"y", "z"},
+		"server":       map[string]interface{}{"port": 8080},
+		"owner":        "overlay",
+		"db":           map[string]interface{}{"pool": map[string]interface{}{"max": 10}},
+	}
+	MergeWithOptions(dst, src, Options{
+		CaseSensitive: true,
+		Rules: []Rule{
+			{Path: "allowedHosts", Strategy: StrategyReplace},
+			{Path: "plugins", Strategy: StrategyAppend},
+			{Path: "hooks", Strategy: StrategyPrepend},
+			{Path: "tags", Strategy: StrategyUnion},
+			{Path: "server", Strategy: StrategyReplace},
+			{Path: "owner", Strategy: StrategyKeepFirst},
+			{Path: "*.pool", Strategy: StrategyDeep},
+			{Path: "db.*", Strategy: StrategyReplace},
+		},
+	})
+
+	want := map[string]interface{}{
+		"allowedHosts": []interface{}{"c.local"},
+		"plugins":      []interface{}{"auth", "auth", "metrics"},
+		"hooks":        []interface{}{"pre", "post"},
+		"tags":         []interface{}{"x", "y", "z"},
+		"server":       map[string]interface{}{"port": 8080},
+		"owner":        "base",
+		"db":           map[string]interface{}{"pool": map[string]interface{}{"min": 1, "max": 10}},
+	}
+	if !reflect.DeepEqual(dst, want) {
+		t.Errorf("MergeWithOptions:\n got %v\nwant %v", dst, want)
+	}
+}
+
+func TestMergeWithOptions_KeyedMerge(t *testing.T) {
+	dst := map[string]interface{}{
+		"Containers": []interface{}{
+			map[string]interface{}{"name": "app", "image": "app:1", "ports": []interface{}{80}},
+			map[string]interface{}{"name": "sidecar", "image": "proxy:1"},
+		},
+	}
+	src := map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "app", "image": "app:2"},
+			map[string]interface{}{"name": "logger", "image": "fluent:1"},
+		},
+	}
+	MergeWithOptions(dst, src, Options{
+		Rules: []Rule{{Path: "containers", Strategy: StrategyKeyedMerge, Key: "name"}},
+	})
+
+	want := []interface{}{
+		map[string]interface{}{"name": "app", "image": "app:2", "ports": []interface{}{80}},
+		map[string]interface{}{"name": "sidecar", "image": "proxy:1"},
+		map[string]interface{}{"name": "logger", "image": "fluent:1"},
+	}
+	if got := dst["containers"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("keyed merge:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestValidateRules(t *testing.T) {
+	tests := []struct {
+		rule    Rule
+		wantErr bool
+	}{
+		{Rule{Path: "a", Strategy: StrategyAppend}, false},
+		{Rule{Path: "a", Strategy: StrategyKeyedMerge, Key: "id"}, false},
+		{Rule{Path: "a", Strategy: "merge"}, true},
+		{Rule{Path: "a", Strategy: StrategyKeyedMerge}, true},
+		{Rule{Path: "a", Strategy: StrategyUnion, Key: "id"}, true},
+		{Rule{Strategy: StrategyReplace}, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateRules([]Rule{tt.rule}); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateRules(%+v) error = %v, wantErr %v", tt.rule, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMergeWithOptions_KeyedMergeRemovalAndNesting(t *testing.T) {
+	opts := Options{
+		CaseSensitive: true,
+		Rules: []Rule{
+			{Path: "containers", Strategy: StrategyKeyedMerge, Key: "name"},
+			{Path: "containers.env", Strategy: StrategyKeyedMerge, Key: "name"},
+		},
+	}
+	dst := map[string]interface{}{}
+	MergeWithOptions(dst, map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "app", "env": []interface{}{
+				map[string]interface{}{"name": "LOG_LEVEL", "value": "info"},
+				map[string]interface{}{"name": "DEBUG", "value": "1"},
+			}},
+			map[string]interface{}{"name": "sidecar"},
+			map[string]interface{}{"name": "ghost", DeleteMarker: true},
+		},
+	}, opts)
+	MergeWithOptions(dst, map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{"name": "sidecar", DeleteMarker: true},
+			map[string]interface{}{"name": "app", "env": []interface{}{
+				map[string]interface{}{"name": "DEBUG", DeleteMarker: true},
+				map[string]interface{}{"name": "LOG_LEVEL", "value": "warn"},
+				map[string]interface{}{"name": "REGION", "value": "eu", DeleteMarker: false},
+			}},
+			"not-a-map",
+		},
+	}, opts)
+
+	want := []interface{}{
+		map[string]interface{}{"name": "app", "env": []interface{}{
+			map[string]interface{}{"name": "LOG_LEVEL", "value": "warn"},
+			map[string]interface{}{"name": "REGION", "value": "eu"},
+		}},
+		"not-a-map",
+	}
+	if got := dst["containers"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("keyed merge:\n got %v\nwant %v", got, want)
+	}
+}
+
+func TestMergeWithOptions_KeyedMergeKeepsInputs(t *testing.T) {
+	opts := Options{
+		CaseSensitive: true,
+		Rules:         []Rule{{Path: "containers", Strategy: StrategyKeyedMerge, Key: "name"}},
+	}
+	base := []interface{}{map[string]interface{}{"name": "app", "image": "app:1"}}
+	dst := map[string]interface{}{"containers": base}
+	MergeWithOptions(dst, map[string]interface{}{
+		"containers": []interface{}{map[string]interface{}{"name": "app", "image": "app:2"}},
+	}, opts)
+
+	if got := base[0].(map[string]interface{})["image"]; got != "app:1" {
+		t.Errorf("merge changed the dst element in place: image = %v", got)
+	}
+
+	overlay := []interface{}{
+		map[string]interface{}{"name": "web", "port": 80},
+		map[string]interface{}{"name": "web", "port": 8080},
+	}
+	fresh := map[string]interface{}{}
+	MergeWithOptions(fresh, map[string]interface{}{"containers": overlay}, opts)
+
+	if got := overlay[0].(map[string]interface{})["port"]; got != 80 {
+		t.Errorf("merge changed the src element in place: port = %v", got)
+	}
+	want := []interface{}{map[string]interface{}{"name": "web", "port": 8080}}
+	if got := fresh["containers"]; !reflect.DeepEqual(got, want) {
+		t.Errorf("keyed merge:\n got %v\nwant %v", got, want)
+	}
+}
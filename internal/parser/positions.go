@@ -0,0 +1,231 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"konfigo/internal/util"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Positions returns the line (1-based) on which each key of a configuration
+// file is defined, keyed by dot-separated path. Arrays are treated as leaves:
+// their elements are not listed. Positions are best effort; paths that cannot
+// be located are simply missing, and content that fails to parse yields an
+// empty map.
+func Positions(filePath string, content []byte, formatOverride string) map[string]int {
+	format := formatOverride
+	if format == "" {
+		format = DetectFormat(filePath)
+	}
+
+	lines := make(map[string]int)
+	switch NormalizeFormat(format) {
+	case "yaml":
+		yamlPositions(content, lines)
+	case "json":
+		jsonPositions(content, lines)
+	case "toml":
+		tomlPositions(content, lines)
+	case "ini":
+		iniPositions(content, lines)
+	case "env":
+		envPositions(content, lines)
+	}
+	return lines
+}
+
+// joinPath appends key to a path, quoting keys that contain dots.
+func joinPath(path, key string) string {
+	return util.AppendKey(path, key)
+}
+
+func yamlPositions(content []byte, lines map[string]int) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil || len(doc.Content) == 0 {
+		return
+	}
+	var walk func(node *yaml.Node, path string)
+	walk = func(node *yaml.Node, path string) {
+		if node.Kind == yaml.AliasNode && node.Alias != nil {
+			node = node.Alias
+		}
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" { // merge key
+				walk(value, path)
+				continue
+			}
+			keyPath := joinPath(path, key.Value)
+			lines[keyPath] = key.Line
+			walk(value, keyPath)
+		}
+	}
+	walk(doc.Content[0], "")
+}
+
+func jsonPositions(content []byte, lines map[string]int) {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	lineAt := func(offset int64) int {
+		return bytes.Count(content[:offset], []byte("\n")) + 1
+	}
+
+	// stack holds a frame per open object or array. Objects nested in arrays
+	// get a path starting with NUL so that their keys are skipped.
+	type frame struct {
+		path    string
+		isArray bool
+		key     string
+		wantKey bool
+	}
+	var stack []*frame
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return
+		}
+		var top *frame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		switch t := tok.(type) {
+		case json.Delim:
+			switch t {
+			case '{', '[':
+				path := ""
+				inArray := false
+				if top != nil {
+					path = joinPath(top.path, top.key)
+					inArray = top.isArray
+					if !top.isArray {
+						top.wantKey = true
+					}
+				}
+				if inArray {
+					path = "\x00" // elements of arrays are not tracked
+				}
+				stack = append(stack, &frame{path: path, isArray: t == '[', wantKey: t == '{'})
+			case '}', ']':
+				stack = stack[:len(stack)-1]
+			}
+		default:
+			if top == nil || top.isArray {
+				continue
+			}
+			if top.wantKey {
+				key, _ := t.(string)
+				top.key = key
+				top.wantKey = false
+				if !strings.HasPrefix(top.path, "\x00") {
+					lines[joinPath(top.path, key)] = lineAt(dec.InputOffset())
+				}
+				continue
+			}
+			top.wantKey = true
+		}
+	}
+}
+
+// tomlKey splits a (possibly dotted and quoted) TOML key into its parts.
+func tomlKey(key string) []string {
+	var parts []string
+	for _, part := range strings.Split(key, ".") {
+		part = strings.TrimSpace(part)
+		if unquoted, err := strconv.Unquote(part); err == nil {
+			part = unquoted
+		} else {
+			part = strings.Trim(part, "'")
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+func tomlPositions(content []byte, lines map[string]int) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 1<<20), 1<<20)
+	table := ""
+	inArrayTable := false
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "[["):
+			name := strings.Join(tomlKey(strings.Trim(line, "[] ")), ".")
+			if _, seen := lines[name]; !seen {
+				lines[name] = lineNum
+			}
+			inArrayTable = true
+			continue
+		case strings.HasPrefix(line, "["):
+			table = strings.Join(tomlKey(strings.Trim(line, "[] ")), ".")
+			lines[table] = lineNum
+			inArrayTable = false
+			continue
+		}
+		if inArrayTable {
+			continue
+		}
+		eq := strings.Index(line, "=")
+		if eq <= 0 {
+			continue
+		}
+		path := table
+		for _, part := range tomlKey(line[:eq]) {
+			path = joinPath(path, part)
+			if _, seen := lines[path]; !seen {
+				lines[path] = lineNum
+			}
+		}
+	}
+}
+
+func iniPositions(content []byte, lines map[string]int) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	section := ""
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "["):
+			section = strings.Trim(line, "[] ")
+			lines[section] = lineNum
+			continue
+		}
+		if eq := strings.IndexAny(line, "=:"); eq > 0 {
+			lines[joinPath(section, strings.TrimSpace(line[:eq]))] = lineNum
+		}
+	}
+}
+
+func envPositions(content []byte, lines map[string]int) {
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 1<<20), 1<<20)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		eq := strings.Index(line, "=")
+		if eq <= 0 {
+			continue
+		}
+		path := ""
+		for _, part := range strings.Split(strings.TrimSpace(line[:eq]), ".") {
+			path = joinPath(path, part)
+			if _, seen := lines[path]; !seen {
+				lines[path] = lineNum
+			}
+		}
+	}
+}
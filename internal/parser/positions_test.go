@@ -0,0 +1,24 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPositions(t *testing.T) {
+	tests := []struct {
+		file    string
+		content string
+		want    map[string]int
+	}{
+		{"a.yaml", "server:\n  host: x\n  ports: [1, 2]\nlist:\n  - a: 1\nname: y\n", map[string]int{"server": 1, "server.host": 2, "server.ports": 3, "list": 4, "name": 6}},
+		{"a.json", "{\n  \"server\": {\n    \"host\": \"x\",\n    \"list\": [{\"a\": 1}, 2]\n  },\n  \"name\": \"y\"\n}", map[string]int{"server": 2, "server.host": 3, "server.list": 4, "name": 6}},
+		{"a.toml", "name = \"y\"\n\n[server]\nhost = \"x\"\ndb.port = 5\n\n[[items]]\na = 1\n", map[string]int{"name": 1, "server": 3, "server.host": 4, "server.db": 5, "server.db.port": 5, "items": 7}},
+		{"a.env", "# c\nHOST=x\nDB.PORT=5\n", map[string]int{"HOST": 2, "DB": 3, "DB.PORT": 3}},
+	}
+	for _, tt := range tests {
+		if got := Positions(tt.file, []byte(tt.content), ""); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Positions(%s) = %v, want %v", tt.file, got, tt.want)
+		}
+	}
+}
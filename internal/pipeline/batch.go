@@ -8,6 +8,7 @@ import (
 	"konfigo/internal/marshaller"
 	"konfigo/internal/parser"
 	"konfigo/internal/reader"
+	"konfigo/internal/render"
 	"konfigo/internal/schema"
 	"konfigo/internal/util"
 	"konfigo/internal/writer"
@@ -35,6 +36,17 @@ func (p *Pipeline) processBatch(baseFinalConfig map[string]interface{}, loadedSc
 		return errors.NewError(errors.ErrorTypeCLIValidation, "forEach.output.filenamePattern is required")
 	}
 
+	var outputTemplate *render.Template
+	if forEachDirective.Output.Template != "" {
+		templatePath := resolveTemplatePath(forEachDirective.Output.Template, forEachDirective.SourceFile)
+		logger.Debug("Loading forEach output template from %s", templatePath)
+		var err error
+		outputTemplate, err = render.ParseFile(templatePath)
+		if err != nil {
+			return errors.WrapError(errors.ErrorTypeTemplate, "failed to load forEach output template", err).WithContext("file", templatePath)
+		}
+	}
+
 	iterationSources := []map[string]interface{}{}
 	itemFileBasenames := []string{} // For ${ITEM_FILE_BASENAME}
 
@@ -50,7 +62,7 @@ func (p *Pipeline) processBatch(baseFinalConfig map[string]interface{}, loadedSc
 	} else { // len(forEachDirective.ItemFiles) > 0
 		logger.Debug("Iterating using 'itemFiles' from forEach.")
 		for _, itemFilePath := range forEachDirective.ItemFiles {
-			fullItemFilePath, err := p.resolveItemFilePath(itemFilePath)
+			fullItemFilePath, err := resolveItemFilePath(itemFilePath, forEachDirective.SourceFile)
 			if err != nil {
 				return err
 			}
@@ -91,7 +103,7 @@ func (p *Pipeline) processBatch(baseFinalConfig map[string]interface{}, loadedSc
 			varsForThisIteration["ITEM_FILE_BASENAME"] = itemFileBasenames[i]
 		}
 
-		processedConfig, err := schema.Process(currentConfig, loadedSchema, varsForThisIteration, envVarsForSchema)
+		processedConfig, err := schema.ProcessWithOptions(currentConfig, loadedSchema, varsForThisIteration, envVarsForSchema, p.processOptions())
 		if err != nil {
 			return errors.WrapError(errors.ErrorTypeSchemaProcess, "schema processing failed for iteration", err).WithContext("iteration", i)
 		}
@@ -101,6 +113,18 @@ func (p *Pipeline) processBatch(baseFinalConfig map[string]interface{}, loadedSc
 			return errors.WrapError(errors.ErrorTypeInternal, "failed to resolve output filename for iteration", err).WithContext("iteration", i)
 		}
 
+		if outputTemplate != nil {
+			outputBytes, err := outputTemplate.Execute(processedConfig)
+			if err != nil {
+				return errors.WrapError(errors.ErrorTypeTemplate, "failed to render template", err).WithContext("file", outputTemplate.Name()).WithContext("iteration", i)
+			}
+			logger.Log("Writing output for iteration %d to %s (template: %s)", i, outputFilename, outputTemplate.Name())
+			if err := writer.WriteFile(outputFilename, outputBytes); err != nil {
+				return errors.WrapError(errors.ErrorTypeFileWrite, "error writing to file", err).WithContext("file", outputFilename).WithContext("iteration", i)
+			}
+			continue
+		}
+
 		outputFormat := strings.ToLower(strings.TrimPrefix(filepath.Ext(outputFilename), "."))
 		if forEachDirective.Output.Format != "" {
 			outputFormat = strings.ToLower(forEachDirective.Output.Format)
@@ -123,14 +147,15 @@ func (p *Pipeline) processBatch(baseFinalConfig map[string]interface{}, loadedSc
 	return nil
 }
 
-// resolveItemFilePath resolves and validates an itemFile path, preventing path traversal.
-func (p *Pipeline) resolveItemFilePath(itemFilePath string) (string, error) {
+// resolveItemFilePath resolves and validates an itemFile path relative to the
+// vars file that declared forEach, preventing path traversal.
+func resolveItemFilePath(itemFilePath, varsFile string) (string, error) {
 	fullItemFilePath := itemFilePath
 	if !filepath.IsAbs(itemFilePath) {
-		if p.Config.VarsFile == "" {
+		if varsFile == "" {
 			return "", errors.NewError(errors.ErrorTypeCLIValidation, "itemFiles with relative paths require a vars file (-V) to resolve against")
 		}
-		fullItemFilePath = filepath.Join(filepath.Dir(p.Config.VarsFile), itemFilePath)
+		fullItemFilePath = filepath.Join(filepath.Dir(varsFile), itemFilePath)
 	}
 
 	// Resolve symlinks to prevent containment bypass, then validate
@@ -146,8 +171,8 @@ func (p *Pipeline) resolveItemFilePath(itemFilePath string) (string, error) {
 	realItemPath = filepath.Join(realItemPath, filepath.Base(absItemPath))
 
 	// Only enforce containment when we have a vars file to contain against
-	if p.Config.VarsFile != "" {
-		allowedBase, err := filepath.Abs(filepath.Dir(p.Config.VarsFile))
+	if varsFile != "" {
+		allowedBase, err := filepath.Abs(filepath.Dir(varsFile))
 		if err != nil {
 			return "", errors.WrapError(errors.ErrorTypeFileRead, "failed to resolve vars file directory", err)
 		}
@@ -163,6 +188,15 @@ func (p *Pipeline) resolveItemFilePath(itemFilePath string) (string, error) {
 	return realItemPath, nil
 }
 
+// resolveTemplatePath resolves a forEach output template path. Relative paths
+// are resolved against the directory of the vars file that declares forEach.
+func resolveTemplatePath(templatePath, varsFile string) string {
+	if filepath.IsAbs(templatePath) || varsFile == "" {
+		return templatePath
+	}
+	return filepath.Join(filepath.Dir(varsFile), templatePath)
+}
+
 // resolveFilenamePattern substitutes placeholders in the filename pattern.
 // Placeholders: ${VAR_NAME}, ${ITEM_INDEX}, ${ITEM_FILE_BASENAME}
 func resolveFilenamePattern(pattern string, iterVars map[string]interface{}, envVarsForSchema map[string]string, schemaVars []variables.Definition, itemIndex int, itemFileBasename string) (string, error) {
@@ -5,7 +5,20 @@ import (
 )
 
 // Run creates and executes the processing pipeline with the given CLI configuration.
+// Subcommands are dispatched to their own entry points.
 func Run(config *cli.Config) error {
 	pipeline := NewPipeline(config)
+	switch config.Command {
+	case cli.CommandSubst:
+		return pipeline.RunSubst()
+	case cli.CommandVars:
+		return pipeline.RunVars()
+	case cli.CommandExplain:
+		return pipeline.RunExplain()
+	case cli.CommandSchema:
+		return pipeline.RunSchema()
+	case cli.CommandLint:
+		return pipeline.RunLint()
+	}
 	return pipeline.Run()
 }
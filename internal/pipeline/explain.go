@@ -0,0 +1,159 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"konfigo/internal/cli"
+	"konfigo/internal/errors"
+	"konfigo/internal/logger"
+	"konfigo/internal/provenance"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// explanation describes the final value of a leaf and how it got there.
+type explanation struct {
+	Path    string             `json:"path"`
+	Value   interface{}        `json:"value"`
+	Set     bool               `json:"set"`
+	History []provenance.Entry `json:"history"`
+}
+
+// RunExplain runs the explain command: it processes the sources like a normal
+// run while recording provenance, then prints the final value of the given
+// path (or of every leaf below it) with the file and line that set it and the
+// values it overrode. A forEach directive is ignored; the global vars are used.
+func (p *Pipeline) RunExplain() error {
+	if len(p.Config.Args) != 1 {
+		return errors.NewError(errors.ErrorTypeCLIValidation, "explain expects exactly one path, e.g. konfigo explain server.port -s config.yaml")
+	}
+	if p.Config.GetSourcePaths() == "" {
+		return errors.NewError(errors.ErrorTypeCLIValidation, "no input source specified. Use -s <paths> or pipe from stdin")
+	}
+	path := p.Config.Args[0]
+
+	p.provenance = provenance.New(p.Config.CaseSensitive)
+	finalConfig, err := p.explainConfig()
+	if err != nil {
+		return err
+	}
+
+	explanations := p.explain(finalConfig, path)
+	if len(explanations) == 0 {
+		return errors.NewErrorf(errors.ErrorTypeCLIValidation, "path '%s' was not found in the configuration", path)
+	}
+
+	if p.Config.OutputJSON {
+		data, err := json.MarshalIndent(explanations, "", "  ")
+		if err != nil {
+			return errors.WrapError(errors.ErrorTypeInternal, "failed to marshal explanation", err)
+		}
+		fmt.Fprintln(os.Stdout, string(data))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, e := range explanations {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if e.Set {
+			fmt.Fprintf(w, "%s = %s\n", e.Path, formatVarValue(e.Value))
+		} else {
+			fmt.Fprintf(w, "%s (not set)\n", e.Path)
+		}
+		for j := len(e.History) - 1; j >= 0; j-- {
+			entry := e.History[j]
+			value := formatVarValue(entry.Value)
+			if entry.Deleted {
+				value = "(deleted)"
+			}
+			status := "overridden"
+			if j == len(e.History)-1 {
+				status = "current"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\n", entry.Origin, value, status)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return errors.WrapError(errors.ErrorTypeFileWrite, "failed to write explanation", err)
+	}
+	return nil
+}
+
+// explainConfig builds the final configuration the way Run does for a single
+// output, without writing it.
+func (p *Pipeline) explainConfig() (map[string]interface{}, error) {
+	loadedSchema, immutablePaths, err := p.loadSchemaAndImmutablePaths()
+	if err != nil {
+		return nil, err
+	}
+	envResult, err := p.loadEnvironment(loadedSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	mergeOpts, err := p.mergeOptions(loadedSchema, immutablePaths)
+	if err != nil {
+		return nil, err
+	}
+	baseConfig, err := p.processSources(mergeOpts, envResult.Config.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	varsFromFile, forEachDirective, err := p.loadVariablesFile()
+	if err != nil {
+		return nil, err
+	}
+	if forEachDirective != nil {
+		logger.Warn("forEach directive in %s is ignored by the %s command", forEachDirective.SourceFile, cli.CommandExplain)
+	}
+
+	if loadedSchema != nil {
+		return p.processSingle(baseConfig, loadedSchema, varsFromFile, envResult.Vars)
+	}
+	return p.processBasicVariableSubstitution(baseConfig, envResult.Vars, varsFromFile)
+}
+
+// explain returns the explanation of every leaf at or below path. Leaves that
+// were deleted along the way are included with Set false.
+func (p *Pipeline) explain(finalConfig map[string]interface{}, path string) []explanation {
+	normalize := func(s string) string { return s }
+	if !p.Config.CaseSensitive {
+		normalize = strings.ToLower
+	}
+
+	// leaves maps the normalized path of every final leaf to its explanation
+	leaves := make(map[string]*explanation)
+	if value, ok := p.paths().Get(finalConfig, path); ok {
+		if m, isMap := value.(map[string]interface{}); isMap && len(m) > 0 {
+			for leaf, v := range provenance.Flatten(m) {
+				leaves[normalize(path+"."+leaf)] = &explanation{Path: path + "." + leaf, Value: v, Set: true}
+			}
+		} else {
+			leaves[normalize(path)] = &explanation{Path: path, Value: value, Set: true}
+		}
+	}
+
+	for _, leaf := range p.provenance.Paths(path) {
+		history := p.provenance.History(leaf)
+		if e, ok := leaves[normalize(leaf)]; ok {
+			e.History = history
+			continue
+		}
+		if len(history) > 0 && history[len(history)-1].Deleted {
+			leaves[normalize(leaf)] = &explanation{Path: leaf, History: history}
+		}
+		// Anything else is a stale entry, e.g. a stripped merge directive
+	}
+
+	explanations := make([]explanation, 0, len(leaves))
+	for _, e := range leaves {
+		explanations = append(explanations, *e)
+	}
+	sort.Slice(explanations, func(i, j int) bool { return explanations[i].Path < explanations[j].Path })
+	return explanations
+}
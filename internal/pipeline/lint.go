@@ -0,0 +1,80 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"konfigo/internal/errors"
+	"konfigo/internal/schema"
+	"os"
+)
+
+// RunLint runs the lint command: it checks the -S schema for mistakes that
+// would otherwise only show up when the schema is used, and prints the
+// findings. The sources given with -s, if any, are merged to learn which
+// paths exist and which variables they reference. The command fails when an
+// error is found; warnings alone do not fail it.
+func (p *Pipeline) RunLint() error {
+	if len(p.Config.Args) != 0 {
+		return errors.NewError(errors.ErrorTypeCLIValidation, "lint takes no arguments, e.g. konfigo lint -S schema.yaml")
+	}
+	if p.Config.SchemaFile == "" {
+		return errors.NewError(errors.ErrorTypeCLIValidation, "lint requires a schema file (-S)")
+	}
+
+	var merged map[string]interface{}
+	if p.Config.GetSourcePaths() != "" {
+		loadedSchema, err := schema.Load(p.Config.SchemaFile)
+		if err != nil {
+			return err
+		}
+		mergeOpts, err := p.mergeOptions(loadedSchema, nil)
+		if err != nil {
+			return err
+		}
+		envResult, err := p.loadEnvironment(nil)
+		if err != nil {
+			return err
+		}
+		merged, err = p.processSources(mergeOpts, envResult.Config.Data)
+		if err != nil {
+			return err
+		}
+	}
+
+	findings, err := schema.Lint(p.Config.SchemaFile, merged, p.Config.CaseSensitive)
+	if err != nil {
+		return err
+	}
+	if findings == nil {
+		findings = []schema.Finding{}
+	}
+
+	errorCount := 0
+	for _, f := range findings {
+		if f.Severity == schema.SeverityError {
+			errorCount++
+		}
+	}
+
+	if p.Config.OutputJSON {
+		data, err := json.MarshalIndent(findings, "", "  ")
+		if err != nil {
+			return errors.WrapError(errors.ErrorTypeInternal, "failed to marshal lint findings", err)
+		}
+		fmt.Fprintln(os.Stdout, string(data))
+	} else {
+		for _, f := range findings {
+			position := f.Position
+			if position == "" {
+				position = p.Config.SchemaFile
+			}
+			fmt.Fprintf(os.Stdout, "%s: %s\n", position, f)
+		}
+		fmt.Fprintf(os.Stdout, "%d error(s), %d warning(s)\n", errorCount, len(findings)-errorCount)
+	}
+
+	if errorCount > 0 {
+		return errors.NewErrorf(errors.ErrorTypeValidation, "lint found %d error(s) in %s", errorCount, p.Config.SchemaFile)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package pipeline
+
+import (
+	"konfigo/internal/logger"
+	"konfigo/internal/merger"
+	"konfigo/internal/util"
+	"sort"
+)
+
+// normalizeKeys converts every map key of data, at any depth and inside
+// arrays, to the case style given with --key-case. Keys that become equal
+// are merged in the sorted order of their original spelling, so the last one
+// wins, and a warning names them. An empty style leaves data as it is.
+func normalizeKeys(data map[string]interface{}, style, source string) map[string]interface{} {
+	if style == "" {
+		return data
+	}
+	return normalizeValue(data, style, source, "").(map[string]interface{})
+}
+
+// normalizeKey converts key to style. Merge directives keep their names.
+func normalizeKey(key, style string) string {
+	if key == merger.DeleteMarker || key == merger.ReplaceMarker {
+		return key
+	}
+	converted, _ := util.ChangeCase(key, style) // style is validated with the flags
+	return converted
+}
+
+// normalizeValue returns v with its map keys converted to style.
+func normalizeValue(v interface{}, style, source, path string) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		result := make(map[string]interface{}, len(val))
+		originals := make(map[string]string, len(val))
+		for _, k := range keys {
+			converted := normalizeKey(k, style)
+			childPath := util.AppendKey(path, converted)
+			child := normalizeValue(val[k], style, source, childPath)
+			existing, collides := result[converted]
+			if !collides {
+				result[converted] = child
+				originals[converted] = k
+				continue
+			}
+			logger.Warn("%s: keys '%s' and '%s' both become '%s' with --key-case %s; merging them", source, originals[converted], k, childPath, style)
+			existingMap, existingOk := existing.(map[string]interface{})
+			childMap, childOk := child.(map[string]interface{})
+			if existingOk && childOk {
+				merger.Merge(existingMap, childMap, true, nil, false)
+			} else {
+				result[converted] = child
+			}
+			originals[converted] = k
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(val))
+		for i, item := range val {
+			result[i] = normalizeValue(item, style, source, util.AppendIndex(path, i))
+		}
+		return result
+	}
+	return v
+}
+
+// normalizePositions converts the keys of the paths in positions to style,
+// so that line numbers can be found for normalized paths.
+func normalizePositions(positions map[string]int, style string) map[string]int {
+	if positions == nil || style == "" {
+		return positions
+	}
+	result := make(map[string]int, len(positions))
+	for path, line := range positions {
+		segments, err := util.ParsePath(path)
+		if err != nil {
+			continue
+		}
+		for i, seg := range segments {
+			if seg.Kind == util.SegmentKey {
+				segments[i].Key = normalizeKey(seg.Key, style)
+				segments[i].Quoted = true
+			}
+		}
+		normalized := util.FormatPath(segments)
+		if existing, ok := result[normalized]; !ok || line > existing {
+			result[normalized] = line
+		}
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"konfigo/internal/errors"
+	"konfigo/internal/merger"
+	"sort"
+	"text/tabwriter"
+)
+
+// overrideReport collects the keys each source overrode, in merge order.
+type overrideReport struct {
+	sources   []string
+	overrides map[string][]merger.Override
+}
+
+// newOverrideReport creates an empty report.
+func newOverrideReport() *overrideReport {
+	return &overrideReport{overrides: make(map[string][]merger.Override)}
+}
+
+// recorder returns a merger.Options.OnOverride callback that records the
+// overrides of source.
+func (r *overrideReport) recorder(source string) func(merger.Override) {
+	return func(o merger.Override) {
+		if _, ok := r.overrides[source]; !ok {
+			r.sources = append(r.sources, source)
+		}
+		r.overrides[source] = append(r.overrides[source], o)
+	}
+}
+
+// write prints the report grouped by source.
+func (r *overrideReport) write(out io.Writer) error {
+	if len(r.sources) == 0 {
+		_, err := fmt.Fprintln(out, "No keys were overridden.")
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Overridden keys by source:")
+	for _, source := range r.sources {
+		fmt.Fprintf(w, "%s\n", source)
+		overrides := r.overrides[source]
+		sort.SliceStable(overrides, func(i, j int) bool { return overrides[i].Path < overrides[j].Path })
+		for _, o := range overrides {
+			value := formatVarValue(o.Value)
+			if o.Deleted {
+				value = "(deleted)"
+			}
+			note := ""
+			switch {
+			case o.Ignored:
+				note = fmt.Sprintf("ignored (%s)", o.Policy)
+			case o.Policy != merger.PolicyAllow:
+				note = string(o.Policy)
+			}
+			line := fmt.Sprintf("  %s\t%s -> %s", o.Path, formatVarValue(o.Previous), value)
+			if note != "" {
+				line += "\t" + note
+			}
+			fmt.Fprintln(w, line)
+		}
+	}
+	return w.Flush()
+}
+
+// mergeSource merges the data of the named source into config. Overrides are
+// recorded in report and shape conflicts checked by shapes, if set. An
+// override rejected by a policy is returned as an ErrorTypeImmutableField
+// error, a shape conflict in strict mode as an ErrorTypeConfigMerge error.
+func mergeSource(config, data map[string]interface{}, opts merger.Options, name string, report *overrideReport, shapes *shapeTracker) error {
+	if report != nil {
+		opts.OnOverride = report.recorder(name)
+	}
+	if shapes != nil {
+		opts.Observer = shapes.observer(name, opts.Observer)
+		opts.OnShapeConflict = shapes.conflictHandler(name)
+	}
+	if err := merger.MergeWithOptions(config, data, opts); err != nil {
+		if errors.IsType(err, errors.ErrorTypeConfigMerge) {
+			return err
+		}
+		return errors.WrapError(errors.ErrorTypeImmutableField, fmt.Sprintf("%s overrides a protected value", name), err)
+	}
+	return nil
+}
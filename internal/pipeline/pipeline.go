@@ -33,20 +33,30 @@ import (
 	"konfigo/internal/cli"
 	"konfigo/internal/config"
 	"konfigo/internal/errors"
+	"konfigo/internal/features/coerce"
 	"konfigo/internal/features/variables"
 	"konfigo/internal/logger"
 	"konfigo/internal/marshaller"
 	"konfigo/internal/merger"
 	"konfigo/internal/parser"
+	"konfigo/internal/provenance"
 	"konfigo/internal/reader"
+	"konfigo/internal/render"
 	"konfigo/internal/schema"
+	"konfigo/internal/util"
 	"konfigo/internal/writer"
+	"os"
 	"strings"
 )
 
 // Pipeline represents the main processing pipeline
 type Pipeline struct {
 	Config *cli.Config
+
+	// varsOrigins maps each variable loaded from -V files to the file that set it.
+	varsOrigins map[string]string
+	// provenance, if set, records where each value of the configuration came from.
+	provenance *provenance.Tracker
 }
 
 // NewPipeline creates a new pipeline with the given CLI configuration
@@ -64,13 +74,20 @@ func (p *Pipeline) Run() error {
 		return err
 	}
 
-	env := config.NewEnvironment()
-	envResult := env.Load()
+	envResult, err := p.loadEnvironment(loadedSchema)
+	if err != nil {
+		return err
+	}
 	envConfig := envResult.Config.Data
 	envVarsForSchema := envResult.Vars
 
+	mergeOpts, err := p.mergeOptions(loadedSchema, immutablePaths)
+	if err != nil {
+		return err
+	}
+
 	// Process sources and merge configurations
-	baseFinalConfig, err := p.processSources(immutablePaths, envConfig)
+	baseFinalConfig, err := p.processSources(mergeOpts, envConfig)
 	if err != nil {
 		return err
 	}
@@ -111,6 +128,20 @@ func (p *Pipeline) Run() error {
 	return nil
 }
 
+// loadEnvironment loads the KONFIGO_KEY_ and KONFIGO_VAR_ variables. Values
+// that the schema, which may be nil, coerces to strings keep their text.
+func (p *Pipeline) loadEnvironment(s *schema.Schema) (*config.LoadResult, error) {
+	env := config.NewEnvironment().CaseSensitive(p.Config.CaseSensitive)
+	if s != nil {
+		env.KeepStrings(coerce.StringPaths(s.Coerce))
+	}
+	result, err := env.Load()
+	if err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeParsing, "invalid environment configuration", err)
+	}
+	return result, nil
+}
+
 // loadSchemaAndImmutablePaths loads the schema file and extracts immutable paths
 func (p *Pipeline) loadSchemaAndImmutablePaths() (*schema.Schema, map[string]struct{}, error) {
 	var loadedSchema *schema.Schema
@@ -132,32 +163,74 @@ func (p *Pipeline) loadSchemaAndImmutablePaths() (*schema.Schema, map[string]str
 	return loadedSchema, immutablePaths, nil
 }
 
-// loadVariablesFile loads the variables file and extracts forEach directive
+// mergeOptions builds the merge options from the CLI flags and, when a schema
+// is loaded, its merge rules.
+func (p *Pipeline) mergeOptions(loadedSchema *schema.Schema, immutablePaths map[string]struct{}) (merger.Options, error) {
+	opts := merger.Options{
+		CaseSensitive:  p.Config.CaseSensitive,
+		ImmutablePaths: immutablePaths,
+		MergeArrays:    p.Config.MergeArrays,
+		NullDeletes:    p.Config.NullDeletes,
+	}
+	if loadedSchema != nil {
+		if err := merger.ValidateRules(loadedSchema.Merge); err != nil {
+			return opts, errors.WrapError(errors.ErrorTypeSchemaLoad, "invalid merge rules", err).WithContext("file", p.Config.SchemaFile)
+		}
+		opts.Rules = loadedSchema.Merge
+
+		if err := merger.ValidatePolicies(loadedSchema.Overrides); err != nil {
+			return opts, errors.WrapError(errors.ErrorTypeSchemaLoad, "invalid override policies", err).WithContext("file", p.Config.SchemaFile)
+		}
+		opts.Policies = loadedSchema.Overrides
+	}
+	return opts, nil
+}
+
+// loadVariablesFile loads the -V files in order and extracts the forEach directive.
+// Later files override the variables of earlier ones (maps are merged deeply),
+// and a forEach directive in a later file replaces an earlier one.
 func (p *Pipeline) loadVariablesFile() (map[string]interface{}, *schema.KonfigoForEach, error) {
 	var varsFromFileGlobal map[string]interface{}
 	var forEachDirective *schema.KonfigoForEach
+	p.varsOrigins = make(map[string]string)
 
-	if p.Config.VarsFile != "" {
-		logger.Log("Loading variables from %s", p.Config.VarsFile)
-		content, err := reader.ReadFile(p.Config.VarsFile)
+	for _, varsFile := range p.Config.VarsFiles {
+		logger.Log("Loading variables from %s", varsFile)
+		content, err := reader.ReadFile(varsFile)
 		if err != nil {
-			return nil, nil, errors.WrapError(errors.ErrorTypeFileRead, "failed to read vars file", err).WithContext("file", p.Config.VarsFile)
+			return nil, nil, errors.WrapError(errors.ErrorTypeFileRead, "failed to read vars file", err).WithContext("file", varsFile)
 		}
-		rawVarsFromFile, err := parser.Parse(p.Config.VarsFile, content, "")
+		rawVarsFromFile, err := parser.Parse(varsFile, content, "")
 		if err != nil {
-			return nil, nil, errors.WrapError(errors.ErrorTypeParsing, "failed to parse vars file", err).WithContext("file", p.Config.VarsFile)
+			return nil, nil, errors.WrapError(errors.ErrorTypeParsing, "failed to parse vars file", err).WithContext("file", varsFile)
 		}
 
 		// Separate forEach from other global vars using config package
-		forEachDirective, varsFromFileGlobal, err = config.ExtractForEachFromVars(rawVarsFromFile)
+		fileForEach, fileVars, err := config.ExtractForEachFromVars(rawVarsFromFile)
 		if err != nil {
-			return nil, nil, errors.WrapError(errors.ErrorTypeSchemaProcess, "failed to extract forEach directive", err)
+			return nil, nil, errors.WrapError(errors.ErrorTypeSchemaProcess, "failed to extract forEach directive", err).WithContext("file", varsFile)
 		}
-		if forEachDirective != nil {
-			forEachDirective.GlobalVars = varsFromFileGlobal // Store global vars for resolver
+		if fileForEach != nil {
+			if forEachDirective != nil {
+				logger.Debug("forEach directive in %s replaces the one in %s", varsFile, forEachDirective.SourceFile)
+			}
+			fileForEach.SourceFile = varsFile
+			forEachDirective = fileForEach
+		}
+
+		if varsFromFileGlobal == nil {
+			varsFromFileGlobal = make(map[string]interface{})
+		}
+		merger.Merge(varsFromFileGlobal, fileVars, true, nil, false)
+		for name := range fileVars {
+			p.varsOrigins[name] = varsFile
 		}
 	}
 
+	if forEachDirective != nil {
+		forEachDirective.GlobalVars = varsFromFileGlobal // Store global vars for resolver
+	}
+
 	return varsFromFileGlobal, forEachDirective, nil
 }
 
@@ -166,16 +239,38 @@ func (p *Pipeline) processBasicVariableSubstitution(baseConfig map[string]interf
 	logger.Debug("No schema provided. Performing basic variable substitution from environment and -V file if present.")
 
 	// Create a resolver with available variable sources
-	resolver, err := variables.NewResolver(envVarsForSchema, varsFromFileGlobal, []variables.Definition{}, baseConfig)
+	resolver, err := variables.NewResolver(envVarsForSchema, varsFromFileGlobal, []variables.Definition{}, baseConfig, p.paths())
 	if err != nil {
 		return nil, errors.WrapError(errors.ErrorTypeVarResolution, "failed to create variable resolver without schema", err)
 	}
 
-	return variables.Substitute(baseConfig, resolver), nil
+	substituted, err := variables.Substitute(baseConfig, resolver, p.Config.StrictVars, p.paths())
+	if err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeVarSubstitute, "variable substitution failed", err)
+	}
+	return substituted, nil
+}
+
+// paths returns the path lookup that matches keys as selected with -c.
+func (p *Pipeline) paths() util.Paths {
+	return util.Paths{CaseSensitive: p.Config.CaseSensitive}
+}
+
+// processOptions builds the schema processing options from the CLI configuration.
+func (p *Pipeline) processOptions() schema.Options {
+	return schema.Options{
+		StrictVars:    p.Config.StrictVars,
+		Provenance:    p.provenance,
+		CaseSensitive: p.Config.CaseSensitive,
+	}
 }
 
 // generateOutputs handles output generation for single processing mode
 func (p *Pipeline) generateOutputs(finalConfig map[string]interface{}) error {
+	if p.Config.TemplateFile != "" {
+		return p.renderTemplateOutput(finalConfig)
+	}
+
 	targets := writer.DetermineOutputTargets(p.Config.OutputFile, p.Config.OutputJSON, p.Config.OutputYAML, p.Config.OutputTOML, p.Config.OutputENV)
 
 	for i, target := range targets {
@@ -199,6 +294,30 @@ func (p *Pipeline) generateOutputs(finalConfig map[string]interface{}) error {
 	return nil
 }
 
+// renderTemplateOutput renders the --template file against the final config
+// and writes the result to the -of file or stdout.
+func (p *Pipeline) renderTemplateOutput(finalConfig map[string]interface{}) error {
+	logger.Log("Rendering template %s", p.Config.TemplateFile)
+	tmpl, err := render.ParseFile(p.Config.TemplateFile)
+	if err != nil {
+		return errors.WrapError(errors.ErrorTypeTemplate, "failed to load template", err).WithContext("file", p.Config.TemplateFile)
+	}
+
+	outputBytes, err := tmpl.Execute(finalConfig)
+	if err != nil {
+		return errors.WrapError(errors.ErrorTypeTemplate, "failed to render template", err).WithContext("file", p.Config.TemplateFile)
+	}
+
+	if p.Config.OutputFile == "" {
+		return writer.WriteToStdout(outputBytes)
+	}
+	logger.Log("Writing rendered template to %s", p.Config.OutputFile)
+	if err := writer.WriteFile(p.Config.OutputFile, outputBytes); err != nil {
+		return errors.WrapError(errors.ErrorTypeFileWrite, "error writing to file", err).WithContext("file", p.Config.OutputFile)
+	}
+	return nil
+}
+
 // parseResult holds the result of parsing a single file
 type parseResult struct {
 	FilePath string
@@ -217,7 +336,8 @@ type sourceEntry struct {
 }
 
 // processSources handles discovery, parsing, and merging of source files
-func (p *Pipeline) processSources(immutablePaths map[string]struct{}, envConfig map[string]interface{}) (map[string]interface{}, error) {
+// using mergeOpts.
+func (p *Pipeline) processSources(mergeOpts merger.Options, envConfig map[string]interface{}) (map[string]interface{}, error) {
 	sourcePaths := p.Config.GetSourcePaths()
 	inputFormatOverride := p.Config.GetInputFormat()
 
@@ -281,6 +401,12 @@ func (p *Pipeline) processSources(immutablePaths map[string]struct{}, envConfig
 		resultsByIndex[res.Index] = res
 	}
 
+	var report *overrideReport
+	if p.Config.OverrideReport {
+		report = newOverrideReport()
+	}
+	shapes := newShapeTracker(p.Config.ShapeConflicts, p.Config.StrictShapes, p.Config.CaseSensitive)
+
 	// Merge everything in original source order
 	finalConfig := make(map[string]interface{})
 	var parseErrors []string
@@ -293,14 +419,30 @@ func (p *Pipeline) processSources(immutablePaths map[string]struct{}, envConfig
 			if err != nil {
 				return nil, errors.WrapError(errors.ErrorTypeStdinRead, "failed to parse stdin", err)
 			}
-			merger.Merge(finalConfig, data, p.Config.CaseSensitive, immutablePaths, p.Config.MergeArrays)
+			data = normalizeKeys(data, p.Config.KeyCase, "stdin")
+			opts := mergeOpts
+			if p.provenance != nil {
+				opts.Observer = p.provenance.Source(provenance.Origin{Kind: provenance.KindStdin},
+					normalizePositions(parser.Positions("stdin", se.Data, inputFormatOverride), p.Config.KeyCase))
+			}
+			if err := mergeSource(finalConfig, data, opts, "stdin", report, shapes); err != nil {
+				return nil, err
+			}
 		} else {
 			res := resultsByIndex[se.Index]
 			if res.Err != nil {
 				parseErrors = append(parseErrors, fmt.Sprintf("%s: %v", res.FilePath, res.Err))
 				continue
 			}
-			merger.Merge(finalConfig, res.Data, p.Config.CaseSensitive, immutablePaths, p.Config.MergeArrays)
+			data := normalizeKeys(res.Data, p.Config.KeyCase, res.FilePath)
+			opts := mergeOpts
+			if p.provenance != nil {
+				opts.Observer = p.provenance.Source(provenance.Origin{Kind: provenance.KindFile, Name: res.FilePath},
+					normalizePositions(sourcePositions(res.FilePath, inputFormatOverride), p.Config.KeyCase))
+			}
+			if err := mergeSource(finalConfig, data, opts, res.FilePath, report, shapes); err != nil {
+				return nil, err
+			}
 		}
 	}
 	if len(parseErrors) > 0 {
@@ -310,12 +452,39 @@ func (p *Pipeline) processSources(immutablePaths map[string]struct{}, envConfig
 
 	if len(envConfig) > 0 {
 		logger.Log("Merging %d configuration key(s) from environment variables...", len(envConfig))
-		merger.Merge(finalConfig, envConfig, p.Config.CaseSensitive, immutablePaths, p.Config.MergeArrays)
+		envConfig = normalizeKeys(envConfig, p.Config.KeyCase, "environment (KONFIGO_KEY_*)")
+		opts := mergeOpts
+		if p.provenance != nil {
+			opts.Observer = p.provenance.Source(provenance.Origin{Kind: provenance.KindEnv}, nil)
+		}
+		if err := mergeSource(finalConfig, envConfig, opts, "environment (KONFIGO_KEY_*)", report, shapes); err != nil {
+			return nil, err
+		}
+	}
+
+	if report != nil {
+		if err := report.write(os.Stderr); err != nil {
+			return nil, errors.WrapError(errors.ErrorTypeFileWrite, "failed to write override report", err)
+		}
 	}
 
+	// Remove $delete/$replace markers that no merge consumed
+	merger.StripDirectives(finalConfig, mergeOpts.NullDeletes)
+
 	return finalConfig, nil
 }
 
+// sourcePositions returns the line of each key in a source file for provenance.
+// The file is read again; positions are only needed when explaining values.
+func sourcePositions(filePath, formatOverride string) map[string]int {
+	content, err := reader.ReadFile(filePath)
+	if err != nil {
+		logger.Debug("Could not read %s for line information: %v", filePath, err)
+		return nil
+	}
+	return parser.Positions(filePath, content, formatOverride)
+}
+
 // parseFilesParallel parses multiple files in parallel using optimized processing
 func (p *Pipeline) parseFilesParallel(entries []sourceEntry, formatOverride string) []parseResult {
 	if len(entries) == 0 {
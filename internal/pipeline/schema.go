@@ -0,0 +1,68 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"konfigo/internal/cli"
+	"konfigo/internal/errors"
+	"konfigo/internal/schema"
+	"konfigo/internal/writer"
+)
+
+// RunSchema runs the schema command:
+//
+//	schema export --format jsonschema   writes the inputSchema and validate rules
+//	                                    of the -S schema as a JSON Schema document
+//	schema resolve                      prints the -S schema composed with the
+//	                                    schemas it extends and imports
+func (p *Pipeline) RunSchema() error {
+	if len(p.Config.Args) != 1 || (p.Config.Args[0] != "export" && p.Config.Args[0] != "resolve") {
+		return errors.NewError(errors.ErrorTypeCLIValidation, "schema expects 'export' or 'resolve', e.g. konfigo schema resolve -S schema.yaml")
+	}
+	if p.Config.SchemaFile == "" {
+		return errors.NewErrorf(errors.ErrorTypeCLIValidation, "schema %s requires a schema file (-S)", p.Config.Args[0])
+	}
+	if p.Config.Args[0] == "resolve" {
+		return p.resolveSchema()
+	}
+	return p.exportSchema()
+}
+
+// exportSchema writes the JSON Schema document of the -S schema to stdout or
+// the -of file.
+func (p *Pipeline) exportSchema() error {
+	if p.Config.ExportFormat == "" {
+		return errors.NewErrorf(errors.ErrorTypeCLIValidation, "schema export requires --format (%s)", cli.ExportFormatJSONSchema)
+	}
+
+	loadedSchema, err := schema.Load(p.Config.SchemaFile)
+	if err != nil {
+		return err
+	}
+	doc, err := schema.ExportJSONSchema(loadedSchema)
+	if err != nil {
+		return errors.WrapError(errors.ErrorTypeSchemaProcess, "failed to export schema", err).WithContext("file", p.Config.SchemaFile)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return errors.WrapError(errors.ErrorTypeInternal, "failed to marshal exported schema", err)
+	}
+	data = append(data, '\n')
+
+	if p.Config.OutputFile == "" {
+		return writer.WriteToStdout(data)
+	}
+	if err := writer.WriteFile(p.Config.OutputFile, data); err != nil {
+		return errors.WrapError(errors.ErrorTypeFileWrite, "failed to write exported schema", err).WithContext("file", p.Config.OutputFile)
+	}
+	return nil
+}
+
+// resolveSchema prints the flattened -S schema in the output format selected
+// with -oX or -of, YAML by default.
+func (p *Pipeline) resolveSchema() error {
+	resolved, err := schema.Resolve(p.Config.SchemaFile)
+	if err != nil {
+		return err
+	}
+	return p.generateOutputs(resolved)
+}
@@ -0,0 +1,135 @@
+package pipeline
+
+import (
+	"fmt"
+	"konfigo/internal/errors"
+	"konfigo/internal/logger"
+	"konfigo/internal/merger"
+	"konfigo/internal/util"
+	"strings"
+)
+
+// shapeTracker remembers which source set each value while sources are
+// merged, so that shape conflicts name both sources.
+type shapeTracker struct {
+	// level is "warn" or "error", the log level of conflicts.
+	level string
+	// strict fails the merge on the first conflict.
+	strict        bool
+	caseSensitive bool
+	origins       map[string]shapeOrigin
+	seq           int
+}
+
+// shapeOrigin is the source that set a value; seq orders the changes.
+type shapeOrigin struct {
+	source string
+	seq    int
+}
+
+// newShapeTracker creates a tracker from the CLI flags. It returns nil when
+// conflicts would only be logged as warnings that quiet mode suppresses.
+func newShapeTracker(level string, strict, caseSensitive bool) *shapeTracker {
+	if !strict && level != "error" && logger.IsQuiet() {
+		return nil
+	}
+	return &shapeTracker{
+		level:         level,
+		strict:        strict,
+		caseSensitive: caseSensitive,
+		origins:       make(map[string]shapeOrigin),
+	}
+}
+
+// observer returns a merger.Observer that records the values source sets
+// and passes every change on to next, if set.
+func (t *shapeTracker) observer(source string, next merger.Observer) merger.Observer {
+	return &shapeObserver{tracker: t, source: source, next: next}
+}
+
+// record notes that source set, or deleted when source is empty, the value at path.
+func (t *shapeTracker) record(path, source string) {
+	t.seq++
+	t.origins[t.key(path)] = shapeOrigin{source: source, seq: t.seq}
+}
+
+// originOf returns the source of the value at path: the source of the
+// latest change to path or one of its ancestors, which replaced everything
+// below it.
+func (t *shapeTracker) originOf(path string) string {
+	var latest shapeOrigin
+	for p, ok := path, true; ok; p, ok = util.ParentPath(p) {
+		if o, found := t.origins[t.key(p)]; found && o.seq > latest.seq {
+			latest = o
+		}
+	}
+	return latest.source
+}
+
+// key returns the lookup key of path.
+func (t *shapeTracker) key(path string) string {
+	if t.caseSensitive {
+		return path
+	}
+	return strings.ToLower(path)
+}
+
+// conflictHandler returns the merger.Options.OnShapeConflict callback for source.
+func (t *shapeTracker) conflictHandler(source string) func(merger.ShapeConflict) error {
+	return func(c merger.ShapeConflict) error {
+		previous := t.originOf(c.Path)
+		if previous == "" {
+			previous = "an earlier source"
+		}
+		message := fmt.Sprintf("shape conflict at '%s': %s sets %s, %s replaces it with %s",
+			c.Path, previous, describeShape(c.Previous), source, describeShape(c.Value))
+		switch {
+		case t.strict:
+			return errors.NewError(errors.ErrorTypeConfigMerge, message).
+				WithContext("path", c.Path).WithContext("source", source).WithContext("previousSource", previous)
+		case t.level == "error":
+			logger.Error("%s", message)
+		default:
+			logger.Warn("%s", message)
+		}
+		return nil
+	}
+}
+
+// describeShape describes a value for a shape conflict message, e.g. `a string ("off")`.
+func describeShape(v interface{}) string {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		return "a map"
+	case []interface{}:
+		return "an array"
+	case string:
+		return fmt.Sprintf("a string (%q)", val)
+	case bool:
+		return fmt.Sprintf("a boolean (%v)", val)
+	case int, int64, uint64, float64:
+		return fmt.Sprintf("a number (%v)", val)
+	}
+	return fmt.Sprintf("a value (%v)", v)
+}
+
+// shapeObserver records the changes of one source for a shapeTracker.
+type shapeObserver struct {
+	tracker *shapeTracker
+	source  string
+	next    merger.Observer
+}
+
+func (o *shapeObserver) Set(path string, value interface{}) {
+	o.tracker.record(path, o.source)
+	if o.next != nil {
+		o.next.Set(path, value)
+	}
+}
+
+func (o *shapeObserver) Delete(path string) {
+	o.tracker.record(path, "")
+	if o.next != nil {
+		o.next.Delete(path)
+	}
+}
@@ -11,7 +11,7 @@ func (p *Pipeline) processSingle(baseConfig map[string]interface{}, loadedSchema
 		varsToProcess = make(map[string]interface{}) // Ensure not nil for schema.Process
 	}
 
-	processedConfig, err := schema.Process(baseConfig, loadedSchema, varsToProcess, envVarsForSchema)
+	processedConfig, err := schema.ProcessWithOptions(baseConfig, loadedSchema, varsToProcess, envVarsForSchema, p.processOptions())
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,211 @@
+package pipeline
+
+import (
+	"fmt"
+	"konfigo/internal/cli"
+	"konfigo/internal/errors"
+	"konfigo/internal/features/variables"
+	"konfigo/internal/logger"
+	"konfigo/internal/schema"
+	"konfigo/internal/writer"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RunSubst runs the subst command: it streams arbitrary text files through the
+// variable resolver, envsubst-style, without parsing them as configuration.
+//
+// Variables are resolved with the usual precedence (KONFIGO_VAR_ environment
+// variables, then -V, then the schema's vars section). When sources are given
+// with -s, the merged configuration backs fromPath variables and ${.path}
+// references. Files are written to stdout, rewritten in place (--in-place) or
+// written into --out-dir. Unresolved placeholders are reported on stderr; they
+// fail the command when they are required markers or when --strict-vars is set.
+func (p *Pipeline) RunSubst() error {
+	files := p.Config.Args
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	for _, file := range files {
+		if file == "-" && (p.Config.InPlace || p.Config.OutputDir != "") {
+			return errors.NewError(errors.ErrorTypeCLIValidation, "stdin cannot be combined with --in-place or --out-dir")
+		}
+	}
+
+	resolver, err := p.commandResolver(cli.CommandSubst)
+	if err != nil {
+		return err
+	}
+	if err := resolver.Validate(); err != nil {
+		return errors.WrapError(errors.ErrorTypeVarResolution, "variable validation failed", err)
+	}
+
+	var unresolved []variables.Unresolved
+	for _, file := range files {
+		missing, err := p.substFile(file, resolver)
+		if err != nil {
+			return err
+		}
+		unresolved = append(unresolved, missing...)
+	}
+
+	return p.reportUnresolved(unresolved)
+}
+
+// commandResolver builds the variable resolver for subcommands that work with
+// variables outside of the regular pipeline (subst and vars).
+func (p *Pipeline) commandResolver(command string) (*variables.DefaultResolver, error) {
+	var loadedSchema *schema.Schema
+	var schemaVars []variables.Definition
+	if p.Config.SchemaFile != "" {
+		logger.Log("Loading schema vars from %s", p.Config.SchemaFile)
+		var err error
+		loadedSchema, err = schema.Load(p.Config.SchemaFile)
+		if err != nil {
+			return nil, err
+		}
+		schemaVars = loadedSchema.Vars
+	}
+
+	envResult, err := p.loadEnvironment(loadedSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	baseConfig := map[string]interface{}{}
+	if p.Config.GetSourcePaths() != "" {
+		mergeOpts, err := p.mergeOptions(loadedSchema, nil)
+		if err != nil {
+			return nil, err
+		}
+		merged, err := p.processSources(mergeOpts, envResult.Config.Data)
+		if err != nil {
+			return nil, err
+		}
+		baseConfig = merged
+	}
+
+	varsFromFile, forEachDirective, err := p.loadVariablesFile()
+	if err != nil {
+		return nil, err
+	}
+	if forEachDirective != nil {
+		logger.Warn("forEach directive in %s is ignored by the %s command", forEachDirective.SourceFile, command)
+	}
+
+	resolver, err := variables.NewResolver(envResult.Vars, varsFromFile, schemaVars, baseConfig, p.paths())
+	if err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeVarResolution, "failed to create variable resolver", err)
+	}
+	return resolver, nil
+}
+
+// substFile substitutes a single file (or stdin for "-") and writes the result
+// to its destination. A file whose placeholders fail the command is not written.
+func (p *Pipeline) substFile(file string, resolver variables.Resolver) ([]variables.Unresolved, error) {
+	if file == "-" {
+		logger.Debug("Substituting variables from standard input")
+		unresolved, err := variables.SubstituteStream(os.Stdin, os.Stdout, resolver, "<stdin>")
+		if err != nil {
+			return nil, errors.WrapError(errors.ErrorTypeStdinRead, "failed to substitute stdin", err)
+		}
+		return unresolved, nil
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeFileRead, "failed to read file", err).WithContext("file", file)
+	}
+	if info.IsDir() {
+		return nil, errors.NewError(errors.ErrorTypeFileRead, "subst expects files, not directories").WithContext("file", file)
+	}
+
+	in, err := os.Open(file)
+	if err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeFileRead, "failed to open file", err).WithContext("file", file)
+	}
+	defer in.Close()
+
+	target := ""
+	switch {
+	case p.Config.InPlace:
+		target = file
+	case p.Config.OutputDir != "":
+		target = filepath.Join(p.Config.OutputDir, outputRelPath(file))
+	default:
+		logger.Debug("Substituting variables in %s", file)
+		unresolved, err := variables.SubstituteStream(in, os.Stdout, resolver, file)
+		if err != nil {
+			return nil, errors.WrapError(errors.ErrorTypeFileWrite, "failed to substitute file", err).WithContext("file", file)
+		}
+		return unresolved, nil
+	}
+
+	logger.Log("Substituting variables in %s -> %s", file, target)
+	if err := writer.EnsureDirectory(target); err != nil {
+		return nil, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".konfigo-*")
+	if err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeFileWrite, "failed to create temporary file", err).WithContext("file", target)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	unresolved, err := variables.SubstituteStream(in, tmp, resolver, file)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeFileWrite, "failed to substitute file", err).WithContext("file", file)
+	}
+	if p.hasFailures(unresolved) {
+		logger.Warn("Not writing %s: it contains placeholders that could not be resolved", target)
+		return unresolved, nil
+	}
+
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()); err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeFileWrite, "failed to set file permissions", err).WithContext("file", target)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeFileWrite, "failed to write file", err).WithContext("file", target)
+	}
+	return unresolved, nil
+}
+
+// outputRelPath returns the path of file inside --out-dir. Local relative paths
+// keep their directory structure; other paths are reduced to their base name.
+func outputRelPath(file string) string {
+	if filepath.IsLocal(file) {
+		return file
+	}
+	return filepath.Base(file)
+}
+
+// hasFailures reports whether any unresolved placeholder fails the command.
+func (p *Pipeline) hasFailures(unresolved []variables.Unresolved) bool {
+	for _, u := range unresolved {
+		if u.Required || p.Config.StrictVars {
+			return true
+		}
+	}
+	return false
+}
+
+// reportUnresolved prints unresolved placeholders to stderr and returns an
+// error for the ones that fail the command.
+func (p *Pipeline) reportUnresolved(unresolved []variables.Unresolved) error {
+	var failures []string
+	for _, u := range unresolved {
+		if u.Required || p.Config.StrictVars {
+			failures = append(failures, u.String())
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "unresolved placeholder %s\n", u.String())
+	}
+	if len(failures) > 0 {
+		return errors.WrapError(errors.ErrorTypeVarSubstitute, "variable substitution failed",
+			fmt.Errorf("%d unresolved variable placeholder(s): %s", len(failures), strings.Join(failures, "; ")))
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"konfigo/internal/cli"
+	"konfigo/internal/errors"
+	"konfigo/internal/features/variables"
+	"os"
+	"text/tabwriter"
+)
+
+// RunVars runs the vars command: it resolves variables the same way the
+// regular pipeline does and lists each one with its value and the source that
+// provided it. Variables that fail their schema constraints are listed first
+// and then fail the command.
+func (p *Pipeline) RunVars() error {
+	resolver, err := p.commandResolver(cli.CommandVars)
+	if err != nil {
+		return err
+	}
+
+	infos := resolver.Variables()
+	for i, info := range infos {
+		if info.Source.Kind == variables.SourceVarsFile {
+			infos[i].Source.Detail = p.varsOrigins[info.Name]
+		}
+	}
+
+	if p.Config.OutputJSON {
+		data, err := json.MarshalIndent(infos, "", "  ")
+		if err != nil {
+			return errors.WrapError(errors.ErrorTypeInternal, "failed to marshal variables", err)
+		}
+		fmt.Fprintln(os.Stdout, string(data))
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tVALUE\tSOURCE\tDESCRIPTION")
+		for _, info := range infos {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", info.Name, formatVarValue(info.Value), info.Source, info.Description)
+		}
+		if err := w.Flush(); err != nil {
+			return errors.WrapError(errors.ErrorTypeFileWrite, "failed to write variables", err)
+		}
+	}
+
+	if err := resolver.Validate(); err != nil {
+		return errors.WrapError(errors.ErrorTypeVarResolution, "variable validation failed", err)
+	}
+	return nil
+}
+
+// formatVarValue renders a variable value on a single line; maps and lists are
+// shown as JSON.
+func formatVarValue(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		if data, err := json.Marshal(v); err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprintf("%v", v)
+}
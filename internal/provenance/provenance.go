@@ -0,0 +1,302 @@
+// Package provenance records where every leaf value of a configuration came
+// from and which earlier values it overrode.
+//
+// A Tracker keeps a history per dot-separated leaf path. Maps are flattened to
+// their leaves; arrays and empty maps are leaves themselves. Changes are
+// recorded from two directions:
+//   - merges report every write through a Source, which implements
+//     merger.Observer and attaches file and line information, and
+//   - processing steps (generators, transformers, substitution) are recorded by
+//     diffing Flatten snapshots taken before and after the step.
+//
+// Usage:
+//
+//	tracker := provenance.New(caseSensitive)
+//	opts.Observer = tracker.Source(provenance.Origin{Kind: provenance.KindFile, Name: "base.yaml"}, lines)
+//	merger.MergeWithOptions(config, data, opts)
+//	for _, entry := range tracker.History("server.port") { ... }
+package provenance
+
+import (
+	"fmt"
+	"konfigo/internal/util"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Kind identifies what kind of step set a value.
+type Kind string
+
+const (
+	// KindFile is a source file given with -s.
+	KindFile Kind = "file"
+	// KindStdin is configuration read from standard input.
+	KindStdin Kind = "stdin"
+	// KindEnv is a KONFIGO_KEY_ environment variable.
+	KindEnv Kind = "env"
+	// KindDefault is a value of the schema defaults section.
+	KindDefault Kind = "default"
+	// KindCoercion is the conversion of a value by the schema coerce section.
+	KindCoercion Kind = "coercion"
+	// KindGenerator is a schema generator.
+	KindGenerator Kind = "generator"
+	// KindTransformer is a schema transformer.
+	KindTransformer Kind = "transformer"
+	// KindImmutable is the restoration of an immutable path.
+	KindImmutable Kind = "immutable"
+	// KindSubstitution is variable substitution.
+	KindSubstitution Kind = "substitution"
+)
+
+// Origin describes what set a value.
+type Origin struct {
+	Kind Kind `json:"kind"`
+	// Name is the file, environment variable or schema step.
+	Name string `json:"name,omitempty"`
+	// Line is the 1-based line in Name, or 0 when unknown.
+	Line int `json:"line,omitempty"`
+}
+
+// String returns the origin as "file:line", "stdin:line" or "kind name".
+func (o Origin) String() string {
+	switch o.Kind {
+	case KindFile, KindStdin:
+		name := o.Name
+		if name == "" {
+			name = string(o.Kind)
+		}
+		if o.Line > 0 {
+			return fmt.Sprintf("%s:%d", name, o.Line)
+		}
+		return name
+	}
+	if o.Name == "" {
+		return string(o.Kind)
+	}
+	return string(o.Kind) + " " + o.Name
+}
+
+// Entry is one change of a leaf value.
+type Entry struct {
+	Value   interface{} `json:"value,omitempty"`
+	Origin  Origin      `json:"origin"`
+	Deleted bool        `json:"deleted,omitempty"`
+}
+
+// Tracker records the history of every leaf path.
+type Tracker struct {
+	caseSensitive bool
+	history       map[string][]Entry
+	// names keeps the first spelling of each path for display.
+	names map[string]string
+}
+
+// New creates an empty tracker. When caseSensitive is false, paths that differ
+// only in case share one history.
+func New(caseSensitive bool) *Tracker {
+	return &Tracker{
+		caseSensitive: caseSensitive,
+		history:       make(map[string][]Entry),
+		names:         make(map[string]string),
+	}
+}
+
+// key returns the history key of path.
+func (t *Tracker) key(path string) string {
+	if t.caseSensitive {
+		return path
+	}
+	return strings.ToLower(path)
+}
+
+// live reports whether the latest entry of key is a value rather than a deletion.
+func (t *Tracker) live(key string) bool {
+	h := t.history[key]
+	return len(h) > 0 && !h[len(h)-1].Deleted
+}
+
+// Set records value (a leaf or a whole subtree) at path. Leaves that were
+// previously set below or above path and are not part of value are recorded
+// as deleted.
+func (t *Tracker) Set(path string, value interface{}, origin Origin) {
+	t.SetWith(path, value, func(string) Origin { return origin })
+}
+
+// SetWith behaves like Set, but asks originFor for the origin of each leaf.
+func (t *Tracker) SetWith(path string, value interface{}, originFor func(leaf string) Origin) {
+	leaves := make(map[string]interface{})
+	flatten(value, path, leaves)
+
+	kept := make(map[string]bool, len(leaves))
+	for leaf := range leaves {
+		kept[t.key(leaf)] = true
+	}
+	origin := originFor(path)
+	t.deleteBelow(path, kept, origin)
+
+	for leaf, v := range leaves {
+		leafOrigin := originFor(leaf)
+		t.deleteAbove(leaf, leafOrigin)
+		t.record(leaf, Entry{Value: v, Origin: leafOrigin})
+	}
+}
+
+// Delete records that path and every leaf below it were removed.
+func (t *Tracker) Delete(path string, origin Origin) {
+	key := t.key(path)
+	if t.live(key) {
+		t.record(path, Entry{Origin: origin, Deleted: true})
+	}
+	t.deleteBelow(path, nil, origin)
+}
+
+// deleteBelow records a deletion for every live leaf below path that is not in keep.
+func (t *Tracker) deleteBelow(path string, keep map[string]bool, origin Origin) {
+	prefix := t.key(path) + "."
+	for key := range t.history {
+		if strings.HasPrefix(key, prefix) && !keep[key] && t.live(key) {
+			t.history[key] = append(t.history[key], Entry{Origin: origin, Deleted: true})
+		}
+	}
+}
+
+// deleteAbove records a deletion for live leaves that are ancestors of path,
+// which happens when a scalar is replaced by a map.
+func (t *Tracker) deleteAbove(path string, origin Origin) {
+	for ancestor, ok := util.ParentPath(t.key(path)); ok; ancestor, ok = util.ParentPath(ancestor) {
+		if t.live(ancestor) {
+			t.history[ancestor] = append(t.history[ancestor], Entry{Origin: origin, Deleted: true})
+		}
+	}
+}
+
+// record appends an entry to the history of path.
+func (t *Tracker) record(path string, entry Entry) {
+	key := t.key(path)
+	if _, ok := t.names[key]; !ok {
+		t.names[key] = path
+	}
+	t.history[key] = append(t.history[key], entry)
+}
+
+// Record records the differences between two Flatten snapshots as changes made by origin.
+func (t *Tracker) Record(before, after map[string]interface{}, origin Origin) {
+	for path, v := range after {
+		if old, ok := before[path]; !ok || !reflect.DeepEqual(old, v) {
+			t.record(path, Entry{Value: v, Origin: origin})
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok && t.live(t.key(path)) && !filled(path, after) {
+			t.record(path, Entry{Origin: origin, Deleted: true})
+		}
+	}
+}
+
+// filled reports whether the leaf at path, an empty map, has keys in after,
+// so that it is no longer a leaf without being deleted.
+func filled(path string, after map[string]interface{}) bool {
+	for p := range after {
+		if strings.HasPrefix(p, path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// History returns the recorded changes of a leaf path, oldest first.
+func (t *Tracker) History(path string) []Entry {
+	return t.history[t.key(path)]
+}
+
+// Paths returns every recorded leaf path equal to or below path, sorted.
+// An empty path returns all leaves.
+func (t *Tracker) Paths(path string) []string {
+	key := t.key(path)
+	var paths []string
+	for k := range t.history {
+		if path == "" || k == key || strings.HasPrefix(k, key+".") {
+			paths = append(paths, t.names[k])
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Flatten returns the leaf values of config keyed by dot-separated path.
+func Flatten(config map[string]interface{}) map[string]interface{} {
+	leaves := make(map[string]interface{})
+	flatten(config, "", leaves)
+	return leaves
+}
+
+// flatten adds the leaves of v below path to leaves.
+func flatten(v interface{}, path string, leaves map[string]interface{}) {
+	if m, ok := v.(map[string]interface{}); ok {
+		if len(m) == 0 && path != "" {
+			// A copy, so that the leaf keeps its value when keys are added later
+			leaves[path] = map[string]interface{}{}
+		}
+		for k, child := range m {
+			flatten(child, util.AppendKey(path, k), leaves)
+		}
+		return
+	}
+	if path != "" {
+		leaves[path] = leafCopy(v)
+	}
+}
+
+// leafCopy returns a copy of an array leaf, so that the recorded value does
+// not change when later merges modify the elements in place.
+func leafCopy(v interface{}) interface{} {
+	if _, ok := v.([]interface{}); !ok {
+		return v
+	}
+	copied, err := util.DeepCopyValue(v)
+	if err != nil {
+		return v
+	}
+	return copied
+}
+
+// Source records the writes of one merge source. It implements merger.Observer.
+type Source struct {
+	tracker *Tracker
+	origin  Origin
+	lines   map[string]int
+}
+
+// Source returns an observer that records writes with origin. Lines maps the
+// source's paths to line numbers (see parser.Positions); a leaf without a line
+// of its own gets the line of its closest ancestor. For KindEnv origins
+// without a name, each leaf is named after its KONFIGO_KEY_ variable.
+func (t *Tracker) Source(origin Origin, lines map[string]int) *Source {
+	return &Source{tracker: t, origin: origin, lines: lines}
+}
+
+// Set records value at path.
+func (s *Source) Set(path string, value interface{}) {
+	s.tracker.SetWith(path, value, s.originFor)
+}
+
+// Delete records the removal of path.
+func (s *Source) Delete(path string) {
+	s.tracker.Delete(path, s.originFor(path))
+}
+
+// originFor returns the origin of a single path.
+func (s *Source) originFor(path string) Origin {
+	origin := s.origin
+	if origin.Kind == KindEnv && origin.Name == "" {
+		origin.Name = "KONFIGO_KEY_" + path
+	}
+	for p, ok := path, s.lines != nil; ok; p, ok = util.ParentPath(p) {
+		if line, found := s.lines[p]; found {
+			origin.Line = line
+			break
+		}
+	}
+	return origin
+}
@@ -0,0 +1,101 @@
+package provenance
+
+import (
+	"konfigo/internal/merger"
+	"reflect"
+	"testing"
+)
+
+func TestTracker_MergeHistory(t *testing.T) {
+	tracker := New(false)
+	config := map[string]interface{}{}
+
+	merge := func(origin Origin, lines map[string]int, data map[string]interface{}) {
+		merger.MergeWithOptions(config, data, merger.Options{Observer: tracker.Source(origin, lines)})
+	}
+	merge(Origin{Kind: KindFile, Name: "base.yaml"}, map[string]int{"server": 1, "server.port": 3, "debug": 5},
+		map[string]interface{}{
+			"server": map[string]interface{}{"port": 80, "host": "localhost"},
+			"debug":  true,
+		})
+	merge(Origin{Kind: KindFile, Name: "prod.yaml"}, map[string]int{"Server.Port": 2},
+		map[string]interface{}{
+			"Server": map[string]interface{}{"Port": 8080},
+			"debug":  map[string]interface{}{merger.DeleteMarker: true},
+		})
+	merge(Origin{Kind: KindEnv}, nil, map[string]interface{}{"server": map[string]interface{}{"host": "prod.local"}})
+
+	port := tracker.History("server.port")
+	if len(port) != 2 || port[0].Origin.String() != "base.yaml:3" || port[1].Origin.String() != "prod.yaml:2" || port[1].Value != 8080 {
+		t.Errorf("server.port history = %+v", port)
+	}
+	host := tracker.History("server.host")
+	if len(host) != 2 || host[0].Origin.String() != "base.yaml:1" || host[1].Origin.String() != "env KONFIGO_KEY_server.host" {
+		t.Errorf("server.host history = %+v", host)
+	}
+	debug := tracker.History("debug")
+	if len(debug) != 2 || !debug[1].Deleted {
+		t.Errorf("debug history = %+v", debug)
+	}
+	if got, want := tracker.Paths("server"), []string{"server.host", "server.port"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Paths(server) = %v, want %v", got, want)
+	}
+}
+
+func TestTracker_RecordSnapshots(t *testing.T) {
+	tracker := New(true)
+	config := map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": "x"}, "e": map[string]interface{}{}}
+	tracker.Set("", config, Origin{Kind: KindFile, Name: "in.json"})
+
+	before := Flatten(config)
+	config["a"] = 2
+	delete(config, "b")
+	config["d"] = []interface{}{1}
+	config["e"] = map[string]interface{}{"f": true}
+	tracker.Record(before, Flatten(config), Origin{Kind: KindGenerator, Name: "g"})
+
+	if h := tracker.History("a"); len(h) != 2 || h[1].Value != 2 {
+		t.Errorf("a history = %+v", h)
+	}
+	if h := tracker.History("b.c"); len(h) != 2 || !h[1].Deleted {
+		t.Errorf("b.c history = %+v", h)
+	}
+	if h := tracker.History("d"); len(h) != 1 || h[0].Origin.String() != "generator g" {
+		t.Errorf("d history = %+v", h)
+	}
+	if h := tracker.History("e"); len(h) != 1 || h[0].Deleted {
+		t.Errorf("an empty map that gets keys is not deleted, e history = %+v", h)
+	}
+}
+
+func TestTracker_ArrayHistoryKeepsValues(t *testing.T) {
+	tracker := New(true)
+	config := map[string]interface{}{}
+	opts := merger.Options{
+		CaseSensitive: true,
+		Rules:         []merger.Rule{{Path: "containers", Strategy: merger.StrategyKeyedMerge, Key: "name"}},
+	}
+
+	merge := func(name, image string) {
+		opts.Observer = tracker.Source(Origin{Kind: KindFile, Name: name}, nil)
+		data := map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{"name": "app", "image": image}},
+		}
+		if err := merger.MergeWithOptions(config, data, opts); err != nil {
+			t.Fatalf("merge %s: %v", name, err)
+		}
+	}
+	merge("base.yaml", "app:1")
+	merge("over.yaml", "app:2")
+
+	h := tracker.History("containers")
+	if len(h) != 2 {
+		t.Fatalf("containers history = %+v", h)
+	}
+	if got := h[0].Value.([]interface{})[0].(map[string]interface{})["image"]; got != "app:1" {
+		t.Errorf("base.yaml value changed by the later merge: image = %v", got)
+	}
+	if got := h[1].Value.([]interface{})[0].(map[string]interface{})["image"]; got != "app:2" {
+		t.Errorf("over.yaml image = %v, want app:2", got)
+	}
+}
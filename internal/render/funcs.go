@@ -0,0 +1,181 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"konfigo/internal/features/expression"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FuncMap returns the curated function library available to templates.
+//
+// Single-argument functions are shared with the expression language. Functions
+// taking several arguments put the value being operated on last, so they read
+// naturally in pipelines: {{ .name | replace "-" "_" | upper }}.
+func FuncMap() template.FuncMap {
+	funcs := template.FuncMap{
+		// strings
+		"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+		"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"split":      func(sep, s string) []string { return strings.Split(s, sep) },
+		"join":       join,
+		"quote":      func(v interface{}) string { return strconv.Quote(stringify(v)) },
+		"squote":     func(v interface{}) string { return "'" + strings.ReplaceAll(stringify(v), "'", "''") + "'" },
+		"indent":     indent,
+		"nindent":    func(n int, s string) string { return "\n" + indent(n, s) },
+
+		// values
+		"default":  defaultValue,
+		"coalesce": coalesce,
+		"required": required,
+		"min":      func(args ...interface{}) (interface{}, error) { return expression.Call("min", args...) },
+		"max":      func(args ...interface{}) (interface{}, error) { return expression.Call("max", args...) },
+
+		// serialization
+		"toJson":       toJSON,
+		"toPrettyJson": toPrettyJSON,
+		"toYaml":       toYAML,
+	}
+
+	for _, name := range []string{
+		"upper", "lower", "trim",
+		"base64encode", "base64decode", "urlencode",
+		"md5", "sha1", "sha256", "sha512",
+		"first", "last", "sort", "unique", "keys",
+		"string", "int", "float", "bool",
+		"abs", "floor", "ceil", "round",
+	} {
+		funcs[name] = expressionFunc(name)
+	}
+	return funcs
+}
+
+// expressionFunc adapts a single-argument expression builtin for templates.
+func expressionFunc(name string) func(v interface{}) (interface{}, error) {
+	return func(v interface{}) (interface{}, error) {
+		return expression.Call(name, v)
+	}
+}
+
+// stringify renders a value for string functions; nil becomes an empty string.
+func stringify(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// join joins the elements of a list with sep.
+func join(sep string, list interface{}) (string, error) {
+	switch l := list.(type) {
+	case []string:
+		return strings.Join(l, sep), nil
+	case []interface{}:
+		parts := make([]string, len(l))
+		for i, item := range l {
+			parts[i] = stringify(item)
+		}
+		return strings.Join(parts, sep), nil
+	}
+	return "", fmt.Errorf("join: expected a list, got %T", list)
+}
+
+// indent prefixes every non-empty line of s with n spaces.
+func indent(n int, s string) string {
+	pad := strings.Repeat(" ", n)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = pad + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isEmpty reports whether v is nil, false, zero or an empty string or collection.
+func isEmpty(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case string:
+		return x == ""
+	case bool:
+		return !x
+	case int:
+		return x == 0
+	case int64:
+		return x == 0
+	case float64:
+		return x == 0
+	case []interface{}:
+		return len(x) == 0
+	case map[string]interface{}:
+		return len(x) == 0
+	}
+	return false
+}
+
+// defaultValue returns v, or def when v is empty: {{ .port | default 8080 }}.
+// Empty means null or a zero value; a key that does not exist fails the
+// lookup before default runs, so optional keys are read with index:
+// {{ index . "port" | default 8080 }}.
+func defaultValue(def interface{}, v ...interface{}) interface{} {
+	if len(v) == 0 || isEmpty(v[0]) {
+		return def
+	}
+	return v[0]
+}
+
+// coalesce returns the first non-empty argument.
+func coalesce(args ...interface{}) interface{} {
+	for _, a := range args {
+		if !isEmpty(a) {
+			return a
+		}
+	}
+	return nil
+}
+
+// required fails rendering with msg when v is empty. Like default, it needs
+// index to check a key that may not exist.
+func required(msg string, v interface{}) (interface{}, error) {
+	if isEmpty(v) {
+		return nil, fmt.Errorf("%s", msg)
+	}
+	return v, nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func toPrettyJSON(v interface{}) (string, error) {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func toYAML(v interface{}) (string, error) {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(b), "\n"), nil
+}
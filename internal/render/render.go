@@ -0,0 +1,67 @@
+// Package render renders Go text/template files against a processed configuration.
+//
+// Templates are executed with the final configuration map as their data (.),
+// so a value is reached with {{ .database.host }}. Missing keys are errors
+// rather than "<no value>", and templates only have access to a curated
+// function library (see FuncMap): there is no access to the environment or
+// the filesystem.
+//
+// Usage:
+//
+//	tmpl, err := render.Parse("nginx.conf.tmpl", content)
+//	if err != nil {
+//	    return err
+//	}
+//	output, err := tmpl.Execute(config)
+package render
+
+import (
+	"bytes"
+	"konfigo/internal/reader"
+	"path/filepath"
+	"text/template"
+)
+
+// Template is a parsed template ready to be executed against configurations.
+type Template struct {
+	tmpl *template.Template
+}
+
+// Parse parses template content. name is used in error messages and is
+// usually the base name of the template file.
+func Parse(name string, content []byte) (*Template, error) {
+	tmpl, err := template.New(name).
+		Option("missingkey=error").
+		Funcs(FuncMap()).
+		Parse(string(content))
+	if err != nil {
+		return nil, err
+	}
+	return &Template{tmpl: tmpl}, nil
+}
+
+// ParseFile reads and parses a template file.
+func ParseFile(path string) (*Template, error) {
+	content, err := reader.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(filepath.Base(path), content)
+}
+
+// Name returns the template name.
+func (t *Template) Name() string {
+	return t.tmpl.Name()
+}
+
+// Execute renders the template with config as its data.
+func (t *Template) Execute(config map[string]interface{}) ([]byte, error) {
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	var buf bytes.Buffer
+	if err := t.tmpl.Execute(&buf, config); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,107 @@
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExecute_RendersConfig(t *testing.T) {
+	config := map[string]interface{}{
+		"server": map[string]interface{}{
+			"name":  "api-gateway",
+			"port":  8080,
+			"hosts": []interface{}{"a.local", "b.local"},
+		},
+		"tls": false,
+	}
+	src := `server {
+    listen {{ .server.port }};
+    server_name {{ .server.hosts | join " " }};
+    # {{ .server.name | replace "-" "_" | upper }}
+{{- if .tls }}
+    ssl on;
+{{- end }}
+    keepalive {{ .keepalive | default 65 }};
+}
+`
+	tmpl, err := Parse("nginx.conf.tmpl", []byte(src))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	// default covers null values; a missing key would be an error.
+	config["keepalive"] = nil
+	out, err := tmpl.Execute(config)
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+
+	want := `server {
+    listen 8080;
+    server_name a.local b.local;
+    # API_GATEWAY
+    keepalive 65;
+}
+`
+	if string(out) != want {
+		t.Errorf("Execute output:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestExecute_MissingKeyIsError(t *testing.T) {
+	tmpl, err := Parse("unit.tmpl", []byte("ExecStart={{ .service.binary }}\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	_, err = tmpl.Execute(map[string]interface{}{"service": map[string]interface{}{}})
+	if err == nil {
+		t.Fatal("expected missing key error")
+	}
+	if !strings.Contains(err.Error(), "unit.tmpl:1") || !strings.Contains(err.Error(), `"binary"`) {
+		t.Errorf("error should name the template position and key, got: %v", err)
+	}
+}
+
+func TestFuncMap_Serialization(t *testing.T) {
+	tmpl, err := Parse("t", []byte("{{ .labels | toJson }}\nlabels:\n{{ .labels | toYaml | indent 2 }}\n{{ required \"name is required\" .name }}"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	out, err := tmpl.Execute(map[string]interface{}{
+		"labels": map[string]interface{}{"app": "web", "tier": "front"},
+		"name":   "web",
+	})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	want := "{\"app\":\"web\",\"tier\":\"front\"}\nlabels:\n  app: web\n  tier: front\nweb"
+	if string(out) != want {
+		t.Errorf("Execute output = %q, want %q", out, want)
+	}
+
+	if _, err := tmpl.Execute(map[string]interface{}{"labels": map[string]interface{}{}, "name": ""}); err == nil || !strings.Contains(err.Error(), "name is required") {
+		t.Errorf("expected required error, got %v", err)
+	}
+}
+
+func TestFuncMap_DefaultWithIndexForMissingKeys(t *testing.T) {
+	tmpl, err := Parse("t", []byte(`{{ index .server "keepalive" | default 65 }} {{ .server.port | default 80 }}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	out, err := tmpl.Execute(map[string]interface{}{"server": map[string]interface{}{"port": 0}})
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if string(out) != "65 80" {
+		t.Errorf("Execute = %q, want %q", out, "65 80")
+	}
+
+	tmpl, err = Parse("t", []byte(`{{ index .server "cert" | required "server.cert is required" }}`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	_, err = tmpl.Execute(map[string]interface{}{"server": map[string]interface{}{}})
+	if err == nil || !strings.Contains(err.Error(), "server.cert is required") {
+		t.Errorf("expected the required message, got: %v", err)
+	}
+}
@@ -0,0 +1,324 @@
+package schema
+
+import (
+	"fmt"
+	"konfigo/internal/errors"
+	"konfigo/internal/features/transformer"
+	"konfigo/internal/logger"
+	"konfigo/internal/merger"
+	"path/filepath"
+	"strings"
+)
+
+// Schemas are composed from other schema files before they are decoded:
+//
+//	extends: ../base.schema.yaml      # or a list, applied in order
+//	imports:
+//	  - ./fragments/tls-validation.yaml
+//
+// The parents named by extends come first, then the imports in order, then
+// the schema itself; later files override earlier ones. List sections are
+// merged entry by entry:
+//
+//	vars                by name
+//	generators          by targetPath
+//	transform           by type and path (or from, for renameKey)
+//	validate            by path
+//	merge, overrides    by path
+//	defaults, coerce    by path
+//	immutable           union
+//	when                by section
+//
+// Entries with the key of inherited entries take the place of all of them;
+// other entries are appended. An entry with "$delete: true" removes the
+// inherited entries with its key instead. Any other section, such as
+// inputSchema, replaces the inherited value.
+
+// listKeys maps the list sections of a schema to the function that returns
+// the identity of their entries.
+var listKeys = map[string]func(map[string]interface{}) string{
+	"vars":       field("name"),
+	"generators": field("targetPath"),
+	"transform":  transformKey,
+	"validate":   field("path"),
+	"merge":      field("path"),
+	"overrides":  field("path"),
+	"defaults":   field("path"),
+	"coerce":     field("path"),
+}
+
+// field returns a function that reads the string field name of an entry.
+func field(name string) func(map[string]interface{}) string {
+	return func(entry map[string]interface{}) string {
+		s, _ := entry[name].(string)
+		return s
+	}
+}
+
+// transformKey identifies a transform by its type and the path it changes.
+func transformKey(entry map[string]interface{}) string {
+	t, _ := entry["type"].(string)
+	path, _ := entry["path"].(string)
+	if t == transformer.RenameKeyType || path == "" {
+		path, _ = entry["from"].(string)
+	}
+	if path == "" {
+		return ""
+	}
+	return t + ":" + path
+}
+
+// composer composes a schema file with the files it extends or imports.
+type composer struct {
+	// rootDir is the directory of the schema being loaded; relative
+	// inputSchema and outputSchema paths of other files are rewritten to be
+	// relative to it.
+	rootDir string
+	// fieldErrors holds the fields of every composed file that do not fit
+	// the schema structure.
+	fieldErrors []fieldError
+	// fieldWarnings holds the deprecated fields of every composed file,
+	// which are dropped.
+	fieldWarnings []fieldError
+}
+
+// warn logs the field warnings.
+func (c *composer) warn() {
+	for _, w := range c.fieldWarnings {
+		logger.Warn("%s", w.String())
+	}
+}
+
+// err returns a SchemaLoad error listing the field errors, or nil.
+func (c *composer) err() error {
+	switch len(c.fieldErrors) {
+	case 0:
+		return nil
+	case 1:
+		return errors.NewError(errors.ErrorTypeSchemaLoad, c.fieldErrors[0].String())
+	}
+	lines := make([]string, len(c.fieldErrors))
+	for i, e := range c.fieldErrors {
+		lines[i] = e.String()
+	}
+	return errors.NewErrorf(errors.ErrorTypeSchemaLoad, "%d invalid schema fields:\n  %s", len(lines), strings.Join(lines, "\n  "))
+}
+
+// compose loads the schema file at path and everything it extends or
+// imports, and returns the merged document. chain holds the files being
+// composed, to detect cycles. When s is set, the absolute paths of the files
+// path extends and imports are recorded in it.
+func (c *composer) compose(path string, chain []string, s *Schema) (map[string]interface{}, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeSchemaLoad, "failed to resolve schema file path", err).WithContext("file", path)
+	}
+	for i, p := range chain {
+		if p == absPath {
+			cycle := append(append([]string{}, chain[i:]...), absPath)
+			return nil, errors.NewErrorf(errors.ErrorTypeSchemaLoad, "schema composition cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+	chain = append(chain, absPath)
+
+	data, err := readSchemaFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c.fieldWarnings = append(c.fieldWarnings, dropDeprecated(path, data)...)
+	c.fieldErrors = append(c.fieldErrors, checkFields(path, data)...)
+	dir := filepath.Dir(absPath)
+	if c.rootDir == "" {
+		c.rootDir = dir
+	}
+	if dir != c.rootDir {
+		rebaseRefs(data, dir, c.rootDir)
+	}
+
+	var parents []string
+	for _, key := range []string{"extends", "imports"} {
+		paths, err := pathList(data[key])
+		if err != nil {
+			return nil, errors.WrapError(errors.ErrorTypeSchemaLoad, "invalid '"+key+"'", err).WithContext("file", path)
+		}
+		for i, p := range paths {
+			if !filepath.IsAbs(p) {
+				paths[i] = filepath.Join(dir, p)
+			}
+		}
+		if s != nil && key == "extends" {
+			s.Extends = paths
+		} else if s != nil {
+			s.Imports = paths
+		}
+		parents = append(parents, paths...)
+		delete(data, key)
+	}
+	result := map[string]interface{}{}
+	for _, parent := range parents {
+		logger.Debug("Schema %s includes %s", path, parent)
+		parentData, err := c.compose(parent, chain, nil)
+		if err != nil {
+			return nil, err
+		}
+		result = mergeSchemaMaps(result, parentData, parent)
+	}
+	return mergeSchemaMaps(result, data, path), nil
+}
+
+// pathList accepts a single path or a list of paths.
+func pathList(v interface{}) ([]string, error) {
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{val}, nil
+	case []interface{}:
+		paths := make([]string, len(val))
+		for i, item := range val {
+			s, ok := item.(string)
+			if !ok || s == "" {
+				return nil, fmt.Errorf("entry %d must be a path", i)
+			}
+			paths[i] = s
+		}
+		return paths, nil
+	}
+	return nil, fmt.Errorf("must be a path or a list of paths, got %T", v)
+}
+
+// rebaseRefs rewrites the relative inputSchema and outputSchema paths of a
+// schema in dir to be relative to rootDir.
+func rebaseRefs(data map[string]interface{}, dir, rootDir string) {
+	for _, key := range []string{"inputSchema", "outputSchema"} {
+		ref, ok := data[key].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		p, ok := ref["path"].(string)
+		if !ok || p == "" || filepath.IsAbs(p) {
+			continue
+		}
+		abs := filepath.Join(dir, p)
+		if rel, err := filepath.Rel(rootDir, abs); err == nil {
+			ref["path"] = rel
+		} else {
+			ref["path"] = abs
+		}
+	}
+}
+
+// mergeSchemaMaps merges the schema document over, read from source, into base.
+func mergeSchemaMaps(base, over map[string]interface{}, source string) map[string]interface{} {
+	for key, value := range over {
+		switch {
+		case key == "immutable":
+			base[key] = unionList(base[key], value)
+		case key == "when":
+			base[key] = mergeSections(base[key], value)
+		case listKeys[key] != nil:
+			base[key] = mergeList(base[key], value, listKeys[key], key, source)
+		default:
+			base[key] = value
+		}
+	}
+	return base
+}
+
+// mergeSections merges the section conditions of over into base; a section
+// named in over takes its condition from over.
+func mergeSections(base, over interface{}) interface{} {
+	baseMap, _ := base.(map[string]interface{})
+	overMap, ok := over.(map[string]interface{})
+	if !ok || baseMap == nil {
+		return over
+	}
+	for section, cond := range overMap {
+		baseMap[section] = cond
+	}
+	return baseMap
+}
+
+// mergeList merges the entries of over into base by their identity. The
+// entries of over with the identity of inherited entries take the place of
+// all of them; the others are appended.
+func mergeList(base, over interface{}, identity func(map[string]interface{}) string, section, source string) []interface{} {
+	baseList, _ := base.([]interface{})
+	overList, ok := over.([]interface{})
+	if !ok {
+		return baseList
+	}
+
+	inherited := make(map[string]bool, len(baseList))
+	for _, entry := range baseList {
+		if id := entryID(entry, identity); id != "" {
+			inherited[id] = true
+		}
+	}
+	replaced := make(map[string][]interface{})
+	deleted := make(map[string]bool)
+	var appended []interface{}
+	for _, entry := range overList {
+		id := entryID(entry, identity)
+		switch {
+		case isDeleteEntry(entry):
+			if inherited[id] {
+				logger.Debug("Schema %s removes %s entry '%s'", source, section, id)
+				deleted[id] = true
+			}
+		case inherited[id]:
+			replaced[id] = append(replaced[id], entry)
+		default:
+			appended = append(appended, entry)
+		}
+	}
+
+	result := make([]interface{}, 0, len(baseList)+len(appended))
+	for _, entry := range baseList {
+		id := entryID(entry, identity)
+		if entries, ok := replaced[id]; ok {
+			if entries != nil {
+				logger.Debug("Schema %s overrides %s entry '%s'", source, section, id)
+				result = append(result, entries...)
+				replaced[id] = nil
+			}
+			continue
+		}
+		if !deleted[id] {
+			result = append(result, entry)
+		}
+	}
+	return append(result, appended...)
+}
+
+// entryID returns the identity of a list entry, or "" if it has none.
+func entryID(entry interface{}, identity func(map[string]interface{}) string) string {
+	if m, ok := entry.(map[string]interface{}); ok {
+		return identity(m)
+	}
+	return ""
+}
+
+// isDeleteEntry reports whether entry is marked with "$delete: true".
+func isDeleteEntry(entry interface{}) bool {
+	m, ok := entry.(map[string]interface{})
+	return ok && m[merger.DeleteMarker] == true
+}
+
+// unionList appends the entries of over that base does not contain.
+func unionList(base, over interface{}) []interface{} {
+	baseList, _ := base.([]interface{})
+	overList, _ := over.([]interface{})
+	result := append([]interface{}{}, baseList...)
+	seen := make(map[interface{}]bool, len(result))
+	for _, entry := range result {
+		seen[fmt.Sprint(entry)] = true
+	}
+	for _, entry := range overList {
+		if key := fmt.Sprint(entry); !seen[key] {
+			seen[key] = true
+			result = append(result, entry)
+		}
+	}
+	return result
+}
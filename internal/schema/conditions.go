@@ -0,0 +1,126 @@
+package schema
+
+import (
+	"fmt"
+	"konfigo/internal/features/condition"
+	"konfigo/internal/features/expression"
+	"konfigo/internal/features/validator"
+	"konfigo/internal/logger"
+	"konfigo/internal/util"
+	"sort"
+)
+
+// conditionalSections lists the sections the top-level when: may name.
+var conditionalSections = []string{"generators", "transform", "validate"}
+
+// conditionErrors returns the when: conditions of s that are malformed or
+// name an unknown section.
+func conditionErrors(s *Schema) []Finding {
+	var findings []Finding
+	check := func(path string, cond *condition.Condition) {
+		if cond == nil {
+			findings = append(findings, Finding{Severity: SeverityError, Path: path, Message: "condition is empty"})
+		} else if err := cond.Validate(); err != nil {
+			findings = append(findings, Finding{Severity: SeverityError, Path: path, Message: err.Error()})
+		}
+	}
+
+	sections := make([]string, 0, len(s.When))
+	for section := range s.When {
+		sections = append(sections, section)
+	}
+	sort.Strings(sections)
+	for _, section := range sections {
+		known := false
+		for _, name := range conditionalSections {
+			known = known || name == section
+		}
+		if !known {
+			findings = append(findings, Finding{Severity: SeverityError, Path: "when",
+				Message: fmt.Sprintf("unknown section '%s'%s", section, util.DidYouMean(section, conditionalSections))})
+			continue
+		}
+		check("when."+section, s.When[section])
+	}
+	for i, def := range s.Generators {
+		if def.When != nil {
+			check(fmt.Sprintf("generators[%d].when", i), def.When)
+		}
+	}
+	for i, def := range s.Transforms {
+		if def.When != nil {
+			check(fmt.Sprintf("transform[%d].when", i), def.When)
+		}
+	}
+	for i, group := range s.Validate {
+		if group.When != nil {
+			check(fmt.Sprintf("validate[%d].when", i), group.When)
+		}
+	}
+	return findings
+}
+
+// conditions returns every when: condition of s.
+func (s *Schema) conditions() []*condition.Condition {
+	var conds []*condition.Condition
+	for _, cond := range s.When {
+		conds = append(conds, cond)
+	}
+	for _, def := range s.Generators {
+		conds = append(conds, def.When)
+	}
+	for _, def := range s.Transforms {
+		conds = append(conds, def.When)
+	}
+	for _, group := range s.Validate {
+		conds = append(conds, group.When)
+	}
+	return conds
+}
+
+// conditionEnv evaluates conditions against the variables of a resolver and
+// the configuration as it is when the condition is checked.
+type conditionEnv struct {
+	vars   expression.Env
+	config map[string]interface{}
+	paths  util.Paths
+}
+
+func (e conditionEnv) Lookup(name string) (interface{}, bool) {
+	return e.vars.Lookup(name)
+}
+
+func (e conditionEnv) Reference(path string) (interface{}, bool) {
+	return e.paths.Get(e.config, path)
+}
+
+// checkCondition reports whether cond holds for config. A nil condition
+// always holds. Skipped items, described by what, are logged at debug level.
+func (p *Processor) checkCondition(cond *condition.Condition, vars expression.Env, config map[string]interface{}, what string) (bool, error) {
+	if cond == nil {
+		return true, nil
+	}
+	ok, err := cond.Evaluate(conditionEnv{vars: vars, config: config, paths: p.paths()})
+	if err != nil {
+		return false, fmt.Errorf("%s: when: %w", what, err)
+	}
+	if !ok {
+		logger.Debug("Skipping %s: condition '%s' is not met", what, cond)
+	}
+	return ok, nil
+}
+
+// activeGroups returns the validation groups whose condition holds for config.
+func (p *Processor) activeGroups(groups []validator.Group, vars expression.Env, config map[string]interface{}) ([]validator.Group, error) {
+	active := make([]validator.Group, 0, len(groups))
+	for i, group := range groups {
+		ok, err := p.checkCondition(group.When, vars, config, fmt.Sprintf("validate[%d] (%s)", i, group.Path))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			active = append(active, group)
+		}
+	}
+	return active, nil
+}
@@ -0,0 +1,333 @@
+package schema
+
+import (
+	"fmt"
+	"konfigo/internal/features/input_schema"
+	"konfigo/internal/features/jsonschema"
+	"konfigo/internal/features/transformer"
+	"konfigo/internal/features/validator"
+	"konfigo/internal/logger"
+	"konfigo/internal/util"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ExportJSONSchema translates the inputSchema structure and the validate
+// groups of s into a JSON Schema (draft 2020-12) document for editors.
+//
+// Paths that defaults, generators or setValue transforms produce are not
+// marked required, since source files do not need to provide them. Rules
+// that JSON Schema cannot express, such as '**' patterns or rules with a
+// when: condition, are skipped with a warning and listed in the "$comment"
+// of the document.
+func ExportJSONSchema(s *Schema) (map[string]interface{}, error) {
+	doc := map[string]interface{}{}
+	if s.InputSchema != nil {
+		base, err := exportInputSchema(s)
+		if err != nil {
+			return nil, err
+		}
+		doc = base
+	}
+	delete(doc, "$schema")
+	doc["$schema"] = jsonschema.Draft202012
+	setKeyword(doc, "type", "object")
+
+	produced := producedPaths(s)
+	var skipped []string
+	for _, group := range s.Validate {
+		if group.When == nil && s.When["validate"] != nil {
+			group.When = s.When["validate"]
+		}
+		if err := exportGroup(doc, group, produced); err != nil {
+			logger.Warn("schema export: skipping validate rule for '%s': %v", group.Path, err)
+			skipped = append(skipped, fmt.Sprintf("'%s' (%v)", group.Path, err))
+		}
+	}
+	if len(skipped) > 0 {
+		doc["$comment"] = "Validate rules not exported: " + strings.Join(skipped, "; ")
+	}
+	return doc, nil
+}
+
+// exportInputSchema returns the inputSchema of s as a JSON Schema. JSON
+// Schema documents are used as they are; sample documents are translated.
+func exportInputSchema(s *Schema) (map[string]interface{}, error) {
+	ref, err := resolveRefPath(s.BaseDir, s.InputSchema)
+	if err != nil {
+		return nil, err
+	}
+	sample, err := input_schema.LoadSchemaMap((*input_schema.Ref)(ref))
+	if err != nil {
+		return nil, err
+	}
+	if jsonschema.IsJSONSchema(sample) {
+		if _, err := jsonschema.Compile(sample); err != nil {
+			return nil, fmt.Errorf("input schema %s: %w", ref.Path, err)
+		}
+		return sample, nil
+	}
+	return sampleSchema(sample, ref.Strict).(map[string]interface{}), nil
+}
+
+// sampleSchema translates a value of a sample input schema: every key is
+// required and values must have the type of the sample value.
+func sampleSchema(v interface{}, strict bool) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		properties := make(map[string]interface{}, len(val))
+		required := make([]interface{}, 0, len(val))
+		for _, key := range sortedKeys(val) {
+			properties[key] = sampleSchema(val[key], strict)
+			required = append(required, key)
+		}
+		node := map[string]interface{}{"type": "object", "properties": properties, "required": required}
+		if strict {
+			node["additionalProperties"] = false
+		}
+		return node
+	case []interface{}:
+		return map[string]interface{}{"type": "array"}
+	case string:
+		return map[string]interface{}{"type": "string"}
+	case bool:
+		return map[string]interface{}{"type": "boolean"}
+	case nil:
+		return map[string]interface{}{}
+	}
+	if _, ok := validator.NumberFromInterface(v); ok {
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{}
+}
+
+// producedPaths returns the paths that defaults, generators and setValue
+// transforms set.
+func producedPaths(s *Schema) []string {
+	var paths []string
+	for _, d := range s.Defaults {
+		paths = append(paths, d.Path)
+	}
+	for _, g := range s.Generators {
+		paths = append(paths, g.TargetPath)
+	}
+	for _, t := range s.Transforms {
+		if t.Type == transformer.SetValueType {
+			paths = append(paths, t.Path)
+		}
+	}
+	return paths
+}
+
+// overlaps reports whether one of two dot-separated paths contains the other.
+func overlaps(a, b string) bool {
+	return a == b || strings.HasPrefix(a, b+".") || strings.HasPrefix(b, a+".") ||
+		strings.HasPrefix(a, b+"[") || strings.HasPrefix(b, a+"[")
+}
+
+// exportGroup adds the rules of a validate group to doc.
+func exportGroup(doc map[string]interface{}, group validator.Group, produced []string) error {
+	if group.When != nil {
+		return fmt.Errorf("conditional rules cannot be expressed in JSON Schema")
+	}
+	segments, err := util.ParsePath(group.Path)
+	if err != nil {
+		return err
+	}
+	required := group.Rules.Required && !util.IsPattern(group.Path)
+	for _, p := range produced {
+		if required && overlaps(group.Path, p) {
+			logger.Debug("schema export: '%s' is set by the schema, not marking it required", group.Path)
+			required = false
+		}
+	}
+
+	if _, ok := exportTypes[strings.ToLower(group.Rules.Type)]; group.Rules.Type != "" && !ok {
+		return fmt.Errorf("type '%s' has no JSON Schema equivalent", group.Rules.Type)
+	}
+	for _, seg := range segments {
+		if err := checkSegment(seg); err != nil {
+			return err
+		}
+	}
+
+	node := doc
+	for _, seg := range segments {
+		node = childSchema(node, seg, required)
+	}
+	exportRule(node, group.Rules)
+	return nil
+}
+
+// checkSegment returns an error for path segments JSON Schema cannot express.
+func checkSegment(seg util.Segment) error {
+	switch {
+	case seg.Kind == util.SegmentKey && seg.Key == "**" && !seg.Quoted:
+		return fmt.Errorf("'**' cannot be expressed in JSON Schema")
+	case seg.Kind == util.SegmentKey, seg.Kind == util.SegmentAnyIndex:
+		return nil
+	case seg.Kind == util.SegmentIndex && seg.Index >= 0:
+		return nil
+	}
+	return fmt.Errorf("segment '%s' cannot be expressed in JSON Schema", util.FormatPath([]util.Segment{seg}))
+}
+
+// impliedType sets the type a path segment implies for node, unless node
+// already says what it holds, for instance with a oneOf from the
+// inputSchema. The keywords added below such a node, like properties or
+// items, only apply to values of the implied type, so they do not narrow it.
+func impliedType(node map[string]interface{}, t string) {
+	for _, keyword := range []string{"type", "oneOf", "anyOf", "allOf", "$ref", "const", "enum"} {
+		if _, ok := node[keyword]; ok {
+			return
+		}
+	}
+	node["type"] = t
+}
+
+// childSchema returns the schema of the value seg selects in a value
+// described by node, creating it as needed. required marks map keys required.
+// seg must pass checkSegment.
+func childSchema(node map[string]interface{}, seg util.Segment, required bool) map[string]interface{} {
+	switch {
+	case seg.Kind == util.SegmentKey && !seg.Quoted && strings.Contains(seg.Key, "*"):
+		impliedType(node, "object")
+		return childMap(childMap(node, "patternProperties"), keyPattern(seg.Key))
+	case seg.Kind == util.SegmentKey:
+		impliedType(node, "object")
+		if required {
+			addRequired(node, seg.Key)
+		}
+		return childMap(childMap(node, "properties"), seg.Key)
+	case seg.Kind == util.SegmentAnyIndex:
+		impliedType(node, "array")
+		return childMap(node, "items")
+	default:
+		impliedType(node, "array")
+		items, _ := node["prefixItems"].([]interface{})
+		for len(items) <= seg.Index {
+			items = append(items, map[string]interface{}{})
+		}
+		node["prefixItems"] = items
+		if required {
+			if min, _ := node["minItems"].(int); min <= seg.Index {
+				node["minItems"] = seg.Index + 1
+			}
+		}
+		return items[seg.Index].(map[string]interface{})
+	}
+}
+
+// keyPattern translates a wildcard key such as "api-*" to an anchored regexp.
+func keyPattern(key string) string {
+	parts := strings.Split(key, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	return "^" + strings.Join(parts, ".*") + "$"
+}
+
+// exportRule adds the keywords of a validation rule to node. Min and max
+// require a number, minLength, enum and regex a string.
+func exportRule(node map[string]interface{}, rule validator.Rule) {
+	if rule.Type != "" {
+		setKeyword(node, "type", exportTypes[strings.ToLower(rule.Type)])
+	}
+	if rule.Min != nil || rule.Max != nil {
+		if rule.Type == "" {
+			setKeyword(node, "type", "number")
+		}
+		if rule.Min != nil {
+			setKeyword(node, "minimum", *rule.Min)
+		}
+		if rule.Max != nil {
+			setKeyword(node, "maximum", *rule.Max)
+		}
+	}
+	if rule.MinLength != nil || len(rule.Enum) > 0 || rule.Regex != "" {
+		if rule.Type == "" {
+			setKeyword(node, "type", "string")
+		}
+		if rule.MinLength != nil {
+			setKeyword(node, "minLength", *rule.MinLength)
+		}
+		if len(rule.Enum) > 0 {
+			enum := make([]interface{}, len(rule.Enum))
+			for i, v := range rule.Enum {
+				enum[i] = v
+			}
+			setKeyword(node, "enum", enum)
+		}
+		if rule.Regex != "" {
+			setKeyword(node, "pattern", rule.Regex)
+		}
+	}
+}
+
+// exportTypes maps validate rule types to JSON Schema types.
+var exportTypes = map[string]string{
+	"string": "string", "int": "integer", "integer": "integer",
+	"bool": "boolean", "boolean": "boolean", "slice": "array", "array": "array",
+	"map": "object", "object": "object", "number": "number", "float": "number",
+	"double": "number", "float64": "number",
+}
+
+// setKeyword sets a keyword of node. A keyword that already holds a different
+// value keeps it, and the new value is added through allOf, so that both apply.
+func setKeyword(node map[string]interface{}, keyword string, value interface{}) {
+	existing, ok := node[keyword]
+	if !ok {
+		node[keyword] = value
+		return
+	}
+	if reflect.DeepEqual(existing, value) {
+		return
+	}
+	if keyword == "type" && existing == "number" && value == "integer" {
+		node[keyword] = value
+		return
+	}
+	if keyword == "type" && existing == "integer" && value == "number" {
+		return
+	}
+	allOf, _ := node["allOf"].([]interface{})
+	for _, sub := range allOf {
+		if reflect.DeepEqual(sub, map[string]interface{}{keyword: value}) {
+			return
+		}
+	}
+	node["allOf"] = append(allOf, map[string]interface{}{keyword: value})
+}
+
+// childMap returns the object at node[key], creating it as needed.
+func childMap(node map[string]interface{}, key string) map[string]interface{} {
+	if child, ok := node[key].(map[string]interface{}); ok {
+		return child
+	}
+	child := map[string]interface{}{}
+	node[key] = child
+	return child
+}
+
+// addRequired adds key to the required list of node.
+func addRequired(node map[string]interface{}, key string) {
+	required, _ := node["required"].([]interface{})
+	for _, k := range required {
+		if k == key {
+			return
+		}
+	}
+	node["required"] = append(required, key)
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
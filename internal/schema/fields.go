@@ -0,0 +1,311 @@
+package schema
+
+import (
+	"fmt"
+	"konfigo/internal/features/coerce"
+	"konfigo/internal/features/defaults"
+	"konfigo/internal/features/generator"
+	"konfigo/internal/features/transformer"
+	"konfigo/internal/features/validator"
+	"konfigo/internal/features/variables"
+	"konfigo/internal/merger"
+	"konfigo/internal/parser"
+	"konfigo/internal/reader"
+	"konfigo/internal/util"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Schema files are checked against the schema structure before they are
+// composed, so that misspelled keys such as "transforms" or "targetpath"
+// are reported instead of being ignored.
+
+// sectionTypes maps the sections of a schema to the type of their value or,
+// for lists, of their entries.
+var sectionTypes = map[string]reflect.Type{
+	"inputSchema":  reflect.TypeOf(Ref{}),
+	"outputSchema": reflect.TypeOf(Ref{}),
+	"merge":        reflect.TypeOf(merger.Rule{}),
+	"overrides":    reflect.TypeOf(merger.PolicyRule{}),
+	"defaults":     reflect.TypeOf(defaults.Definition{}),
+	"coerce":       reflect.TypeOf(coerce.Definition{}),
+	"vars":         reflect.TypeOf(variables.Definition{}),
+	"generators":   reflect.TypeOf(generator.Definition{}),
+	"transform":    reflect.TypeOf(transformer.Definition{}),
+	"validate":     reflect.TypeOf(validator.Group{}),
+}
+
+// deprecatedFields are top-level keys that earlier versions ignored. They are
+// dropped with a warning instead of failing the load, with the reason given.
+var deprecatedFields = map[string]string{
+	"config": "schemas do not set configuration values; move them to a source file",
+}
+
+// dropDeprecated removes the deprecated fields from the schema file at path,
+// parsed into data, and returns a warning for each.
+func dropDeprecated(path string, data map[string]interface{}) []fieldError {
+	var warnings []fieldError
+	for _, key := range sortedKeys(data) {
+		reason, ok := deprecatedFields[key]
+		if !ok {
+			continue
+		}
+		delete(data, key)
+		warnings = append(warnings, fieldError{
+			File:    path,
+			Message: fmt.Sprintf("field '%s' is deprecated and ignored: %s", key, reason),
+			keys:    []interface{}{key},
+		})
+	}
+	if len(warnings) > 0 {
+		locateFields(path, warnings)
+	}
+	return warnings
+}
+
+// fieldError is a field of a schema file that does not fit the schema
+// structure: an unknown key, or a value of the wrong shape.
+type fieldError struct {
+	File    string
+	Line    int // 0 when the position is unknown
+	Column  int
+	Path    string // location of the error, e.g. "transform[2]"
+	Message string
+	keys    []interface{} // keys and indices leading to the field
+}
+
+// Position returns "file:line:column", or the file when the line is unknown.
+func (e fieldError) Position() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d:%d", e.File, e.Line, e.Column)
+	}
+	return e.File
+}
+
+// String returns the error as "file:line:column: path: message".
+func (e fieldError) String() string {
+	if e.Path == "" {
+		return e.Position() + ": " + e.Message
+	}
+	return e.Position() + ": " + e.Path + ": " + e.Message
+}
+
+// checkFields returns the fields of the schema file at path, parsed into
+// data, that do not fit the schema structure, with their position in the
+// file where it can be found.
+func checkFields(path string, data map[string]interface{}) []fieldError {
+	c := &fieldChecker{file: path}
+	c.checkStruct(nil, data, reflect.TypeOf(Schema{}))
+	if len(c.errors) > 0 {
+		locateFields(path, c.errors)
+	}
+	return c.errors
+}
+
+// fieldChecker collects the field errors of a schema file.
+type fieldChecker struct {
+	file   string
+	errors []fieldError
+}
+
+// add records an error for the field keys lead to, reported at the
+// location of its first n keys.
+func (c *fieldChecker) add(keys []interface{}, n int, format string, args ...interface{}) {
+	c.errors = append(c.errors, fieldError{
+		File:    c.file,
+		Path:    formatKeys(keys[:n]),
+		Message: fmt.Sprintf(format, args...),
+		keys:    append([]interface{}(nil), keys...),
+	})
+}
+
+// checkStruct reports the keys of m that struct type t has no yaml field
+// for, and checks the values of the others.
+func (c *fieldChecker) checkStruct(keys []interface{}, m map[string]interface{}, t reflect.Type) {
+	fields := yamlFields(t)
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	topLevel := t == reflect.TypeOf(Schema{})
+	if topLevel {
+		names = append(names, "extends", "imports")
+	}
+
+	for _, key := range sortedKeys(m) {
+		fieldKeys := append(keys, key)
+		field, ok := fields[key]
+		switch {
+		case ok:
+		case topLevel && (key == "extends" || key == "imports"):
+			continue // checked by compose
+		case key == merger.DeleteMarker && len(keys) == 2:
+			continue // removes an inherited list entry
+		default:
+			c.add(fieldKeys, len(keys), "unknown field '%s'%s", key, util.DidYouMean(key, names))
+			continue
+		}
+
+		ft := field.Type
+		if topLevel && sectionTypes[key] != nil {
+			ft = sectionTypes[key]
+			if field.Type.Kind() == reflect.Slice {
+				ft = reflect.SliceOf(ft)
+			}
+		}
+		c.checkValue(fieldKeys, m[key], ft)
+	}
+}
+
+// checkValue checks a value that is decoded into a field of type t.
+func (c *fieldChecker) checkValue(keys []interface{}, v interface{}, t reflect.Type) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if v == nil {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if m, ok := v.(map[string]interface{}); ok {
+			c.checkStruct(keys, m, t)
+		} else if !reflect.PointerTo(t).Implements(yamlUnmarshaler) {
+			c.add(keys, len(keys), "expected a mapping, got %s", describeValue(v))
+		}
+	case reflect.Slice:
+		list, ok := v.([]interface{})
+		if !ok {
+			c.add(keys, len(keys), "expected a list, got %s", describeValue(v))
+			return
+		}
+		for i, item := range list {
+			c.checkValue(append(keys, i), item, t.Elem())
+		}
+	case reflect.Map:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			c.add(keys, len(keys), "expected a mapping, got %s", describeValue(v))
+			return
+		}
+		for _, key := range sortedKeys(m) {
+			c.checkValue(append(keys, key), m[key], t.Elem())
+		}
+	}
+}
+
+// yamlUnmarshaler is implemented by types that decode other shapes than a
+// mapping, such as conditions written as a string.
+var yamlUnmarshaler = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+
+// yamlFields returns the fields of struct type t by their yaml name.
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "-" || !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// formatKeys joins keys and indices into a location such as "transform[2].case".
+func formatKeys(keys []interface{}) string {
+	var b strings.Builder
+	for _, k := range keys {
+		switch key := k.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", key)
+		case string:
+			if b.Len() > 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(key)
+		}
+	}
+	return b.String()
+}
+
+// describeValue names the kind of a decoded value.
+func describeValue(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "a mapping"
+	case []interface{}:
+		return "a list"
+	case string:
+		return "a string"
+	case bool:
+		return "a boolean"
+	}
+	if _, ok := validator.NumberFromInterface(v); ok {
+		return "a number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// locateFields sets the line and column of field errors in YAML and JSON
+// schema files. Positions are best effort; TOML files only name the file.
+func locateFields(path string, errs []fieldError) {
+	switch parser.NormalizeFormat(parser.DetectFormat(path)) {
+	case "yaml", "json":
+	default:
+		return
+	}
+	content, err := reader.ReadFile(path)
+	if err != nil {
+		return
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil || len(doc.Content) == 0 {
+		return
+	}
+	for i := range errs {
+		if node := findNode(doc.Content[0], errs[i].keys); node != nil {
+			errs[i].Line, errs[i].Column = node.Line, node.Column
+		}
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Line < errs[j].Line || errs[i].Line == errs[j].Line && errs[i].Column < errs[j].Column
+	})
+}
+
+// findNode returns the node of the key or list entry that keys lead to.
+func findNode(node *yaml.Node, keys []interface{}) *yaml.Node {
+	var found *yaml.Node
+	for _, k := range keys {
+		if node.Kind == yaml.AliasNode && node.Alias != nil {
+			node = node.Alias
+		}
+		found = nil
+		switch key := k.(type) {
+		case string:
+			if node.Kind != yaml.MappingNode {
+				return nil
+			}
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == key {
+					found, node = node.Content[i], node.Content[i+1]
+					break
+				}
+			}
+		case int:
+			if node.Kind != yaml.SequenceNode || key >= len(node.Content) {
+				return nil
+			}
+			found, node = node.Content[key], node.Content[key]
+		}
+		if found == nil {
+			return nil
+		}
+	}
+	return found
+}
@@ -0,0 +1,392 @@
+package schema
+
+import (
+	"fmt"
+	"konfigo/internal/features/coerce"
+	"konfigo/internal/features/defaults"
+	"konfigo/internal/features/expression"
+	"konfigo/internal/features/generator"
+	"konfigo/internal/features/input_schema"
+	"konfigo/internal/features/jsonschema"
+	"konfigo/internal/features/transformer"
+	"konfigo/internal/features/validator"
+	"konfigo/internal/features/variables"
+	"konfigo/internal/merger"
+	"konfigo/internal/util"
+	"sort"
+	"strings"
+)
+
+// Lint severities. Errors are mistakes that fail or silently break a run;
+// warnings are definitions that are probably not doing what was intended.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Finding is a problem found in a schema by Lint. Path locates the entry,
+// such as "transform[2]" or "vars[0]". Position is the file, line and
+// column of the problem when it is known, e.g. "base.yaml:12:5".
+type Finding struct {
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+	Position string `json:"position,omitempty"`
+}
+
+// String returns the finding as "severity: path: message".
+func (f Finding) String() string {
+	if f.Path == "" {
+		return f.Severity + ": " + f.Message
+	}
+	return f.Severity + ": " + f.Path + ": " + f.Message
+}
+
+// Lint checks the schema file at path, composed with the schemas it extends
+// or imports, without processing any configuration. It reports:
+//
+//   - fields no section of the schema knows, usually typos
+//   - defaults, coerce, generator, transform, validate, merge and vars entries the registries reject
+//   - vars that are defined twice, never referenced or hidden by another source
+//   - paths that validate, coerce and transform rules or vars read but nothing produces
+//
+// config is the merged configuration of the sources the schema is used
+// with, or nil. When set, its placeholders count as variable references and
+// its paths as produced. Paths are only checked when config is set or the
+// schema has an inputSchema, and match keys exactly when caseSensitive is set.
+func Lint(path string, config map[string]interface{}, caseSensitive bool) ([]Finding, error) {
+	s := &Schema{}
+	c := &composer{}
+	raw, err := c.compose(path, nil, s)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &linter{schema: s, caseSensitive: caseSensitive}
+	for _, fe := range c.fieldErrors {
+		l.findings = append(l.findings, Finding{SeverityError, fe.Path, fe.Message, fe.Position()})
+	}
+	for _, fw := range c.fieldWarnings {
+		l.findings = append(l.findings, Finding{SeverityWarning, fw.Path, fw.Message, fw.Position()})
+	}
+	if err := decode(path, raw, s, false); err != nil {
+		if len(l.findings) > 0 {
+			return l.findings, nil // the field errors explain why
+		}
+		return nil, err
+	}
+
+	l.findings = append(l.findings, conditionErrors(s)...)
+	l.checkDefinitions()
+	l.checkVars(raw, config)
+	l.checkPaths(config)
+
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		return l.findings[i].Severity == SeverityError && l.findings[j].Severity != SeverityError
+	})
+	return l.findings, nil
+}
+
+// linter collects the findings for a schema.
+type linter struct {
+	schema        *Schema
+	caseSensitive bool
+	findings      []Finding
+}
+
+func (l *linter) errorf(path, format string, args ...interface{}) {
+	l.findings = append(l.findings, Finding{Severity: SeverityError, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (l *linter) warnf(path, format string, args ...interface{}) {
+	l.findings = append(l.findings, Finding{Severity: SeverityWarning, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// checkDefinitions validates every entry of the schema against the registry
+// of its section.
+func (l *linter) checkDefinitions() {
+	s := l.schema
+	if err := merger.ValidateRules(s.Merge); err != nil {
+		l.errorf("merge", "%v", err)
+	}
+	if err := merger.ValidatePolicies(s.Overrides); err != nil {
+		l.errorf("overrides", "%v", err)
+	}
+	for i, def := range s.Defaults {
+		if err := defaults.ValidateDefinition(def); err != nil {
+			l.errorf(fmt.Sprintf("defaults[%d]", i), "%v", err)
+		}
+	}
+	for i, def := range s.Coerce {
+		if err := coerce.ValidateDefinition(def); err != nil {
+			l.errorf(fmt.Sprintf("coerce[%d]", i), "%v", err)
+		}
+	}
+	for i, def := range s.Generators {
+		if err := generator.ValidateDefinition(def); err != nil {
+			l.errorf(fmt.Sprintf("generators[%d]", i), "%v", err)
+		}
+	}
+	for i, def := range s.Transforms {
+		if hasPlaceholder(def) {
+			continue // checked once its variables are substituted
+		}
+		if err := transformer.ValidateDefinition(def); err != nil {
+			l.errorf(fmt.Sprintf("transform[%d]", i), "%v", err)
+		}
+	}
+	for i, group := range s.Validate {
+		if err := validator.ValidateDefinition(group); err != nil {
+			l.errorf(fmt.Sprintf("validate[%d]", i), "%v", err)
+		}
+	}
+}
+
+// hasPlaceholder reports whether a string field of a transformer definition
+// holds a variable placeholder.
+func hasPlaceholder(def transformer.Definition) bool {
+	for _, f := range []string{def.Path, def.From, def.To, def.Case, def.Prefix, def.Suffix, def.Pattern, def.Target} {
+		if strings.Contains(f, "${") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkVars reports invalid, duplicate, shadowed and unused variable
+// definitions. References are looked for in every string of the schema
+// outside the vars section and in config.
+func (l *linter) checkVars(raw, config map[string]interface{}) {
+	referenced := make(map[string]bool)
+	for key, value := range raw {
+		if key != "vars" {
+			collectReferences(value, referenced)
+		}
+	}
+	for _, def := range l.schema.Vars {
+		// Values of other variables may refer to a variable too
+		collectReferences([]interface{}{def.Value, def.DefaultValue}, referenced)
+	}
+	for _, def := range l.schema.Generators {
+		collectExpression(def.Expression, referenced)
+	}
+	for _, cond := range l.schema.conditions() {
+		for _, name := range cond.Variables() {
+			referenced[name] = true
+		}
+	}
+	collectReferences(config, referenced)
+
+	seen := make(map[string]bool, len(l.schema.Vars))
+	for i, def := range l.schema.Vars {
+		path := fmt.Sprintf("vars[%d]", i)
+		if err := variables.ValidateDefinition(def); err != nil {
+			l.errorf(path, "%v", err)
+			continue
+		}
+		if seen[def.Name] {
+			l.errorf(path, "variable '%s' is defined more than once", def.Name)
+			continue
+		}
+		seen[def.Name] = true
+
+		switch {
+		case def.FromEnv != "" && def.FromPath != "":
+			l.warnf(path, "variable '%s': fromPath is never used because fromEnv is set", def.Name)
+		case def.FromEnv != "" && def.Value != "":
+			l.warnf(path, "variable '%s': value is never used because fromEnv is set; use defaultValue for a fallback", def.Name)
+		case def.FromPath != "" && def.Value != "":
+			l.warnf(path, "variable '%s': value is never used because fromPath is set; use defaultValue for a fallback", def.Name)
+		}
+
+		if !referenced[def.Name] {
+			where := "the schema"
+			if config != nil {
+				where = "the schema or the sources"
+			}
+			l.warnf(path, "variable '%s' is not referenced in %s", def.Name, where)
+		}
+	}
+}
+
+// collectReferences records the variables that ${VAR} and ${{ expression }}
+// placeholders in the strings of v refer to.
+func collectReferences(v interface{}, referenced map[string]bool) {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for _, item := range val {
+			collectReferences(item, referenced)
+		}
+	case []interface{}:
+		for _, item := range val {
+			collectReferences(item, referenced)
+		}
+	case string:
+		for _, m := range variables.ExprRegex.FindAllStringSubmatch(val, -1) {
+			if m[1] == "" {
+				collectExpression(m[2], referenced)
+			}
+		}
+		for _, m := range variables.VarRegex.FindAllStringSubmatch(val, -1) {
+			if m[1] == "" && !strings.HasPrefix(m[2], ".") {
+				referenced[m[2]] = true
+			}
+		}
+	}
+}
+
+// collectExpression records the variables an expression reads.
+func collectExpression(source string, referenced map[string]bool) {
+	if strings.TrimSpace(source) == "" {
+		return
+	}
+	expr, err := expression.Compile(source)
+	if err != nil {
+		return
+	}
+	for _, name := range expr.Variables() {
+		referenced[name] = true
+	}
+}
+
+// checkPaths reports the paths that rules read but neither the inputSchema,
+// config nor the schema itself produces.
+func (l *linter) checkPaths(config map[string]interface{}) {
+	s := l.schema
+	var known []string
+	if s.InputSchema != nil {
+		paths, ok := l.inputSchemaPaths()
+		if !ok {
+			return
+		}
+		known = append(known, paths...)
+	} else if config == nil {
+		return
+	}
+	if config != nil {
+		known = appendPaths(known, "", config)
+	}
+
+	produced := producedPaths(s)
+	for _, t := range s.Transforms {
+		if t.Type == transformer.RenameKeyType && t.To != "" {
+			produced = append(produced, t.To)
+		}
+	}
+
+	exists := func(pattern string) bool {
+		caseSensitive := l.caseSensitive
+		for _, p := range known {
+			if util.MatchPathOrAncestor(pattern, p, caseSensitive) {
+				return true
+			}
+		}
+		for _, p := range produced {
+			if util.MatchPathOrAncestor(pattern, p, caseSensitive) || util.MatchPathOrAncestor(p, pattern, caseSensitive) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for i, group := range s.Validate {
+		if group.Path != "" && !exists(group.Path) {
+			l.warnf(fmt.Sprintf("validate[%d]", i), "nothing produces the path '%s'", group.Path)
+		}
+	}
+	for i, def := range s.Coerce {
+		if def.Path != "" && !exists(def.Path) {
+			l.warnf(fmt.Sprintf("coerce[%d]", i), "nothing produces the path '%s'", def.Path)
+		}
+	}
+	for i, t := range s.Transforms {
+		path := t.Path
+		if t.Type == transformer.RenameKeyType {
+			path = t.From
+		}
+		if t.Type == transformer.SetValueType || path == "" || strings.Contains(path, "${") {
+			continue
+		}
+		if !exists(path) {
+			l.warnf(fmt.Sprintf("transform[%d]", i), "nothing produces the path '%s'", path)
+		}
+	}
+	for i, def := range s.Vars {
+		if def.FromPath != "" && !exists(def.FromPath) {
+			l.warnf(fmt.Sprintf("vars[%d]", i), "variable '%s': nothing produces the path '%s'", def.Name, def.FromPath)
+		}
+	}
+}
+
+// inputSchemaPaths returns the paths the inputSchema declares. It reports
+// false when the inputSchema cannot be read or does not list its paths.
+func (l *linter) inputSchemaPaths() ([]string, bool) {
+	ref, err := resolveRefPath(l.schema.BaseDir, l.schema.InputSchema)
+	if err != nil {
+		l.errorf("inputSchema", "%v", err)
+		return nil, false
+	}
+	doc, err := input_schema.LoadSchemaMap((*input_schema.Ref)(ref))
+	if err != nil {
+		l.errorf("inputSchema", "%v", err)
+		return nil, false
+	}
+	if !jsonschema.IsJSONSchema(doc) {
+		return appendPaths(nil, "", doc), true
+	}
+	if _, err := jsonschema.Compile(doc); err != nil {
+		l.errorf("inputSchema", "input schema %s: %v", ref.Path, err)
+		return nil, false
+	}
+	var paths []string
+	if !jsonSchemaPaths(doc, "", &paths) {
+		return nil, false
+	}
+	return paths, true
+}
+
+// jsonSchemaPaths appends the paths the properties of a JSON Schema declare.
+// It reports false when the schema allows keys it does not declare or
+// composes subschemas, which are not followed.
+func jsonSchemaPaths(node map[string]interface{}, path string, paths *[]string) bool {
+	if path != "" {
+		*paths = append(*paths, path)
+	}
+	for _, keyword := range []string{"$ref", "allOf", "anyOf", "oneOf", "if", "patternProperties"} {
+		if _, ok := node[keyword]; ok {
+			return false
+		}
+	}
+	if items, ok := node["items"].(map[string]interface{}); ok {
+		return jsonSchemaPaths(items, util.AppendIndex(path, 0), paths)
+	}
+	properties, ok := node["properties"].(map[string]interface{})
+	if !ok {
+		return node["type"] != "object" && node["type"] != nil
+	}
+	for key, prop := range properties {
+		child, ok := prop.(map[string]interface{})
+		if !ok || !jsonSchemaPaths(child, util.AppendKey(path, key), paths) {
+			return false
+		}
+	}
+	return true
+}
+
+// appendPaths appends the paths of v and of every value below it.
+func appendPaths(paths []string, path string, v interface{}) []string {
+	if path != "" {
+		paths = append(paths, path)
+	}
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for key, item := range val {
+			paths = appendPaths(paths, util.AppendKey(path, key), item)
+		}
+	case []interface{}:
+		for i, item := range val {
+			paths = appendPaths(paths, util.AppendIndex(path, i), item)
+		}
+	}
+	return paths
+}
@@ -3,31 +3,72 @@ package schema
 import (
 	"fmt"
 	"konfigo/internal/errors"
+	"konfigo/internal/features/coerce"
+	"konfigo/internal/features/defaults"
+	"konfigo/internal/features/expression"
 	"konfigo/internal/features/generator"
 	"konfigo/internal/features/input_schema"
+	"konfigo/internal/features/jsonschema"
 	"konfigo/internal/features/transformer"
 	"konfigo/internal/features/validator"
 	"konfigo/internal/features/variables"
 	"konfigo/internal/logger"
+	"konfigo/internal/merger"
 	"konfigo/internal/parser"
+	"konfigo/internal/provenance"
 	"konfigo/internal/reader"
 	"konfigo/internal/util"
 	"path/filepath"
 	"reflect"
+	"sort"
 )
 
+// Options controls optional schema processing behavior.
+type Options struct {
+	// StrictVars fails processing when any variable placeholder remains unresolved.
+	StrictVars bool
+	// Provenance, if set, records the values changed by each processing step.
+	Provenance *provenance.Tracker
+	// CaseSensitive matches keys exactly instead of ignoring case, in schema
+	// paths, ${.path} references and override policy paths.
+	CaseSensitive bool
+}
+
 // Processor handles the orchestration of schema-driven configuration processing.
-type Processor struct{}
+type Processor struct {
+	opts Options
+}
 
 // NewProcessor creates a new processor instance.
 func NewProcessor() *Processor {
 	return &Processor{}
 }
 
+// NewProcessorWithOptions creates a new processor instance with the given options.
+func NewProcessorWithOptions(opts Options) *Processor {
+	return &Processor{opts: opts}
+}
+
+// paths returns the path lookup that matches keys as selected by the options.
+func (p *Processor) paths() util.Paths {
+	return util.Paths{CaseSensitive: p.opts.CaseSensitive}
+}
+
 // Process orchestrates the entire schema-driven pipeline with the new steps.
 func (p *Processor) Process(config map[string]interface{}, schema *Schema, varsFromFile map[string]interface{}, envVars map[string]string) (map[string]interface{}, error) {
 	logger.Log("Applying schema...")
 
+	// Fill the paths the sources leave out before anything reads or validates them
+	if err := p.applyDefaults(config, schema.Defaults); err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeSchemaProcess, "defaults failed", err)
+	}
+
+	// Convert values to their declared types; values with placeholders are
+	// converted once they are substituted
+	if err := p.applyCoercion(config, schema.Coerce, true); err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeValidation, "coercion failed", err)
+	}
+
 	if schema.InputSchema != nil {
 		resolvedRef, err := resolveRefPath(schema.BaseDir, schema.InputSchema)
 		if err != nil {
@@ -45,44 +86,86 @@ func (p *Processor) Process(config map[string]interface{}, schema *Schema, varsF
 	}
 
 	// 1. Resolve variables, now including envVars
-	resolver, err := variables.NewResolver(envVars, varsFromFile, schema.Vars, config)
+	resolver, err := variables.NewResolver(envVars, varsFromFile, schema.Vars, config, p.paths())
 	if err != nil {
 		return nil, errors.WrapError(errors.ErrorTypeVarResolution, "variable resolution failed", err)
 	}
+	if err := resolver.Validate(); err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeVarResolution, "variable validation failed", err)
+	}
 
-	// Snapshot immutable values before generators/transformers
+	// Snapshot immutable values before generators/transformers, keyed by the
+	// concrete paths that immutable patterns match. Paths covered by an
+	// override policy follow the policy instead.
 	immutableSnapshot := make(map[string]interface{}, len(immutableSet))
 	for ip := range immutableSet {
-		if val, found := util.GetNestedValue(config, ip); found {
-			immutableSnapshot[ip] = val
+		for _, path := range p.paths().Expand(config, ip) {
+			if _, ok := merger.PolicyFor(schema.Overrides, path, p.opts.CaseSensitive); ok {
+				continue
+			}
+			val, _ := p.paths().Get(config, path)
+			immutableSnapshot[path] = val
 		}
 	}
+	var policySnapshot map[string]interface{}
+	if len(schema.Overrides) > 0 {
+		policySnapshot = provenance.Flatten(config)
+	}
+
+	// Conditions see the variables and the configuration as it is when they are checked
+	vars := resolver.ExpressionEnv()
 
 	// 2. Run generators
-	if err := generator.Apply(config, schema.Generators, resolver); err != nil {
-		return nil, errors.WrapError(errors.ErrorTypeInternal, "generator failed", err)
+	if ok, err := p.checkCondition(schema.When["generators"], vars, config, "the generators section"); err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeSchemaProcess, "condition failed", err)
+	} else if ok {
+		if err := p.applyGenerators(config, schema.Generators, resolver, vars); err != nil {
+			return nil, errors.WrapError(errors.ErrorTypeInternal, "generator failed", err)
+		}
 	}
 
 	// 3. Run transformers
-	if err := p.applyTransforms(config, schema.Transforms, resolver); err != nil {
-		return nil, errors.WrapError(errors.ErrorTypeInternal, "transform failed", err)
+	if ok, err := p.checkCondition(schema.When["transform"], vars, config, "the transform section"); err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeSchemaProcess, "condition failed", err)
+	} else if ok {
+		if err := p.applyTransforms(config, schema.Transforms, resolver, vars); err != nil {
+			return nil, errors.WrapError(errors.ErrorTypeInternal, "transform failed", err)
+		}
 	}
 
 	// Restore immutable values if they were modified by generators/transformers
-	for ip, originalVal := range immutableSnapshot {
-		currentVal, found := util.GetNestedValue(config, ip)
-		if !found || !reflect.DeepEqual(currentVal, originalVal) {
-			logger.Warn("Restoring immutable path '%s' that was modified by generator/transformer", ip)
-			util.SetNestedValue(config, ip, originalVal)
+	if err := p.track(config, provenance.Origin{Kind: provenance.KindImmutable}, func() error {
+		if err := p.restoreImmutables(config, immutableSnapshot); err != nil {
+			return err
 		}
+		return p.enforceOverridePolicies(config, policySnapshot, schema.Overrides)
+	}); err != nil {
+		return nil, err
 	}
 
 	// 4. Substitute variables throughout the config
-	processedConfig := variables.Substitute(config, resolver)
+	processedConfig, err := variables.Substitute(config, resolver, p.opts.StrictVars, p.paths())
+	if err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeVarSubstitute, "variable substitution failed", err)
+	}
+	if p.opts.Provenance != nil {
+		p.opts.Provenance.Record(provenance.Flatten(config), provenance.Flatten(processedConfig), provenance.Origin{Kind: provenance.KindSubstitution})
+	}
+	if err := p.applyCoercion(processedConfig, schema.Coerce, false); err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeValidation, "coercion failed", err)
+	}
 
 	// 5. Validate the final configuration
-	if err := validator.Apply(processedConfig, schema.Validate); err != nil {
-		return nil, errors.WrapError(errors.ErrorTypeValidation, "validation failed", err)
+	if ok, err := p.checkCondition(schema.When["validate"], vars, processedConfig, "the validate section"); err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeSchemaProcess, "condition failed", err)
+	} else if ok {
+		groups, err := p.activeGroups(schema.Validate, vars, processedConfig)
+		if err != nil {
+			return nil, errors.WrapError(errors.ErrorTypeSchemaProcess, "condition failed", err)
+		}
+		if err := validator.Apply(processedConfig, groups, p.paths()); err != nil {
+			return nil, errors.WrapError(errors.ErrorTypeValidation, "validation failed", err)
+		}
 	}
 
 	if schema.OutputSchema != nil {
@@ -101,12 +184,155 @@ func (p *Processor) Process(config map[string]interface{}, schema *Schema, varsF
 	return processedConfig, nil
 }
 
-// applyTransforms applies a list of transformer definitions to the configuration.
-func (p *Processor) applyTransforms(config map[string]interface{}, transforms []transformer.Definition, resolver variables.Resolver) error {
-	return transformer.Apply(config, transforms, resolver)
+// restoreImmutables puts back immutable values that generators or transformers modified.
+func (p *Processor) restoreImmutables(config map[string]interface{}, snapshot map[string]interface{}) error {
+	for ip, originalVal := range snapshot {
+		currentVal, found := p.paths().Get(config, ip)
+		if !found || !reflect.DeepEqual(currentVal, originalVal) {
+			logger.Warn("Restoring immutable path '%s' that was modified by generator/transformer", ip)
+			if err := p.paths().Set(config, ip, originalVal); err != nil {
+				return errors.WrapError(errors.ErrorTypeImmutableField, "failed to restore immutable path", err).WithContext("path", ip)
+			}
+		}
+	}
+	return nil
+}
+
+// enforceOverridePolicies applies the override policies to the values that
+// generators and transformers changed. before is the Flatten snapshot of the
+// configuration taken before they ran.
+func (p *Processor) enforceOverridePolicies(config, before map[string]interface{}, rules []merger.PolicyRule) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	after := provenance.Flatten(config)
+	paths := make([]string, 0, len(before))
+	for path := range before {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		previous := before[path]
+		value, found := after[path]
+		if found && reflect.DeepEqual(previous, value) {
+			continue
+		}
+		policy, ok := merger.PolicyFor(rules, path, p.opts.CaseSensitive)
+		if !ok {
+			continue
+		}
+		switch policy {
+		case merger.PolicyWarn:
+			logger.Warn("Generator/transformer changed '%s' (was %v)", path, previous)
+		case merger.PolicyError:
+			return errors.NewErrorf(errors.ErrorTypeImmutableField, "path '%s' was changed by a generator or transformer (was %v; override policy: error)", path, previous)
+		case merger.PolicyFirstWins:
+			logger.Debug("Restoring '%s' that was changed by generator/transformer (first-wins)", path)
+			if err := p.paths().Set(config, path, previous); err != nil {
+				return errors.WrapError(errors.ErrorTypeImmutableField, "failed to restore overridden path", err).WithContext("path", path)
+			}
+		}
+	}
+	return nil
+}
+
+// applyDefaults fills the paths of the defaults section that the
+// configuration does not set. Definitions run one at a time so that, when
+// provenance is tracked, each defaulted value is attributed to its entry.
+func (p *Processor) applyDefaults(config map[string]interface{}, defs []defaults.Definition) error {
+	if err := defaults.ValidateDefinitions(defs); err != nil {
+		return err
+	}
+	for i, def := range defs {
+		origin := provenance.Origin{Kind: provenance.KindDefault, Name: fmt.Sprintf("%s (defaults[%d])", def.Path, i)}
+		if err := p.track(config, origin, func() error {
+			_, err := defaults.Apply(config, defs[i:i+1], p.paths())
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyCoercion converts the values at the paths of the coerce section (see
+// coerce.Apply).
+func (p *Processor) applyCoercion(config map[string]interface{}, defs []coerce.Definition, skipPlaceholders bool) error {
+	if len(defs) == 0 {
+		return nil
+	}
+	return p.track(config, provenance.Origin{Kind: provenance.KindCoercion}, func() error {
+		return coerce.Apply(config, defs, skipPlaceholders, p.paths())
+	})
+}
+
+// applyGenerators applies a list of generator definitions to the configuration.
+// Generators run one at a time, so that their conditions see the changes of
+// the generators before them and, when provenance is tracked, each change
+// can be attributed to its generator.
+func (p *Processor) applyGenerators(config map[string]interface{}, generators []generator.Definition, resolver variables.Resolver, vars expression.Env) error {
+	if err := generator.ValidateDefinitions(generators); err != nil {
+		return err
+	}
+	for i, def := range generators {
+		name := fmt.Sprintf("%s (generators[%d])", def.Type, i)
+		ok, err := p.checkCondition(def.When, vars, config, name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		origin := provenance.Origin{Kind: provenance.KindGenerator, Name: name}
+		if err := p.track(config, origin, func() error {
+			return generator.Apply(config, generators[i:i+1], resolver, p.paths())
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyTransforms applies a list of transformer definitions to the
+// configuration, one at a time like applyGenerators.
+func (p *Processor) applyTransforms(config map[string]interface{}, transforms []transformer.Definition, resolver variables.Resolver, vars expression.Env) error {
+	for i, def := range transforms {
+		name := fmt.Sprintf("%s (transform[%d])", def.Type, i)
+		ok, err := p.checkCondition(def.When, vars, config, name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		origin := provenance.Origin{Kind: provenance.KindTransformer, Name: name}
+		if err := p.track(config, origin, func() error {
+			return transformer.Apply(config, transforms[i:i+1], resolver, p.paths())
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// track runs step and, when provenance is tracked, records the values it
+// changed in config as set by origin.
+func (p *Processor) track(config map[string]interface{}, origin provenance.Origin, step func() error) error {
+	if p.opts.Provenance == nil {
+		return step()
+	}
+	before := provenance.Flatten(config)
+	if err := step(); err != nil {
+		return err
+	}
+	p.opts.Provenance.Record(before, provenance.Flatten(config), origin)
+	return nil
 }
 
 // filterOutputSchema filters the configuration against an output schema.
+// A JSON Schema keeps the properties it declares, or everything when strict,
+// and the result must validate against it.
 func (p *Processor) filterOutputSchema(config map[string]interface{}, ref *Ref) (map[string]interface{}, error) {
 	schemaContent, err := reader.ReadFile(ref.Path)
 	if err != nil {
@@ -116,10 +342,25 @@ func (p *Processor) filterOutputSchema(config map[string]interface{}, ref *Ref)
 	if err != nil {
 		return nil, err
 	}
+	if jsonschema.IsJSONSchema(schemaMap) {
+		schema, err := jsonschema.Compile(schemaMap)
+		if err != nil {
+			return nil, fmt.Errorf("output schema %s: %w", ref.Path, err)
+		}
+		if !ref.Strict {
+			config = schema.Project(config).(map[string]interface{})
+		}
+		if err := schema.Validate(config); err != nil {
+			return nil, err
+		}
+		return config, nil
+	}
 	return p.projectMap(config, schemaMap, "", ref.Strict)
 }
 
-// projectMap projects data onto a schema map, optionally enforcing strict validation.
+// projectMap projects data onto a schema map, optionally enforcing strict
+// validation. Keys are matched as selected by the options and take the
+// spelling of the schema.
 func (p *Processor) projectMap(data, schema map[string]interface{}, path string, strict bool) (map[string]interface{}, error) {
 	result := make(map[string]interface{})
 	for key, schemaVal := range schema {
@@ -127,7 +368,8 @@ func (p *Processor) projectMap(data, schema map[string]interface{}, path string,
 		if path != "" {
 			currentPath = path + "." + key
 		}
-		if dataVal, ok := data[key]; ok {
+		if dataKey, ok := p.paths().LookupKey(data, key); ok {
+			dataVal := data[dataKey]
 			if schemaMap, sOK := schemaVal.(map[string]interface{}); sOK {
 				if dataMap, dOK := dataVal.(map[string]interface{}); dOK {
 					nestedResult, err := p.projectMap(dataMap, schemaMap, currentPath, strict)
@@ -155,7 +397,7 @@ func (p *Processor) projectMap(data, schema map[string]interface{}, path string,
 	if strict {
 		// Check for keys in data that are NOT in the schema
 		for key := range data {
-			if _, schemaHasKey := schema[key]; !schemaHasKey {
+			if _, schemaHasKey := p.paths().LookupKey(schema, key); !schemaHasKey {
 				currentDataPath := key
 				if path != "" {
 					currentDataPath = path + "." + key
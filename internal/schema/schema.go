@@ -23,14 +23,20 @@
 package schema
 
 import (
+	"bytes"
 	"konfigo/internal/errors"
+	"konfigo/internal/features/coerce"
+	"konfigo/internal/features/condition"
+	"konfigo/internal/features/defaults"
 	"konfigo/internal/features/generator"
 	"konfigo/internal/features/transformer"
 	"konfigo/internal/features/validator"
 	"konfigo/internal/features/variables"
+	"konfigo/internal/merger"
 	"konfigo/internal/parser"
 	"konfigo/internal/reader"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -41,6 +47,9 @@ import (
 type KonfigoForEachOutput struct {
 	FilenamePattern string `yaml:"filenamePattern" json:"filenamePattern"`
 	Format          string `yaml:"format,omitempty" json:"format,omitempty"` // e.g., json, yaml, toml
+	// Template is a Go text/template file rendered for each iteration instead
+	// of marshalling the config. Relative paths resolve against the vars file.
+	Template string `yaml:"template,omitempty" json:"template,omitempty"`
 }
 
 // KonfigoForEach defines the structure for batch processing directives.
@@ -51,6 +60,9 @@ type KonfigoForEach struct {
 	Output    KonfigoForEachOutput     `yaml:"output" json:"output"`
 	// GlobalVars will hold variables defined outside forEach in the main vars file
 	GlobalVars map[string]interface{} `yaml:"-" json:"-"` // Loaded separately
+	// SourceFile is the vars file that declared the directive. Relative
+	// itemFiles and templates are resolved against its directory.
+	SourceFile string `yaml:"-" json:"-"`
 }
 
 // Schema represents the entire Konfigo schema structure.
@@ -59,11 +71,22 @@ type Schema struct {
 	InputSchema  *Ref                     `yaml:"inputSchema"`
 	OutputSchema *Ref                     `yaml:"outputSchema"`
 	Immutable    []string                 `yaml:"immutable"`
+	Merge        []merger.Rule            `yaml:"merge"`
+	Overrides    []merger.PolicyRule      `yaml:"overrides"`
+	Defaults     []defaults.Definition    `yaml:"defaults"`
+	Coerce       []coerce.Definition      `yaml:"coerce"`
 	Vars         []variables.Definition   `yaml:"vars"`
 	Generators   []generator.Definition   `yaml:"generators"`
 	Transforms   []transformer.Definition `yaml:"transform"`
 	Validate     []validator.Group        `yaml:"validate"`
-	BaseDir      string                   `yaml:"-"`
+	// When skips whole sections (generators, transform, validate) unless
+	// their condition holds.
+	When map[string]*condition.Condition `yaml:"when"`
+	// Extends and Imports are the absolute paths of the schema files this
+	// schema was composed from (see compose.go).
+	Extends []string `yaml:"-"`
+	Imports []string `yaml:"-"`
+	BaseDir string   `yaml:"-"`
 }
 
 // Ref represents a reference to another schema file.
@@ -72,46 +95,96 @@ type Ref struct {
 	Strict bool   `yaml:"strict"`
 }
 
-// Load loads and parses a schema file from the given path.
+// Load loads and parses a schema file from the given path, composed with
+// the schema files it extends or imports. Fields that are not part of the
+// schema structure are reported with their position and the closest known
+// field name.
 func Load(path string) (*Schema, error) {
-	format := parser.DetectFormat(path)
-
-	// Validate that the format is suitable for schema files
-	if err := parser.ValidateSchemaFormat(format); err != nil {
+	var schema Schema
+	c := &composer{}
+	data, err := c.compose(path, nil, &schema)
+	if err != nil {
 		return nil, err
 	}
-
-	content, err := reader.ReadFile(path)
-	if err != nil {
-		return nil, errors.WrapError(errors.ErrorTypeSchemaLoad, "failed to read schema file", err).WithContext("file", path)
+	c.warn()
+	if err := c.err(); err != nil {
+		return nil, err
 	}
-
-	data, err := parser.Parse(path, content, "")
-	if err != nil {
-		return nil, errors.WrapError(errors.ErrorTypeParsing, "failed to parse schema file", err).WithContext("file", path)
+	if err := decode(path, data, &schema, true); err != nil {
+		return nil, err
+	}
+	if problems := conditionErrors(&schema); len(problems) > 0 {
+		lines := make([]string, len(problems))
+		for i, f := range problems {
+			lines[i] = f.Path + ": " + f.Message
+		}
+		return nil, errors.NewErrorf(errors.ErrorTypeSchemaLoad, "invalid conditions: %s", strings.Join(lines, "; ")).WithContext("file", path)
 	}
+	return &schema, nil
+}
 
+// decode decodes the composed schema document data of the schema file at
+// path into schema. strict rejects fields that are not part of the schema
+// structure.
+func decode(path string, data map[string]interface{}, schema *Schema, strict bool) error {
 	yamlBytes, err := yaml.Marshal(data)
 	if err != nil {
-		return nil, errors.WrapError(errors.ErrorTypeSchemaProcess, "failed to internally process schema data", err)
+		return errors.WrapError(errors.ErrorTypeSchemaProcess, "failed to internally process schema data", err)
 	}
 
 	const maxSchemaSize = 10 * 1024 * 1024 // 10 MiB
 	if len(yamlBytes) > maxSchemaSize {
-		return nil, errors.NewErrorf(errors.ErrorTypeSchemaLoad, "schema file exceeds maximum allowed size of %d bytes", maxSchemaSize)
+		return errors.NewErrorf(errors.ErrorTypeSchemaLoad, "schema file exceeds maximum allowed size of %d bytes", maxSchemaSize)
 	}
-	var schema Schema
-	if err := yaml.Unmarshal(yamlBytes, &schema); err != nil {
-		return nil, errors.WrapError(errors.ErrorTypeSchemaLoad, "failed to decode schema structure", err).WithContext("file", path)
+	dec := yaml.NewDecoder(bytes.NewReader(yamlBytes))
+	dec.KnownFields(strict)
+	if err := dec.Decode(schema); err != nil {
+		return errors.WrapError(errors.ErrorTypeSchemaLoad, "failed to decode schema structure", err).WithContext("file", path)
 	}
 
 	absPath, err := filepath.Abs(path)
 	if err != nil {
-		return nil, errors.WrapError(errors.ErrorTypeSchemaLoad, "failed to resolve schema file path", err).WithContext("file", path)
+		return errors.WrapError(errors.ErrorTypeSchemaLoad, "failed to resolve schema file path", err).WithContext("file", path)
 	}
 	schema.BaseDir = filepath.Dir(absPath)
+	return nil
+}
 
-	return &schema, nil
+// Resolve loads the schema file at path composed with the schema files it
+// extends or imports, and returns the flattened document. Relative
+// inputSchema and outputSchema paths are relative to the directory of path.
+func Resolve(path string) (map[string]interface{}, error) {
+	c := &composer{}
+	data, err := c.compose(path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.warn()
+	if err := c.err(); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// readSchemaFile reads and parses a single schema file.
+func readSchemaFile(path string) (map[string]interface{}, error) {
+	format := parser.DetectFormat(path)
+
+	// Validate that the format is suitable for schema files
+	if err := parser.ValidateSchemaFormat(format); err != nil {
+		return nil, err
+	}
+
+	content, err := reader.ReadFile(path)
+	if err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeSchemaLoad, "failed to read schema file", err).WithContext("file", path)
+	}
+
+	data, err := parser.Parse(path, content, "")
+	if err != nil {
+		return nil, errors.WrapError(errors.ErrorTypeParsing, "failed to parse schema file", err).WithContext("file", path)
+	}
+	return data, nil
 }
 
 // Process is a convenience function that creates a processor and processes the configuration.
@@ -120,3 +193,9 @@ func Process(config map[string]interface{}, schema *Schema, varsFromFile map[str
 	processor := NewProcessor()
 	return processor.Process(config, schema, varsFromFile, envVars)
 }
+
+// ProcessWithOptions creates a processor with the given options and processes the configuration.
+func ProcessWithOptions(config map[string]interface{}, schema *Schema, varsFromFile map[string]interface{}, envVars map[string]string, opts Options) (map[string]interface{}, error) {
+	processor := NewProcessorWithOptions(opts)
+	return processor.Process(config, schema, varsFromFile, envVars)
+}
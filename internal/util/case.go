@@ -0,0 +1,103 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+// Paths looks up values by path expression (see ParsePath). Keys are matched
+// like the default merge: a key spelled exactly as in the path is preferred,
+// and otherwise a key that differs only in case matches. Set CaseSensitive,
+// as the -c flag does, to match keys exactly.
+type Paths struct {
+	// CaseSensitive matches keys exactly instead of ignoring case.
+	CaseSensitive bool
+}
+
+// exact matches keys exactly. GetNestedValue, SetNestedValue,
+// DeleteNestedValue and ExpandPath use it.
+var exact = Paths{CaseSensitive: true}
+
+// LookupKey returns the key of m that matches key: key itself when present
+// or, unless p is case-sensitive, a key that differs only in case. When
+// several keys differ only in case, the smallest one is returned.
+func (p Paths) LookupKey(m map[string]interface{}, key string) (string, bool) {
+	if _, ok := m[key]; ok {
+		return key, true
+	}
+	if p.CaseSensitive {
+		return "", false
+	}
+	found, ok := "", false
+	for k := range m {
+		if strings.EqualFold(k, key) && (!ok || k < found) {
+			found, ok = k, true
+		}
+	}
+	return found, ok
+}
+
+// Resolve returns path with its keys spelled as they are in data, so that a
+// case-insensitive path names the value it finds. Segments below the deepest
+// existing value are kept as given; malformed paths are returned as is.
+func (p Paths) Resolve(data map[string]interface{}, path string) string {
+	if p.CaseSensitive {
+		return path
+	}
+	segments, err := ParsePath(path)
+	if err != nil {
+		return path
+	}
+	var current interface{} = data
+	for i, seg := range segments {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			if seg.Kind != SegmentKey {
+				return FormatPath(segments)
+			}
+			key, ok := p.LookupKey(v, seg.Key)
+			if !ok {
+				return FormatPath(segments)
+			}
+			if key != seg.Key {
+				segments[i] = Segment{Kind: SegmentKey, Key: key, Quoted: true}
+			}
+			current = v[key]
+		case []interface{}:
+			index, ok := resolveIndex(seg.Index, len(v))
+			if seg.Kind != SegmentIndex || !ok {
+				return FormatPath(segments)
+			}
+			current = v[index]
+		default:
+			return FormatPath(segments)
+		}
+	}
+	return FormatPath(segments)
+}
+
+// CaseStyles lists the styles accepted by ChangeCase.
+var CaseStyles = []string{"upper", "lower", "snake", "camel", "kebab", "pascal"}
+
+// ChangeCase converts input to a case style: upper, lower, snake, camel,
+// kebab or pascal.
+func ChangeCase(input, style string) (string, error) {
+	switch strings.ToLower(style) {
+	case "upper":
+		return strings.ToUpper(input), nil
+	case "lower":
+		return strings.ToLower(input), nil
+	case "snake":
+		return strcase.ToSnake(input), nil
+	case "camel":
+		return strcase.ToLowerCamel(input), nil
+	case "kebab":
+		return strcase.ToKebab(input), nil
+	case "pascal":
+		return strcase.ToCamel(input), nil
+	default:
+		return "", fmt.Errorf("unsupported case type '%s'. Supported: %s", style, strings.Join(CaseStyles, ", "))
+	}
+}
@@ -0,0 +1,102 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNestedValue_CaseInsensitive(t *testing.T) {
+	data := map[string]interface{}{
+		"Database": map[string]interface{}{"Port": 5432},
+	}
+	if _, ok := GetNestedValue(data, "database.port"); ok {
+		t.Fatal("GetNestedValue should match keys exactly")
+	}
+	if _, ok := (Paths{CaseSensitive: true}).Get(data, "database.port"); ok {
+		t.Fatal("case-sensitive lookups should match keys exactly")
+	}
+
+	var paths Paths
+	if got, ok := paths.Get(data, "database.PORT"); !ok || got != 5432 {
+		t.Errorf("Get(database.PORT) = %v, %v, want 5432, true", got, ok)
+	}
+	if err := paths.Set(data, "DATABASE.port", 6543); err != nil {
+		t.Fatal(err)
+	}
+	if err := paths.Set(data, "database.host", "db"); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"Database": map[string]interface{}{"Port": 6543, "host": "db"},
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("Set should reuse existing keys, got %v", data)
+	}
+	if err := paths.Delete(data, "database.port"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := data["Database"].(map[string]interface{})["Port"]; ok {
+		t.Error("Delete should delete keys that differ in case")
+	}
+}
+
+func TestLookupKey_PrefersExactMatch(t *testing.T) {
+	var paths Paths
+	m := map[string]interface{}{"key": 1, "Key": 2, "KEY": 3}
+	if k, ok := paths.LookupKey(m, "Key"); !ok || k != "Key" {
+		t.Errorf("LookupKey(Key) = %q, want the exact match", k)
+	}
+	if k, ok := paths.LookupKey(m, "kEy"); !ok || k != "KEY" {
+		t.Errorf("LookupKey(kEy) = %q, want the smallest match KEY", k)
+	}
+}
+
+func TestPaths_Resolve(t *testing.T) {
+	data := map[string]interface{}{
+		"Services": map[string]interface{}{
+			"API": map[string]interface{}{"Ports": []interface{}{80}},
+		},
+	}
+	if got := (Paths{CaseSensitive: true}).Resolve(data, "services.api"); got != "services.api" {
+		t.Errorf("Resolve should not rewrite case-sensitive paths, got %q", got)
+	}
+	if got := ExpandPath(data, "services.*.ports"); len(got) != 0 {
+		t.Errorf("ExpandPath should match keys exactly, got %v", got)
+	}
+
+	var paths Paths
+	tests := map[string]string{
+		"services.api.ports[0]": "Services.API.Ports[0]",
+		"services.api.missing":  "Services.API.missing",
+		"services.Web.port":     "Services.Web.port",
+	}
+	for path, want := range tests {
+		if got := paths.Resolve(data, path); got != want {
+			t.Errorf("Resolve(%q) = %q, want %q", path, got, want)
+		}
+	}
+	if got := paths.Expand(data, "services.*.ports"); !reflect.DeepEqual(got, []string{"Services.API.Ports"}) {
+		t.Errorf("Expand(services.*.ports) = %v", got)
+	}
+	if got := paths.Expand(data, "SERVICES.api"); !reflect.DeepEqual(got, []string{"Services.API"}) {
+		t.Errorf("Expand(SERVICES.api) = %v", got)
+	}
+}
+
+func TestChangeCase(t *testing.T) {
+	tests := []struct{ style, want string }{
+		{"lower", "featureflags"},
+		{"snake", "feature_flags"},
+		{"kebab", "feature-flags"},
+		{"camel", "featureFlags"},
+		{"pascal", "FeatureFlags"},
+	}
+	for _, tt := range tests {
+		if got, err := ChangeCase("FeatureFlags", tt.style); err != nil || got != tt.want {
+			t.Errorf("ChangeCase(FeatureFlags, %s) = %q, %v, want %q", tt.style, got, err, tt.want)
+		}
+	}
+	if _, err := ChangeCase("x", "title"); err == nil {
+		t.Error("ChangeCase should reject unknown styles")
+	}
+}
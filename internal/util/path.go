@@ -0,0 +1,223 @@
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Path expressions address values in a nested configuration:
+//
+//	server.host                       map keys separated by '.'
+//	servers[0].host                   array element by index
+//	servers[-1]                       index counted from the end
+//	servers[+]                        a new element appended (SetNestedValue only)
+//	annotations."kubernetes.io/name"  a quoted key that contains dots
+//	annotations["kubernetes.io/name"] the same key in brackets
+//
+// Quoted keys use double quotes with backslash escapes, or single quotes
+// taken literally.
+
+// SegmentKind distinguishes the steps of a path.
+type SegmentKind int
+
+const (
+	// SegmentKey is a map key.
+	SegmentKey SegmentKind = iota
+	// SegmentIndex is an array index; negative indices count from the end.
+	SegmentIndex
+	// SegmentAppend is the position after the last element of an array ([+]).
+	SegmentAppend
+	// SegmentAnyIndex matches every element of an array ([*]) in path patterns.
+	SegmentAnyIndex
+)
+
+// Segment is one step of a path expression.
+type Segment struct {
+	Kind  SegmentKind
+	Key   string
+	Index int
+	// Quoted is true for quoted keys, which never contain wildcards.
+	Quoted bool
+}
+
+// ParsePath parses a path expression into its segments.
+func ParsePath(path string) ([]Segment, error) {
+	if path == "" {
+		return nil, fmt.Errorf("empty path")
+	}
+	var segments []Segment
+	expectKey := true // at the start or after a '.'
+	for i := 0; i < len(path); {
+		switch c := path[i]; {
+		case c == '.':
+			if expectKey {
+				return nil, fmt.Errorf("path '%s': empty key at offset %d", path, i)
+			}
+			expectKey = true
+			i++
+		case c == '[':
+			seg, n, err := parseBracket(path[i:])
+			if err != nil {
+				return nil, fmt.Errorf("path '%s': %w", path, err)
+			}
+			if len(segments) == 0 && seg.Kind != SegmentKey {
+				return nil, fmt.Errorf("path '%s': must start with a key", path)
+			}
+			segments = append(segments, seg)
+			expectKey = false
+			i += n
+		case !expectKey:
+			return nil, fmt.Errorf("path '%s': expected '.' or '[' at offset %d", path, i)
+		case c == '"' || c == '\'':
+			key, n, err := parseQuoted(path[i:])
+			if err != nil {
+				return nil, fmt.Errorf("path '%s': %w", path, err)
+			}
+			segments = append(segments, Segment{Kind: SegmentKey, Key: key, Quoted: true})
+			expectKey = false
+			i += n
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			segments = append(segments, Segment{Kind: SegmentKey, Key: path[i : i+end]})
+			expectKey = false
+			i += end
+		}
+	}
+	if expectKey {
+		return nil, fmt.Errorf("path '%s': ends with '.'", path)
+	}
+	return segments, nil
+}
+
+// parseBracket parses a bracket segment at the start of s and returns it with
+// the number of bytes consumed.
+func parseBracket(s string) (Segment, int, error) {
+	if len(s) > 1 && (s[1] == '"' || s[1] == '\'') {
+		key, n, err := parseQuoted(s[1:])
+		if err != nil {
+			return Segment{}, 0, err
+		}
+		if 1+n >= len(s) || s[1+n] != ']' {
+			return Segment{}, 0, fmt.Errorf("missing ']' after quoted key")
+		}
+		return Segment{Kind: SegmentKey, Key: key, Quoted: true}, n + 2, nil
+	}
+
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return Segment{}, 0, fmt.Errorf("missing ']'")
+	}
+	switch inner := s[1:end]; inner {
+	case "+":
+		return Segment{Kind: SegmentAppend}, end + 1, nil
+	case "*":
+		return Segment{Kind: SegmentAnyIndex}, end + 1, nil
+	default:
+		index, err := strconv.Atoi(inner)
+		if err != nil {
+			return Segment{}, 0, fmt.Errorf("invalid index '[%s]'", inner)
+		}
+		return Segment{Kind: SegmentIndex, Index: index}, end + 1, nil
+	}
+}
+
+// parseQuoted parses a quoted key at the start of s and returns it with the
+// number of bytes consumed. Double-quoted keys use Go string escapes.
+func parseQuoted(s string) (string, int, error) {
+	quote := s[0]
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quote == '"' {
+				i++
+			}
+		case quote:
+			if quote == '\'' {
+				return s[1:i], i + 1, nil
+			}
+			key, err := strconv.Unquote(s[:i+1])
+			if err != nil {
+				return "", 0, fmt.Errorf("invalid quoted key %s", s[:i+1])
+			}
+			return key, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quoted key")
+}
+
+// AppendKey appends a map key to a path, quoting the key when it cannot be
+// written bare.
+func AppendKey(path, key string) string {
+	if needsQuoting(key) {
+		key = strconv.Quote(key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// FormatPath writes segments as a path expression.
+func FormatPath(segments []Segment) string {
+	var path string
+	for _, seg := range segments {
+		switch seg.Kind {
+		case SegmentKey:
+			if seg.Quoted || !strings.Contains(seg.Key, "*") {
+				path = AppendKey(path, seg.Key)
+			} else if path == "" {
+				path = seg.Key
+			} else {
+				path += "." + seg.Key
+			}
+		case SegmentIndex:
+			path = AppendIndex(path, seg.Index)
+		case SegmentAppend:
+			path += "[+]"
+		case SegmentAnyIndex:
+			path += "[*]"
+		}
+	}
+	return path
+}
+
+// ParentPath returns the path of the map or array that holds the value at
+// path. It reports false for top-level keys and malformed paths.
+func ParentPath(path string) (string, bool) {
+	if !strings.ContainsAny(path, "[\"'") {
+		i := strings.LastIndex(path, ".")
+		if i < 0 {
+			return "", false
+		}
+		return path[:i], true
+	}
+	segments, err := ParsePath(path)
+	if err != nil || len(segments) < 2 {
+		return "", false
+	}
+	return FormatPath(segments[:len(segments)-1]), true
+}
+
+// AppendIndex appends an array index to a path.
+func AppendIndex(path string, index int) string {
+	return path + "[" + strconv.Itoa(index) + "]"
+}
+
+// needsQuoting reports whether key contains characters that have a meaning
+// in path expressions.
+func needsQuoting(key string) bool {
+	return key == "" || strings.ContainsAny(key, ".[*") || key[0] == '"' || key[0] == '\''
+}
+
+// resolveIndex returns the position of index in an array of length n,
+// counting negative indices from the end.
+func resolveIndex(index, n int) (int, bool) {
+	if index < 0 {
+		index += n
+	}
+	return index, index >= 0 && index < n
+}
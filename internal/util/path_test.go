@@ -0,0 +1,144 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want []Segment
+	}{
+		{"server.host", []Segment{{Kind: SegmentKey, Key: "server"}, {Kind: SegmentKey, Key: "host"}}},
+		{"servers[0].host", []Segment{{Kind: SegmentKey, Key: "servers"}, {Kind: SegmentIndex, Index: 0}, {Kind: SegmentKey, Key: "host"}}},
+		{"servers[-1]", []Segment{{Kind: SegmentKey, Key: "servers"}, {Kind: SegmentIndex, Index: -1}}},
+		{"servers[+]", []Segment{{Kind: SegmentKey, Key: "servers"}, {Kind: SegmentAppend}}},
+		{"servers[*]", []Segment{{Kind: SegmentKey, Key: "servers"}, {Kind: SegmentAnyIndex}}},
+		{`a."b.c"`, []Segment{{Kind: SegmentKey, Key: "a"}, {Kind: SegmentKey, Key: "b.c", Quoted: true}}},
+		{`a["b.c"]`, []Segment{{Kind: SegmentKey, Key: "a"}, {Kind: SegmentKey, Key: "b.c", Quoted: true}}},
+		{`a.'x\y'`, []Segment{{Kind: SegmentKey, Key: "a"}, {Kind: SegmentKey, Key: `x\y`, Quoted: true}}},
+		{`a."q\"d"`, []Segment{{Kind: SegmentKey, Key: "a"}, {Kind: SegmentKey, Key: `q"d`, Quoted: true}}},
+		{"m[0][1]", []Segment{{Kind: SegmentKey, Key: "m"}, {Kind: SegmentIndex, Index: 0}, {Kind: SegmentIndex, Index: 1}}},
+	}
+	for _, tt := range tests {
+		got, err := ParsePath(tt.path)
+		if err != nil {
+			t.Errorf("ParsePath(%q) returned error: %v", tt.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParsePath(%q) = %+v, want %+v", tt.path, got, tt.want)
+		}
+	}
+
+	for _, path := range []string{"", "a..b", "a.", ".a", "[0]", "a[x]", "a[0", `a."b`, `a["b"`, "a[0]b"} {
+		if _, err := ParsePath(path); err == nil {
+			t.Errorf("ParsePath(%q) should fail", path)
+		}
+	}
+}
+
+func TestAppendKeyRoundTrip(t *testing.T) {
+	for _, key := range []string{"plain", "kubernetes.io/name", "a[0]", "*", "", `"quoted"`} {
+		path := AppendKey("root", key)
+		segments, err := ParsePath(path)
+		if err != nil {
+			t.Fatalf("ParsePath(%q) returned error: %v", path, err)
+		}
+		if len(segments) != 2 || segments[1].Key != key {
+			t.Errorf("AppendKey(root, %q) = %q, which parses to %+v", key, path, segments)
+		}
+	}
+}
+
+func TestGetNestedValue(t *testing.T) {
+	data := map[string]interface{}{
+		"servers": []interface{}{
+			map[string]interface{}{"host": "a"},
+			map[string]interface{}{"host": "b"},
+		},
+		"annotations": map[string]interface{}{"kubernetes.io/name": "web"},
+	}
+	tests := []struct {
+		path  string
+		want  interface{}
+		found bool
+	}{
+		{"servers[0].host", "a", true},
+		{"servers[-1].host", "b", true},
+		{"servers[2].host", nil, false},
+		{"servers[-3]", nil, false},
+		{`annotations."kubernetes.io/name"`, "web", true},
+		{`annotations["kubernetes.io/name"]`, "web", true},
+		{"annotations.kubernetes.io/name", nil, false},
+		{"annotations[0]", nil, false},
+	}
+	for _, tt := range tests {
+		got, found := GetNestedValue(data, tt.path)
+		if found != tt.found || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("GetNestedValue(%q) = %v, %v, want %v, %v", tt.path, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestSetNestedValue(t *testing.T) {
+	data := map[string]interface{}{
+		"servers": []interface{}{map[string]interface{}{"host": "a"}},
+	}
+	steps := []struct {
+		path  string
+		value interface{}
+	}{
+		{"servers[0].port", 80},
+		{"servers[+]", map[string]interface{}{"host": "b"}},
+		{"servers[-1].port", 81},
+		{"tags[+]", "x"},
+		{`labels."app.kubernetes.io/name"`, "web"},
+	}
+	for _, s := range steps {
+		if err := SetNestedValue(data, s.path, s.value); err != nil {
+			t.Fatalf("SetNestedValue(%q) returned error: %v", s.path, err)
+		}
+	}
+	want := map[string]interface{}{
+		"servers": []interface{}{
+			map[string]interface{}{"host": "a", "port": 80},
+			map[string]interface{}{"host": "b", "port": 81},
+		},
+		"tags":   []interface{}{"x"},
+		"labels": map[string]interface{}{"app.kubernetes.io/name": "web"},
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("SetNestedValue produced %v, want %v", data, want)
+	}
+
+	for _, path := range []string{"servers[5]", "servers[*].host", "labels[0]", "a..b"} {
+		if err := SetNestedValue(data, path, 1); err == nil {
+			t.Errorf("SetNestedValue(%q) should fail", path)
+		}
+	}
+}
+
+func TestDeleteNestedValue(t *testing.T) {
+	data := map[string]interface{}{
+		"servers": []interface{}{"a", "b", "c"},
+		"labels":  map[string]interface{}{"app.kubernetes.io/name": "web", "tier": "front"},
+	}
+	for _, path := range []string{"servers[-1]", "servers[0]", `labels."app.kubernetes.io/name"`, "missing.key"} {
+		if err := DeleteNestedValue(data, path); err != nil {
+			t.Fatalf("DeleteNestedValue(%q) returned error: %v", path, err)
+		}
+	}
+	want := map[string]interface{}{
+		"servers": []interface{}{"b"},
+		"labels":  map[string]interface{}{"tier": "front"},
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("DeleteNestedValue produced %v, want %v", data, want)
+	}
+
+	if err := DeleteNestedValue(data, "servers[+]"); err == nil {
+		t.Error("DeleteNestedValue should reject [+]")
+	}
+}
@@ -0,0 +1,255 @@
+package util
+
+import (
+	"sort"
+	"strings"
+)
+
+// Path patterns are path expressions (see ParsePath) whose segments may
+// contain wildcards:
+//
+//	services.*.port     '*' matches any single key
+//	services.api-*.port '*' inside a key matches any run of characters
+//	servers[*].host     '[*]' matches any array index
+//	**.password         '**' matches any number of keys and indices, including none
+//
+// Paths without wildcards are literal and match only themselves. Quoted keys
+// are always literal.
+
+// IsPattern reports whether path contains wildcards.
+func IsPattern(path string) bool {
+	if !strings.Contains(path, "*") {
+		return false
+	}
+	segments, err := ParsePath(path)
+	if err != nil {
+		return false
+	}
+	for _, seg := range segments {
+		if seg.Kind == SegmentAnyIndex || isWildcardKey(seg) {
+			return true
+		}
+	}
+	return false
+}
+
+// isWildcardKey reports whether seg is an unquoted key containing '*'.
+func isWildcardKey(seg Segment) bool {
+	return seg.Kind == SegmentKey && !seg.Quoted && strings.Contains(seg.Key, "*")
+}
+
+// isPlainPath reports whether path is a dot-separated path without
+// wildcards, quotes or brackets, which can be compared as a string.
+func isPlainPath(path string) bool {
+	return !strings.ContainsAny(path, "*[\"'")
+}
+
+// MatchPath reports whether the concrete path matches pattern.
+func MatchPath(pattern, path string, caseSensitive bool) bool {
+	if isPlainPath(pattern) && isPlainPath(path) {
+		if caseSensitive {
+			return pattern == path
+		}
+		return strings.EqualFold(pattern, path)
+	}
+	patternSegments, err := ParsePath(pattern)
+	if err != nil {
+		return false
+	}
+	pathSegments, err := ParsePath(path)
+	if err != nil {
+		return false
+	}
+	if !caseSensitive {
+		lowerKeys(patternSegments)
+		lowerKeys(pathSegments)
+	}
+	return matchSegments(patternSegments, pathSegments)
+}
+
+// foldKey returns key lowercased unless p is case-sensitive, for matching
+// against segments lowercased with lowerKeys.
+func (p Paths) foldKey(key string) string {
+	if p.CaseSensitive {
+		return key
+	}
+	return strings.ToLower(key)
+}
+
+// lowerKeys lowercases the keys of segments in place.
+func lowerKeys(segments []Segment) {
+	for i := range segments {
+		segments[i].Key = strings.ToLower(segments[i].Key)
+	}
+}
+
+// MatchPathOrAncestor reports whether pattern matches path or one of its
+// ancestors, i.e. whether a rule for pattern covers path.
+func MatchPathOrAncestor(pattern, path string, caseSensitive bool) bool {
+	if isPlainPath(pattern) && isPlainPath(path) {
+		if !caseSensitive {
+			pattern, path = strings.ToLower(pattern), strings.ToLower(path)
+		}
+		return path == pattern || strings.HasPrefix(path, pattern+".")
+	}
+	patternSegments, err := ParsePath(pattern)
+	if err != nil {
+		return false
+	}
+	pathSegments, err := ParsePath(path)
+	if err != nil {
+		return false
+	}
+	if !caseSensitive {
+		lowerKeys(patternSegments)
+		lowerKeys(pathSegments)
+	}
+	for n := len(pathSegments); n > 0; n-- {
+		if matchSegments(patternSegments, pathSegments[:n]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches path segments against pattern segments.
+func matchSegments(pattern, path []Segment) bool {
+	for len(pattern) > 0 {
+		if isGlobstar(pattern[0]) {
+			for skip := 0; skip <= len(path); skip++ {
+				if matchSegments(pattern[1:], path[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 || !matchSegment(pattern[0], path[0]) {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
+
+// isGlobstar reports whether seg is the '**' wildcard.
+func isGlobstar(seg Segment) bool {
+	return seg.Kind == SegmentKey && !seg.Quoted && seg.Key == "**"
+}
+
+// matchSegment matches a single path segment against a pattern segment.
+func matchSegment(pattern, seg Segment) bool {
+	switch pattern.Kind {
+	case SegmentKey:
+		if seg.Kind != SegmentKey {
+			return false
+		}
+		if isWildcardKey(pattern) {
+			return matchKey(pattern.Key, seg.Key)
+		}
+		return pattern.Key == seg.Key
+	case SegmentIndex:
+		return seg.Kind == SegmentIndex && seg.Index == pattern.Index
+	case SegmentAnyIndex:
+		return seg.Kind == SegmentIndex
+	}
+	return false
+}
+
+// matchKey matches a key against a pattern in which '*' matches any run of
+// characters.
+func matchKey(pattern, key string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == key
+	}
+	if !strings.HasPrefix(key, parts[0]) {
+		return false
+	}
+	key = key[len(parts[0]):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(key, part)
+		if i < 0 {
+			return false
+		}
+		key = key[i+len(part):]
+	}
+	return strings.HasSuffix(key, parts[len(parts)-1])
+}
+
+// ExpandPath returns the concrete paths in data that match pattern, sorted.
+// A literal path is returned when it exists, with its keys spelled as in data
+// (see Paths.Resolve). Keys are matched exactly; see Paths.Expand to ignore
+// their case.
+func ExpandPath(data map[string]interface{}, pattern string) []string {
+	return exact.Expand(data, pattern)
+}
+
+// Expand returns the concrete paths in data that match pattern, sorted.
+func (p Paths) Expand(data map[string]interface{}, pattern string) []string {
+	if !IsPattern(pattern) {
+		if _, ok := p.Get(data, pattern); ok {
+			return []string{p.Resolve(data, pattern)}
+		}
+		return nil
+	}
+	segments, err := ParsePath(pattern)
+	if err != nil {
+		return nil
+	}
+	if !p.CaseSensitive {
+		lowerKeys(segments)
+	}
+
+	found := make(map[string]bool)
+	p.expandSegments(data, "", segments, found)
+	paths := make([]string, 0, len(found))
+	for path := range found {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// expandSegments adds the paths below the value at path that match pattern to found.
+func (p Paths) expandSegments(value interface{}, path string, pattern []Segment, found map[string]bool) {
+	if len(pattern) == 0 {
+		if path != "" {
+			found[path] = true
+		}
+		return
+	}
+	seg := pattern[0]
+	globstar := isGlobstar(seg)
+	if globstar {
+		// '**' matches nothing here, or one step and stays in the pattern
+		p.expandSegments(value, path, pattern[1:], found)
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			switch {
+			case globstar:
+				p.expandSegments(child, AppendKey(path, key), pattern, found)
+			case matchSegment(seg, Segment{Kind: SegmentKey, Key: p.foldKey(key)}):
+				p.expandSegments(child, AppendKey(path, key), pattern[1:], found)
+			}
+		}
+	case []interface{}:
+		if path == "" {
+			return
+		}
+		for i, child := range v {
+			switch {
+			case globstar:
+				p.expandSegments(child, AppendIndex(path, i), pattern, found)
+			case seg.Kind == SegmentAnyIndex:
+				p.expandSegments(child, AppendIndex(path, i), pattern[1:], found)
+			case seg.Kind == SegmentIndex:
+				if index, ok := resolveIndex(seg.Index, len(v)); ok && index == i {
+					p.expandSegments(child, AppendIndex(path, i), pattern[1:], found)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"server.port", "server.port", true},
+		{"server.port", "server.host", false},
+		{"services.*.port", "services.api.port", true},
+		{"services.*.port", "services.api.tls.port", false},
+		{"services.api-*.port", "services.api-v2.port", true},
+		{"services.api-*.port", "services.web.port", false},
+		{"*.*", "a.b", true},
+		{"*", "a.b", false},
+		{"**.password", "password", true},
+		{"**.password", "db.primary.password", true},
+		{"**.password", "db.passwords", false},
+		{"db.**", "db", true},
+		{"db.**", "db.pool.max", true},
+		{"a.**.z", "a.b.c.z", true},
+		{"a.**.z", "a.z", true},
+		{"a.**.z", "a.b.c", false},
+		{"*-key", "api-key", true},
+		{"a*b*c", "aXbYc", true},
+		{"a*b*c", "aXcYb", false},
+		{"servers[*].host", "servers[0].host", true},
+		{"servers[*].host", "servers.host", false},
+		{"servers[1].host", "servers[1].host", true},
+		{"**.host", "servers[3].host", true},
+		{`labels."*"`, "labels.app", false},
+		{`labels."*"`, `labels."*"`, true},
+	}
+	for _, tt := range tests {
+		if got := MatchPath(tt.pattern, tt.path, true); got != tt.want {
+			t.Errorf("MatchPath(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+
+	if !MatchPath("Services.*.Port", "services.api.port", false) {
+		t.Error("MatchPath should ignore case when caseSensitive is false")
+	}
+	if MatchPath("Services.*.Port", "services.api.port", true) {
+		t.Error("MatchPath should respect case when caseSensitive is true")
+	}
+}
+
+func TestMatchPathOrAncestor(t *testing.T) {
+	if !MatchPathOrAncestor("services.*", "services.api.port", true) {
+		t.Error("expected services.* to cover services.api.port")
+	}
+	if MatchPathOrAncestor("services.*.port", "services.api", true) {
+		t.Error("services.*.port must not cover its parent")
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	data := map[string]interface{}{
+		"services": map[string]interface{}{
+			"api": map[string]interface{}{"port": 80, "password": "a"},
+			"web": map[string]interface{}{"port": 81},
+		},
+		"db":       map[string]interface{}{"primary": map[string]interface{}{"password": "b"}},
+		"password": "c",
+		"servers": []interface{}{
+			map[string]interface{}{"host": "a"},
+			map[string]interface{}{"host": "b"},
+		},
+		"labels": map[string]interface{}{"app.kubernetes.io/name": "web"},
+	}
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"services.*.port", []string{"services.api.port", "services.web.port"}},
+		{"**.password", []string{"db.primary.password", "password", "services.api.password"}},
+		{"services.a*", []string{"services.api"}},
+		{"db.primary", []string{"db.primary"}},
+		{"db.missing", nil},
+		{"services.*.missing", []string{}},
+		{"servers[*].host", []string{"servers[0].host", "servers[1].host"}},
+		{"servers[-1].host", []string{"servers[-1].host"}},
+		{"labels.*", []string{`labels."app.kubernetes.io/name"`}},
+	}
+	for _, tt := range tests {
+		if got := ExpandPath(data, tt.pattern); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExpandPath(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package util
+
+import (
+	"sort"
+	"strings"
+)
+
+// Suggest returns the candidate closest to name by edit distance, ignoring
+// case, or "" when none is close enough to be a likely typo. A candidate is
+// close enough when at most a third of the characters of name, and at least
+// one, have to change.
+func Suggest(name string, candidates []string) string {
+	sorted := append([]string(nil), candidates...)
+	sort.Strings(sorted)
+
+	lower := strings.ToLower(name)
+	maxDistance := len(name) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+	best, bestDistance := "", maxDistance+1
+	for _, c := range sorted {
+		if c == name {
+			continue
+		}
+		if d := editDistance(lower, strings.ToLower(c)); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+// DidYouMean returns " (did you mean 'x'?)" for the suggestion of Suggest,
+// to be appended to an error message, or "" when there is none.
+func DidYouMean(name string, candidates []string) string {
+	if s := Suggest(name, candidates); s != "" {
+		return " (did you mean '" + s + "'?)"
+	}
+	return ""
+}
+
+// editDistance returns the number of insertions, deletions, substitutions
+// and transpositions of adjacent characters that turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// rows holds the distances for the two previous prefixes of a and the current one
+	rows := [3][]int{make([]int, len(rb)+1), make([]int, len(rb)+1), make([]int, len(rb)+1)}
+	for j := range rows[1] {
+		rows[1][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		prev2, prev, cur := rows[0], rows[1], rows[2]
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		rows[0], rows[1], rows[2] = prev, cur, prev2
+	}
+	return rows[1][len(rb)]
+}
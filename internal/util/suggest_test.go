@@ -0,0 +1,28 @@
+package util
+
+import "testing"
+
+func TestSuggest(t *testing.T) {
+	fields := []string{"type", "targetPath", "format", "sources", "expression"}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"targetpath", "targetPath"},
+		{"tagetPath", "targetPath"},
+		{"sorces", "sources"},
+		{"fromat", "format"},
+		{"typo", "type"},
+		{"color", ""},
+		{"type", ""},
+	}
+	for _, tt := range tests {
+		if got := Suggest(tt.name, fields); got != tt.want {
+			t.Errorf("Suggest(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if got := DidYouMean("transforms", []string{"transform", "validate"}); got != " (did you mean 'transform'?)" {
+		t.Errorf("DidYouMean(transforms) = %q", got)
+	}
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"math"
 	"strconv"
 	"strings"
 )
@@ -33,8 +34,9 @@ func InferType(value string) interface{} {
 		return intVal
 	}
 
-	// Try to parse as float
-	if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+	// Try to parse as float; "inf" and "nan" stay strings, since JSON and
+	// YAML output cannot hold them as numbers
+	if floatVal, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(floatVal, 0) && !math.IsNaN(floatVal) {
 		return floatVal
 	}
 
@@ -66,7 +68,7 @@ func TryConvertType(value string, targetType string) (interface{}, bool) {
 		return nil, false
 
 	case "float", "float64", "double":
-		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(floatVal, 0) && !math.IsNaN(floatVal) {
 			return floatVal, true
 		}
 		return nil, false
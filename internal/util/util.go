@@ -3,75 +3,169 @@ package util
 import (
 	"encoding/json" // For deep copy fallback
 	"fmt"
-	"strings"
 )
 
-// GetNestedValue retrieves a value from a nested map using a dot-separated path.
+// GetNestedValue retrieves a value from a nested map using a path expression
+// such as "server.host" or "servers[0].host" (see ParsePath). Keys are
+// matched exactly; see Paths.Get to ignore their case.
 func GetNestedValue(data map[string]interface{}, path string) (interface{}, bool) {
-	if path == "" {
+	return exact.Get(data, path)
+}
+
+// Get retrieves a value from a nested map using a path expression.
+func (p Paths) Get(data map[string]interface{}, path string) (interface{}, bool) {
+	segments, err := ParsePath(path)
+	if err != nil {
 		return nil, false
 	}
-	keys := strings.Split(path, ".")
-	current := interface{}(data)
+	return p.getSegments(data, segments)
+}
 
-	for _, key := range keys {
-		if m, ok := current.(map[string]interface{}); ok {
-			if val, exists := m[key]; exists {
-				current = val
-			} else {
+// getSegments follows segments from current and returns the value they lead to.
+func (p Paths) getSegments(current interface{}, segments []Segment) (interface{}, bool) {
+	for _, seg := range segments {
+		switch seg.Kind {
+		case SegmentKey:
+			m, ok := current.(map[string]interface{})
+			if !ok {
 				return nil, false
 			}
-		} else {
+			key, exists := p.LookupKey(m, seg.Key)
+			if !exists {
+				return nil, false
+			}
+			current = m[key]
+		case SegmentIndex:
+			s, ok := current.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			i, ok := resolveIndex(seg.Index, len(s))
+			if !ok {
+				return nil, false
+			}
+			current = s[i]
+		default:
 			return nil, false
 		}
 	}
 	return current, true
 }
 
-// SetNestedValue sets a value in a nested map using a dot-separated path.
-// It creates nested maps as needed.
-func SetNestedValue(data map[string]interface{}, path string, value interface{}) {
-	if path == "" {
-		return
+// SetNestedValue sets a value in a nested map using a path expression.
+// It creates nested maps as needed, replacing values that are not maps, and
+// "[+]" appends to an array, creating it when missing. It fails for malformed
+// paths and for indices outside an existing array. Keys are matched exactly;
+// see Paths.Set to reuse the spelling of keys that differ in case.
+func SetNestedValue(data map[string]interface{}, path string, value interface{}) error {
+	return exact.Set(data, path, value)
+}
+
+// Set sets a value in a nested map using a path expression, reusing the
+// spelling of the existing keys it matches.
+func (p Paths) Set(data map[string]interface{}, path string, value interface{}) error {
+	segments, err := ParsePath(path)
+	if err != nil {
+		return err
+	}
+	if _, err := p.setSegments(data, segments, value); err != nil {
+		return fmt.Errorf("path '%s': %w", path, err)
 	}
-	keys := strings.Split(path, ".")
-	currentMap := data
+	return nil
+}
 
-	for i, k := range keys {
-		if i == len(keys)-1 {
-			currentMap[k] = value
-			return
+// setSegments sets value at segments below container and returns the
+// container to store in its parent; it differs from container when a map or
+// array had to be created or an array grew.
+func (p Paths) setSegments(container interface{}, segments []Segment, value interface{}) (interface{}, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+	seg := segments[0]
+	switch seg.Kind {
+	case SegmentKey:
+		m, ok := container.(map[string]interface{})
+		if !ok {
+			m = make(map[string]interface{})
 		}
-
-		if nextMap, ok := currentMap[k].(map[string]interface{}); ok {
-			currentMap = nextMap
+		key := seg.Key
+		if existing, found := p.LookupKey(m, key); found {
+			key = existing
+		}
+		child, err := p.setSegments(m[key], segments[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = child
+		return m, nil
+	case SegmentIndex, SegmentAppend:
+		s, ok := container.([]interface{})
+		if !ok && container != nil {
+			return nil, fmt.Errorf("cannot index a %T", container)
+		}
+		i := len(s)
+		if seg.Kind == SegmentIndex {
+			if i, ok = resolveIndex(seg.Index, len(s)); !ok {
+				return nil, fmt.Errorf("index %d out of range for an array of length %d (use [+] to append)", seg.Index, len(s))
+			}
 		} else {
-			newMap := make(map[string]interface{})
-			currentMap[k] = newMap
-			currentMap = newMap
+			s = append(s, nil)
+		}
+		child, err := p.setSegments(s[i], segments[1:], value)
+		if err != nil {
+			return nil, err
 		}
+		s[i] = child
+		return s, nil
+	default:
+		return nil, fmt.Errorf("[*] cannot be used to set a value")
 	}
 }
 
-// DeleteNestedValue deletes a key from a nested map.
-func DeleteNestedValue(data map[string]interface{}, path string) {
-	keys := strings.Split(path, ".")
-	if len(keys) == 0 {
-		return
+// DeleteNestedValue deletes a map key or array element from a nested map.
+// Deleting a path that does not exist does nothing; malformed paths fail.
+// Keys are matched exactly; see Paths.Delete to ignore their case.
+func DeleteNestedValue(data map[string]interface{}, path string) error {
+	return exact.Delete(data, path)
+}
+
+// Delete deletes a map key or array element from a nested map.
+func (p Paths) Delete(data map[string]interface{}, path string) error {
+	segments, err := ParsePath(path)
+	if err != nil {
+		return err
 	}
-	if len(keys) == 1 {
-		delete(data, keys[0])
-		return
+	parentSegments, last := segments[:len(segments)-1], segments[len(segments)-1]
+	parent, ok := p.getSegments(data, parentSegments)
+	if !ok {
+		return nil
 	}
 
-	parentPath := strings.Join(keys[:len(keys)-1], ".")
-	lastKey := keys[len(keys)-1]
-
-	if parent, ok := GetNestedValue(data, parentPath); ok {
+	switch last.Kind {
+	case SegmentKey:
 		if parentMap, ok := parent.(map[string]interface{}); ok {
-			delete(parentMap, lastKey)
+			if key, found := p.LookupKey(parentMap, last.Key); found {
+				delete(parentMap, key)
+			}
+		}
+	case SegmentIndex:
+		s, ok := parent.([]interface{})
+		if !ok {
+			return nil
+		}
+		i, ok := resolveIndex(last.Index, len(s))
+		if !ok {
+			return nil
 		}
+		remaining := make([]interface{}, 0, len(s)-1)
+		remaining = append(append(remaining, s[:i]...), s[i+1:]...)
+		if _, err := p.setSegments(data, parentSegments, remaining); err != nil {
+			return fmt.Errorf("path '%s': %w", path, err)
+		}
+	default:
+		return fmt.Errorf("path '%s': [+] and [*] cannot be deleted", path)
 	}
+	return nil
 }
 
 // WalkAndReplace recursively walks through the map/slice structure and applies the replacer function to all string values.
@@ -122,6 +216,18 @@ func DeepCopyMap(originalMap map[string]interface{}) (map[string]interface{}, er
 	return jsonCopiedMap, nil
 }
 
+// DeepCopyValue creates a deep copy of a configuration value (map, slice or scalar).
+func DeepCopyValue(value interface{}) (interface{}, error) {
+	if m, ok := value.(map[string]interface{}); ok {
+		return DeepCopyMap(m)
+	}
+	copied, err := deepCopyValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
+
 // deepCopyMapNative performs native deep copy for common configuration data types.
 // Returns error if encounters unsupported types that require JSON fallback.
 func deepCopyMapNative(originalMap map[string]interface{}) (map[string]interface{}, error) {